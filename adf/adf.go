@@ -0,0 +1,74 @@
+// Package adf converts between Markdown and the Atlassian Document Format
+// Jira Cloud uses for rich text, like issue descriptions.
+//
+// Only the Markdown people write in issues is covered: headings, paragraphs,
+// lists, quotes, code, rules, links, bold, italic, strikethrough and
+// mentions. Mentions are written as links, [@Name](mention:accountId), since
+// Jira needs the account ID. Single line breaks are kept as line breaks
+// rather than joined like CommonMark does, that's what people expect in Jira.
+// Jira Server has no documents, ToWiki and FromWiki convert its wiki markup
+// instead.
+package adf
+
+import (
+	"slices"
+
+	"github.com/ctreminiom/go-atlassian/pkg/infra/models"
+)
+
+// Node is a node of a document, the same type the Jira client uses.
+type Node = models.CommentNodeScheme
+
+// Mark is formatting on a text node.
+type Mark = models.MarkScheme
+
+// mentionScheme is the link scheme mentions are written with.
+const mentionScheme = "mention:"
+
+// markOrder is the nesting of marks in Markdown, outermost first. Code can't
+// hold other formatting so it's always innermost.
+var markOrder = []string{"link", "strong", "em", "strike", "code"}
+
+func markRank(mark *Mark) int {
+	if i := slices.Index(markOrder, mark.Type); i >= 0 {
+		return i
+	}
+	return len(markOrder)
+}
+
+// sortMarks puts marks in markOrder, dropping the ones Markdown can't show.
+func sortMarks(marks []*Mark) []*Mark {
+	sorted := make([]*Mark, 0, len(marks))
+	for _, mark := range marks {
+		if markRank(mark) < len(markOrder) {
+			sorted = append(sorted, mark)
+		}
+	}
+	slices.SortStableFunc(sorted, func(a, b *Mark) int { return markRank(a) - markRank(b) })
+	return sorted
+}
+
+func sameMark(a, b *Mark) bool {
+	return a.Type == b.Type && href(a) == href(b)
+}
+
+func sameMarks(a, b []*Mark) bool {
+	return slices.EqualFunc(a, b, sameMark)
+}
+
+func href(mark *Mark) string {
+	if mark.Type != "link" {
+		return ""
+	}
+	url, _ := mark.Attrs["href"].(string)
+	return url
+}
+
+func linkMark(url string) *Mark {
+	return &Mark{Type: "link", Attrs: map[string]interface{}{"href": url}}
+}
+
+func attr(node *Node, name string) string {
+	value, _ := node.Attrs[name].(string)
+	return value
+}
@@ -0,0 +1,170 @@
+package adf
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// golden compares got with the file, or rewrites the file with -update.
+func golden(t *testing.T, path string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(path, got, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v, run the tests with -update to create it", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s differs:\n%s", path, got)
+	}
+}
+
+func toJSON(t *testing.T, doc *Node) []byte {
+	t.Helper()
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(data, '\n')
+}
+
+func markdownFiles(t *testing.T) []string {
+	t.Helper()
+	files, err := filepath.Glob("testdata/*.md")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no Markdown in testdata: %v", err)
+	}
+	return files
+}
+
+func TestFromMarkdown(t *testing.T) {
+	for _, file := range markdownFiles(t) {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			markdown, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden(t, strings.TrimSuffix(file, ".md")+".json", toJSON(t, FromMarkdown(string(markdown))))
+		})
+	}
+}
+
+// The Markdown in testdata is written the way ToMarkdown writes it, so it
+// comes back unchanged.
+func TestMarkdownRoundTrip(t *testing.T) {
+	for _, file := range markdownFiles(t) {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			markdown, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := strings.TrimSpace(string(markdown))
+			if got := ToMarkdown(FromMarkdown(want)); got != want {
+				t.Errorf("ToMarkdown(FromMarkdown()) =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestToMarkdown(t *testing.T) {
+	files, err := filepath.Glob("testdata/jira/*.json")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no documents in testdata/jira: %v", err)
+	}
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var doc Node
+			if err := json.Unmarshal(data, &doc); err != nil {
+				t.Fatal(err)
+			}
+			markdown := ToMarkdown(&doc)
+			golden(t, strings.TrimSuffix(file, ".json")+".md", []byte(markdown+"\n"))
+
+			// what gg writes back reads the same
+			if again := ToMarkdown(FromMarkdown(markdown)); again != markdown {
+				t.Errorf("Markdown changed on the way back:\n%s", again)
+			}
+		})
+	}
+}
+
+func TestFromWiki(t *testing.T) {
+	files, err := filepath.Glob("testdata/server/*.wiki")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no wiki markup in testdata/server: %v", err)
+	}
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			wiki, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden(t, strings.TrimSuffix(file, ".wiki")+".md", []byte(ToMarkdown(FromWiki(string(wiki)))+"\n"))
+		})
+	}
+}
+
+// The wiki markup ToWiki writes reads the same when it comes back.
+func TestWikiRoundTrip(t *testing.T) {
+	for _, file := range markdownFiles(t) {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			markdown, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wiki := ToWiki(FromMarkdown(string(markdown)))
+			if again := ToWiki(FromWiki(wiki)); again != wiki {
+				t.Errorf("ToWiki(FromWiki()) =\n%s\nwant\n%s", again, wiki)
+			}
+		})
+	}
+}
+
+func TestFromMarkdownVariants(t *testing.T) {
+	tests := []struct {
+		markdown string
+		want     string
+	}{
+		{"* one\n+ two", "- one\n- two"},
+		{"1) one\n2) two", "1. one\n2. two"},
+		{"__bold__ and _italic_", "**bold** and *italic*"},
+		{"# Title #", "# Title"},
+		{"<https://example.com>", "https://example.com"},
+		{"- item\nlazy continuation", "- item\n  lazy continuation"},
+		{"~~~\ncode\n~~~", "```\ncode\n```"},
+		{"line one\r\nline two", "line one\nline two"},
+		{"unclosed **bold and [link", "unclosed \\*\\*bold and \\[link"},
+	}
+	for _, tt := range tests {
+		if got := ToMarkdown(FromMarkdown(tt.markdown)); got != tt.want {
+			t.Errorf("ToMarkdown(FromMarkdown(%q)) = %q, want %q", tt.markdown, got, tt.want)
+		}
+	}
+}
+
+func TestToWiki(t *testing.T) {
+	for _, file := range markdownFiles(t) {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			markdown, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wiki := ToWiki(FromMarkdown(string(markdown)))
+			golden(t, strings.TrimSuffix(file, ".md")+".wiki", []byte(wiki+"\n"))
+		})
+	}
+}
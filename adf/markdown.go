@@ -0,0 +1,405 @@
+package adf
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	headingRe = regexp.MustCompile(`^(#{1,6})(?:\s+(.*?))?(?:\s+#+)?\s*$`)
+	fenceRe   = regexp.MustCompile("^(`{3,}|~{3,})\\s*([^`\\s]*)")
+	ruleRe    = regexp.MustCompile(`^(?:-\s*){3,}$|^(?:\*\s*){3,}$|^(?:_\s*){3,}$`)
+	quoteRe   = regexp.MustCompile(`^>\s?`)
+	// itemRe matches list items, the groups are indent, marker and content
+	itemRe = regexp.MustCompile(`^( *)([-*+]|\d{1,9}[.)])(?: +(.*)|$)`)
+)
+
+// FromMarkdown turns Markdown into a document.
+func FromMarkdown(markdown string) *Node {
+	markdown = strings.ReplaceAll(markdown, "\r\n", "\n")
+	return &Node{
+		Type:    "doc",
+		Version: 1,
+		Content: parseBlocks(strings.Split(markdown, "\n")),
+	}
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// startsBlock tells whether a line interrupts a paragraph.
+func startsBlock(line string) bool {
+	return headingRe.MatchString(line) || fenceRe.MatchString(line) || ruleRe.MatchString(line) ||
+		quoteRe.MatchString(line) || itemRe.MatchString(line)
+}
+
+func parseBlocks(lines []string) []*Node {
+	var blocks []*Node
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimLeft(line, " ")
+		switch {
+		case isBlank(line):
+			i++
+		case fenceRe.MatchString(trimmed):
+			var block *Node
+			block, i = parseCodeBlock(lines, i)
+			blocks = append(blocks, block)
+		case headingRe.MatchString(trimmed):
+			match := headingRe.FindStringSubmatch(trimmed)
+			blocks = append(blocks, &Node{
+				Type:    "heading",
+				Attrs:   map[string]interface{}{"level": len(match[1])},
+				Content: parseInline(match[2]),
+			})
+			i++
+		case ruleRe.MatchString(trimmed):
+			blocks = append(blocks, &Node{Type: "rule"})
+			i++
+		case quoteRe.MatchString(trimmed):
+			var quoted []string
+			for ; i < len(lines) && quoteRe.MatchString(strings.TrimLeft(lines[i], " ")); i++ {
+				quoted = append(quoted, quoteRe.ReplaceAllString(strings.TrimLeft(lines[i], " "), ""))
+			}
+			blocks = append(blocks, &Node{Type: "blockquote", Content: parseBlocks(quoted)})
+		case itemRe.MatchString(line):
+			var list *Node
+			list, i = parseList(lines, i)
+			blocks = append(blocks, list)
+		default:
+			paragraph := []string{strings.TrimSpace(line)}
+			for i++; i < len(lines) && !isBlank(lines[i]) && !startsBlock(strings.TrimLeft(lines[i], " ")); i++ {
+				paragraph = append(paragraph, strings.TrimSpace(lines[i]))
+			}
+			blocks = append(blocks, &Node{Type: "paragraph", Content: parseInline(strings.Join(paragraph, "\n"))})
+		}
+	}
+	return blocks
+}
+
+func parseCodeBlock(lines []string, i int) (*Node, int) {
+	match := fenceRe.FindStringSubmatch(strings.TrimLeft(lines[i], " "))
+	fence := match[1]
+	block := &Node{Type: "codeBlock"}
+	if match[2] != "" {
+		block.Attrs = map[string]interface{}{"language": match[2]}
+	}
+	var code []string
+	for i++; i < len(lines); i++ {
+		if trimmed := strings.TrimSpace(lines[i]); strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			i++
+			break
+		}
+		code = append(code, lines[i])
+	}
+	if text := strings.Join(code, "\n"); text != "" {
+		block.Content = []*Node{{Type: "text", Text: text}}
+	}
+	return block, i
+}
+
+// parseList reads the items of a list starting at line i, items end where a
+// line is indented no further than their marker.
+func parseList(lines []string, i int) (*Node, int) {
+	first := itemRe.FindStringSubmatch(lines[i])
+	indent := len(first[1])
+	ordered := !strings.ContainsAny(first[2], "-*+")
+	list := &Node{Type: "bulletList"}
+	if ordered {
+		list.Type = "orderedList"
+		if start, _ := strconv.Atoi(first[2][:len(first[2])-1]); start != 1 {
+			list.Attrs = map[string]interface{}{"order": start}
+		}
+	}
+
+	for i < len(lines) {
+		match := itemRe.FindStringSubmatch(lines[i])
+		if match == nil || len(match[1]) != indent || ordered == strings.ContainsAny(match[2], "-*+") {
+			break
+		}
+		item := []string{match[3]}
+		contentIndent := indent + len(match[2]) + 1
+		for i++; i < len(lines); i++ {
+			line := lines[i]
+			if isBlank(line) {
+				// a blank line only ends the item when nothing indented follows
+				if i+1 < len(lines) && leadingSpaces(lines[i+1]) > indent {
+					item = append(item, "")
+					continue
+				}
+				break
+			}
+			spaces := leadingSpaces(line)
+			if spaces <= indent {
+				if startsBlock(line[spaces:]) {
+					break
+				}
+				// a lazy continuation of the item's paragraph
+				line = strings.Repeat(" ", contentIndent) + line[spaces:]
+				spaces = contentIndent
+			}
+			item = append(item, line[min(spaces, contentIndent):])
+		}
+		list.Content = append(list.Content, &Node{Type: "listItem", Content: itemBlocks(item)})
+		for i < len(lines) && isBlank(lines[i]) {
+			if i+1 < len(lines) && itemRe.MatchString(lines[i+1]) && leadingSpaces(lines[i+1]) == indent {
+				i++
+				continue
+			}
+			break
+		}
+	}
+	return list, i
+}
+
+// itemBlocks parses the content of a list item, which is always at least an
+// empty paragraph.
+func itemBlocks(lines []string) []*Node {
+	blocks := parseBlocks(lines)
+	if len(blocks) == 0 {
+		return []*Node{{Type: "paragraph"}}
+	}
+	return blocks
+}
+
+func leadingSpaces(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// inline collects the text nodes of a paragraph, merging neighbours with the
+// same marks.
+type inline struct {
+	nodes []*Node
+}
+
+func (in *inline) text(text string, marks []*Mark) {
+	if text == "" {
+		return
+	}
+	marks = sortMarks(marks)
+	if n := len(in.nodes); n > 0 && in.nodes[n-1].Type == "text" && sameMarks(in.nodes[n-1].Marks, marks) {
+		in.nodes[n-1].Text += text
+		return
+	}
+	node := &Node{Type: "text", Text: text}
+	if len(marks) > 0 {
+		node.Marks = marks
+	}
+	in.nodes = append(in.nodes, node)
+}
+
+func parseInline(text string) []*Node {
+	in := &inline{}
+	in.parse(text, nil)
+	return in.nodes
+}
+
+var urlRe = regexp.MustCompile(`^https?://[^\s<>]*[^\s<>.,;:!?)'"]`)
+
+// parse reads inline Markdown, marks are the ones of the surrounding text.
+func (in *inline) parse(s string, marks []*Mark) {
+	var plain strings.Builder
+	flush := func() {
+		in.text(plain.String(), marks)
+		plain.Reset()
+	}
+	with := func(mark *Mark) []*Mark {
+		return append(append([]*Mark{}, marks...), mark)
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && isPunct(s[i+1]):
+			plain.WriteByte(s[i+1])
+			i += 2
+			continue
+		case c == '\n':
+			flush()
+			in.nodes = append(in.nodes, &Node{Type: "hardBreak"})
+			i++
+			continue
+		case c == '`':
+			n := runLength(s, i)
+			if end := strings.Index(s[i+n:], strings.Repeat("`", n)); end >= 0 && runLength(s, i+n+end) == n {
+				flush()
+				code := s[i+n : i+n+end]
+				if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' {
+					code = code[1 : len(code)-1]
+				}
+				in.text(code, with(&Mark{Type: "code"}))
+				i += n + end + n
+				continue
+			}
+			plain.WriteString(s[i : i+n])
+			i += n
+			continue
+		case c == '*' || c == '_' || c == '~':
+			if consumed := in.emphasis(s, i, marks, flush); consumed > 0 {
+				i += consumed
+				continue
+			}
+			n := runLength(s, i)
+			plain.WriteString(s[i : i+n])
+			i += n
+			continue
+		case c == '[':
+			if consumed := in.link(s[i:], marks, flush); consumed > 0 {
+				i += consumed
+				continue
+			}
+		case c == '<':
+			if end := strings.IndexByte(s[i:], '>'); end > 0 && urlRe.MatchString(s[i+1:i+end]) {
+				flush()
+				url := s[i+1 : i+end]
+				in.text(url, with(linkMark(url)))
+				i += end + 1
+				continue
+			}
+		case c == 'h' && (i == 0 || !isWord(s[i-1])):
+			if url := urlRe.FindString(s[i:]); url != "" {
+				flush()
+				in.text(url, with(linkMark(url)))
+				i += len(url)
+				continue
+			}
+		}
+		plain.WriteByte(c)
+		i++
+	}
+	flush()
+}
+
+// emphasis reads bold, italic and strikethrough starting at s[i], returning
+// how much of s it took.
+func (in *inline) emphasis(s string, i int, marks []*Mark, flush func()) int {
+	c := s[i]
+	n := runLength(s, i)
+	after := i + n
+	if after >= len(s) || s[after] == ' ' || s[after] == '\n' {
+		return 0
+	}
+	if c == '_' && i > 0 && isWord(s[i-1]) {
+		return 0
+	}
+	for _, size := range []int{2, 1} {
+		if n < size || (c == '~' && size != 2) {
+			continue
+		}
+		rest := s[i+size:]
+		end := closer(rest, c, size)
+		if end < 0 {
+			continue
+		}
+		markType := "em"
+		switch {
+		case c == '~':
+			markType = "strike"
+		case size == 2:
+			markType = "strong"
+		}
+		flush()
+		in.parse(rest[:end], append(append([]*Mark{}, marks...), &Mark{Type: markType}))
+		return size + end + size
+	}
+	return 0
+}
+
+// closer finds where a delimiter run opened with size characters c closes in
+// s, skipping runs opened and closed in between.
+func closer(s string, c byte, size int) int {
+	depth := 0
+	for j := 0; j < len(s); {
+		switch {
+		case s[j] == '\\':
+			j += 2
+			continue
+		case s[j] == '`':
+			// no emphasis inside code
+			n := runLength(s, j)
+			if end := strings.Index(s[j+n:], strings.Repeat("`", n)); end >= 0 {
+				j += n + end + n
+				continue
+			}
+			j += n
+			continue
+		case s[j] != c:
+			j++
+			continue
+		}
+		n := runLength(s, j)
+		canClose := j > 0 && s[j-1] != ' ' && s[j-1] != '\n'
+		canOpen := j+n < len(s) && s[j+n] != ' ' && s[j+n] != '\n'
+		if c == '_' {
+			canClose = canClose && (j+n == len(s) || !isWord(s[j+n]))
+			canOpen = canOpen && (j == 0 || !isWord(s[j-1]))
+		}
+		switch {
+		case canClose && n-depth >= size && (n-depth == size || !canOpen || depth > 0):
+			return j + n - size
+		case canClose && depth > 0:
+			depth = max(depth-n, 0)
+		case canOpen:
+			depth += n
+		}
+		j += n
+	}
+	return -1
+}
+
+// link reads [text](url) at the start of s, returning how much of s it took.
+func (in *inline) link(s string, marks []*Mark, flush func()) int {
+	depth := 0
+	end := -1
+	for j := 0; j < len(s) && end < 0; j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				end = j
+			}
+		}
+	}
+	if end < 0 || end+1 >= len(s) || s[end+1] != '(' {
+		return 0
+	}
+	closing := strings.IndexByte(s[end+2:], ')')
+	if closing < 0 {
+		return 0
+	}
+	text, url := s[1:end], strings.TrimSpace(s[end+2:end+2+closing])
+	if url == "" || strings.ContainsAny(url, " \n") {
+		return 0
+	}
+	flush()
+	if id, ok := strings.CutPrefix(url, mentionScheme); ok && strings.HasPrefix(text, "@") {
+		in.nodes = append(in.nodes, &Node{Type: "mention", Attrs: map[string]interface{}{"id": id, "text": text}})
+	} else {
+		in.parse(text, append(append([]*Mark{}, marks...), linkMark(url)))
+	}
+	return end + 2 + closing + 1
+}
+
+func runLength(s string, i int) int {
+	n := 1
+	for i+n < len(s) && s[i+n] == s[i] {
+		n++
+	}
+	return n
+}
+
+func isPunct(c byte) bool {
+	return c < utf8.RuneSelf && unicode.IsPunct(rune(c)) || strings.IndexByte("`^+<=>|~$", c) >= 0
+}
+
+func isWord(c byte) bool {
+	return c >= utf8.RuneSelf || c == '_' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))
+}
@@ -0,0 +1,267 @@
+package adf
+
+import (
+	"cmp"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ToMarkdown turns a document into Markdown, which also reads fine as plain
+// text in a terminal. Nodes Markdown has no syntax for keep their text.
+func ToMarkdown(doc *Node) string {
+	if doc == nil {
+		return ""
+	}
+	return strings.TrimSpace(blocks(doc.Content, "\n\n"))
+}
+
+func blocks(nodes []*Node, separator string) string {
+	parts := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		if part := block(node); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, separator)
+}
+
+func block(node *Node) string {
+	switch node.Type {
+	case "paragraph":
+		return markdownMarkup.inlines(node.Content, true)
+	case "heading":
+		level, _ := node.Attrs["level"].(float64)
+		if n, ok := node.Attrs["level"].(int); ok {
+			level = float64(n)
+		}
+		text := strings.ReplaceAll(markdownMarkup.inlines(node.Content, false), "\n", " ")
+		return strings.Repeat("#", max(1, min(6, int(level)))) + " " + text
+	case "bulletList", "orderedList":
+		return list(node)
+	case "codeBlock":
+		return codeBlock(node)
+	case "blockquote":
+		return prefixLines(blocks(node.Content, "\n\n"), "> ", ">")
+	case "rule":
+		return "---"
+	case "table":
+		return table(node)
+	case "text", "hardBreak", "mention", "emoji", "inlineCard", "status":
+		// inline nodes where blocks belong, like in table cells
+		return markdownMarkup.inlines([]*Node{node}, true)
+	}
+	return blocks(node.Content, "\n\n")
+}
+
+func list(node *Node) string {
+	start := 1
+	if order, ok := node.Attrs["order"].(float64); ok {
+		start = int(order)
+	} else if order, ok := node.Attrs["order"].(int); ok {
+		start = order
+	}
+	items := make([]string, 0, len(node.Content))
+	for i, item := range node.Content {
+		marker := "- "
+		if node.Type == "orderedList" {
+			marker = strconv.Itoa(start+i) + ". "
+		}
+		content := blocks(item.Content, "\n")
+		if content == "" {
+			items = append(items, strings.TrimSpace(marker))
+			continue
+		}
+		items = append(items, marker+prefixLines(content, strings.Repeat(" ", len(marker)), "")[len(marker):])
+	}
+	return strings.Join(items, "\n")
+}
+
+// table writes a Markdown table, taking the first row as the header since
+// Markdown tables always have one.
+func table(node *Node) string {
+	rows := make([]string, 0, len(node.Content)+1)
+	for i, row := range node.Content {
+		cells := make([]string, 0, len(row.Content))
+		for _, cell := range row.Content {
+			text := strings.ReplaceAll(blocks(cell.Content, " "), "\n", " ")
+			cells = append(cells, strings.ReplaceAll(text, "|", "\\|"))
+		}
+		rows = append(rows, "| "+strings.Join(cells, " | ")+" |")
+		if i == 0 {
+			rows = append(rows, "|"+strings.Repeat(" --- |", len(cells)))
+		}
+	}
+	return strings.Join(rows, "\n")
+}
+
+func codeBlock(node *Node) string {
+	var code strings.Builder
+	for _, child := range node.Content {
+		code.WriteString(child.Text)
+	}
+	fence := "```"
+	for strings.Contains(code.String(), fence) {
+		fence += "`"
+	}
+	return fence + attr(node, "language") + "\n" + code.String() + "\n" + fence
+}
+
+// prefixLines prefixes every line, using empty for empty lines.
+func prefixLines(text, prefix, empty string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = empty
+		} else {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// markup is the syntax of text with marks, Markdown or wiki markup.
+type markup struct {
+	open, close func(*Mark) string
+	escape      func(text string, lineStart bool) string
+	mention     func(name, id string) string
+}
+
+var markdownMarkup = markup{
+	open:   openMark,
+	close:  closeMark,
+	escape: escape,
+	mention: func(name, id string) string {
+		return "[@" + escape(name, false) + "](" + mentionScheme + id + ")"
+	},
+}
+
+// inlines renders text with its marks. Marks are opened and closed only
+// where they change, and whitespace is kept outside of them, so the result
+// reads like markup someone would write.
+func (m markup) inlines(nodes []*Node, escapeStart bool) string {
+	var sb strings.Builder
+	var open []*Mark
+	pending := ""
+	lineStart := escapeStart
+	setMarks := func(marks []*Mark) {
+		keep := 0
+		for keep < len(open) && keep < len(marks) && sameMark(open[keep], marks[keep]) {
+			keep++
+		}
+		for i := len(open) - 1; i >= keep; i-- {
+			sb.WriteString(m.close(open[i]))
+		}
+		sb.WriteString(pending)
+		pending = ""
+		for _, mark := range marks[keep:] {
+			sb.WriteString(m.open(mark))
+		}
+		open = marks
+	}
+
+	for _, node := range nodes {
+		switch node.Type {
+		case "text":
+			if strings.TrimSpace(node.Text) == "" {
+				pending += node.Text
+				continue
+			}
+			trimmed := strings.TrimLeft(node.Text, " ")
+			lead := node.Text[:len(node.Text)-len(trimmed)]
+			core := strings.TrimRight(trimmed, " ")
+			pending += lead
+			marks := sortMarks(node.Marks)
+			if len(marks) == 1 && href(marks[0]) == core && lead == "" && core == node.Text {
+				// a bare URL
+				setMarks(nil)
+				sb.WriteString(core)
+				lineStart = false
+				continue
+			}
+			setMarks(marks)
+			if isCode(marks) {
+				sb.WriteString(core)
+			} else {
+				sb.WriteString(m.escape(core, lineStart))
+			}
+			pending = trimmed[len(core):]
+			lineStart = false
+		case "hardBreak":
+			setMarks(nil)
+			sb.WriteString("\n")
+			lineStart = escapeStart
+		case "mention":
+			setMarks(nil)
+			name := strings.TrimPrefix(attr(node, "text"), "@")
+			sb.WriteString(m.mention(name, attr(node, "id")))
+			lineStart = false
+		case "emoji":
+			setMarks(nil)
+			sb.WriteString(cmp.Or(attr(node, "text"), attr(node, "shortName")))
+			lineStart = false
+		case "inlineCard":
+			setMarks(nil)
+			sb.WriteString(attr(node, "url"))
+			lineStart = false
+		case "status":
+			setMarks(nil)
+			sb.WriteString(m.escape(attr(node, "text"), lineStart))
+			lineStart = false
+		default:
+			setMarks(nil)
+			sb.WriteString(m.inlines(node.Content, false))
+			lineStart = false
+		}
+	}
+	setMarks(nil)
+	return sb.String()
+}
+
+func isCode(marks []*Mark) bool {
+	return len(marks) > 0 && marks[len(marks)-1].Type == "code"
+}
+
+func openMark(mark *Mark) string {
+	switch mark.Type {
+	case "link":
+		return "["
+	case "strong":
+		return "**"
+	case "em":
+		return "*"
+	case "strike":
+		return "~~"
+	case "code":
+		return "`"
+	}
+	return ""
+}
+
+func closeMark(mark *Mark) string {
+	if mark.Type == "link" {
+		return "](" + href(mark) + ")"
+	}
+	return openMark(mark)
+}
+
+var (
+	specialRe   = regexp.MustCompile("[\\\\`*\\[\\]<]|~~|(?:^|[^\\pL\\pN])_|_(?:[^\\pL\\pN]|$)")
+	lineStartRe = regexp.MustCompile(`^(?:#|>|[-+](?: |$)|-{3}|\d+[.)](?: |$))`)
+)
+
+// escape keeps text from being read as Markdown. At the start of a line,
+// what would start a heading, quote or list is escaped too.
+func escape(text string, lineStart bool) string {
+	escaped := specialRe.ReplaceAllStringFunc(text, func(s string) string {
+		i := strings.IndexAny(s, "\\`*[]<~_")
+		return s[:i] + "\\" + s[i:]
+	})
+	if lineStart {
+		if match := lineStartRe.FindString(escaped); match != "" {
+			i := strings.IndexAny(match, "#>-+.)")
+			escaped = escaped[:i] + "\\" + escaped[i:]
+		}
+	}
+	return escaped
+}
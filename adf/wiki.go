@@ -0,0 +1,535 @@
+package adf
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ToWiki turns a document into the wiki markup Jira Server and Data Center
+// use for rich text instead of documents. Mentions are written with the ID
+// as the username, [~name].
+func ToWiki(doc *Node) string {
+	if doc == nil {
+		return ""
+	}
+	return strings.TrimSpace(wikiBlocks(doc.Content, "\n\n"))
+}
+
+func wikiBlocks(nodes []*Node, separator string) string {
+	parts := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		if part := wikiBlock(node); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, separator)
+}
+
+func wikiBlock(node *Node) string {
+	switch node.Type {
+	case "paragraph":
+		return wikiMarkup.inlines(node.Content, true)
+	case "heading":
+		level, _ := node.Attrs["level"].(float64)
+		if n, ok := node.Attrs["level"].(int); ok {
+			level = float64(n)
+		}
+		text := strings.ReplaceAll(wikiMarkup.inlines(node.Content, false), "\n", " ")
+		return "h" + strconv.Itoa(max(1, min(6, int(level)))) + ". " + text
+	case "bulletList", "orderedList":
+		return wikiList(node, "")
+	case "codeBlock":
+		var code strings.Builder
+		for _, child := range node.Content {
+			code.WriteString(child.Text)
+		}
+		if language := attr(node, "language"); language != "" {
+			return "{code:" + language + "}\n" + code.String() + "\n{code}"
+		}
+		return "{noformat}\n" + code.String() + "\n{noformat}"
+	case "blockquote":
+		return "{quote}\n" + wikiBlocks(node.Content, "\n\n") + "\n{quote}"
+	case "rule":
+		return "----"
+	case "table":
+		return wikiTable(node)
+	case "text", "hardBreak", "mention", "emoji", "inlineCard", "status":
+		return wikiMarkup.inlines([]*Node{node}, true)
+	}
+	return wikiBlocks(node.Content, "\n\n")
+}
+
+// wikiList writes nested lists with the markers of all levels, like "*#".
+func wikiList(node *Node, prefix string) string {
+	marker := prefix + "*"
+	if node.Type == "orderedList" {
+		marker = prefix + "#"
+	}
+	lines := make([]string, 0, len(node.Content))
+	for _, item := range node.Content {
+		var text, nested []string
+		for _, child := range item.Content {
+			if child.Type == "bulletList" || child.Type == "orderedList" {
+				nested = append(nested, wikiList(child, marker))
+			} else if part := wikiBlock(child); part != "" {
+				text = append(text, part)
+			}
+		}
+		lines = append(lines, strings.TrimSpace(marker+" "+strings.Join(text, "\n")))
+		lines = append(lines, nested...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func wikiTable(node *Node) string {
+	rows := make([]string, 0, len(node.Content))
+	for _, row := range node.Content {
+		var sb strings.Builder
+		separator := "|"
+		for _, cell := range row.Content {
+			separator = "|"
+			if cell.Type == "tableHeader" {
+				separator = "||"
+			}
+			text := strings.ReplaceAll(wikiBlocks(cell.Content, " "), "\n", " ")
+			sb.WriteString(separator + " " + text + " ")
+		}
+		rows = append(rows, sb.String()+separator)
+	}
+	return strings.Join(rows, "\n")
+}
+
+var wikiMarkup = markup{
+	open: func(mark *Mark) string {
+		switch mark.Type {
+		case "link":
+			return "["
+		case "strong":
+			return "*"
+		case "em":
+			return "_"
+		case "strike":
+			return "-"
+		case "code":
+			return "{{"
+		}
+		return ""
+	},
+	close: func(mark *Mark) string {
+		switch mark.Type {
+		case "link":
+			return "|" + href(mark) + "]"
+		case "strong":
+			return "*"
+		case "em":
+			return "_"
+		case "strike":
+			return "-"
+		case "code":
+			return "}}"
+		}
+		return ""
+	},
+	escape: escapeWiki,
+	mention: func(_, id string) string {
+		return "[~" + id + "]"
+	},
+}
+
+var (
+	wikiSpecialRe   = regexp.MustCompile(`[*_{}\[\]|!^~+-]`)
+	wikiLineStartRe = regexp.MustCompile(`^(?:#|h[1-6]\.|bq\.)`)
+)
+
+// escapeWiki keeps text from being read as wiki markup, including headings
+// and numbered lists at the start of a line. Backslashes stay single, two of
+// them are a line break.
+func escapeWiki(text string, lineStart bool) string {
+	escaped := wikiSpecialRe.ReplaceAllString(text, `\$0`)
+	if lineStart && wikiLineStartRe.MatchString(escaped) {
+		escaped = "\\" + escaped
+	}
+	return escaped
+}
+
+var (
+	wikiHeadingRe = regexp.MustCompile(`^h([1-6])\.\s*(.*)$`)
+	wikiRuleRe    = regexp.MustCompile(`^-{4,}\s*$`)
+	wikiQuoteRe   = regexp.MustCompile(`^bq\.\s*(.*)$`)
+	// wikiMacroRe matches the macros that hold blocks, the groups are name,
+	// parameters and what follows on the line
+	wikiMacroRe = regexp.MustCompile(`^\{(code|noformat|quote|panel)(?::([^}]*))?\}(.*)$`)
+	// wikiItemRe matches list items, the groups are the markers of all levels
+	// and content
+	wikiItemRe = regexp.MustCompile(`^([*#]+|-)\s+(.*)$`)
+)
+
+// FromWiki turns the wiki markup of Jira Server and Data Center into a
+// document. Formatting Markdown has no syntax for, like colors, panels and
+// underlines, keeps only its text. Mentions get the username as ID.
+func FromWiki(wiki string) *Node {
+	wiki = strings.ReplaceAll(wiki, "\r\n", "\n")
+	return &Node{
+		Type:    "doc",
+		Version: 1,
+		Content: parseWikiBlocks(strings.Split(wiki, "\n")),
+	}
+}
+
+// startsWikiBlock tells whether a line interrupts a paragraph.
+func startsWikiBlock(line string) bool {
+	return wikiHeadingRe.MatchString(line) || wikiRuleRe.MatchString(line) || wikiQuoteRe.MatchString(line) ||
+		wikiMacroRe.MatchString(line) || wikiItemRe.MatchString(line) || strings.HasPrefix(line, "|")
+}
+
+func parseWikiBlocks(lines []string) []*Node {
+	var blocks []*Node
+	for i := 0; i < len(lines); {
+		line := strings.TrimSpace(lines[i])
+		switch {
+		case line == "":
+			i++
+		case wikiMacroRe.MatchString(line):
+			match := wikiMacroRe.FindStringSubmatch(line)
+			var body []string
+			body, i = wikiMacroBody(lines, i, match[1], match[3])
+			switch match[1] {
+			case "code", "noformat":
+				block := &Node{Type: "codeBlock"}
+				if language := wikiLanguage(match[1], match[2]); language != "" {
+					block.Attrs = map[string]interface{}{"language": language}
+				}
+				if text := strings.Join(body, "\n"); text != "" {
+					block.Content = []*Node{{Type: "text", Text: text}}
+				}
+				blocks = append(blocks, block)
+			case "quote":
+				blocks = append(blocks, &Node{Type: "blockquote", Content: parseWikiBlocks(body)})
+			default:
+				blocks = append(blocks, parseWikiBlocks(body)...)
+			}
+		case wikiHeadingRe.MatchString(line):
+			match := wikiHeadingRe.FindStringSubmatch(line)
+			level, _ := strconv.Atoi(match[1])
+			blocks = append(blocks, &Node{
+				Type:    "heading",
+				Attrs:   map[string]interface{}{"level": level},
+				Content: parseWikiInline(match[2]),
+			})
+			i++
+		case wikiRuleRe.MatchString(line):
+			blocks = append(blocks, &Node{Type: "rule"})
+			i++
+		case wikiQuoteRe.MatchString(line):
+			quoted := wikiQuoteRe.FindStringSubmatch(line)[1]
+			blocks = append(blocks, &Node{Type: "blockquote", Content: []*Node{
+				{Type: "paragraph", Content: parseWikiInline(quoted)},
+			}})
+			i++
+		case strings.HasPrefix(line, "|"):
+			var table *Node
+			table, i = parseWikiTable(lines, i)
+			blocks = append(blocks, table)
+		case wikiItemRe.MatchString(line):
+			var list *Node
+			list, i = parseWikiList(lines, i, 1)
+			blocks = append(blocks, list)
+		default:
+			var paragraph []string
+			paragraph, i = wikiParagraph(lines, i)
+			blocks = append(blocks, &Node{Type: "paragraph", Content: parseWikiInline(strings.Join(paragraph, "\n"))})
+		}
+	}
+	return blocks
+}
+
+// wikiParagraph collects the lines of the paragraph starting at line i, up
+// to a blank line or another block.
+func wikiParagraph(lines []string, i int) ([]string, int) {
+	paragraph := []string{strings.TrimSpace(lines[i])}
+	for i++; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || startsWikiBlock(line) {
+			break
+		}
+		paragraph = append(paragraph, line)
+	}
+	return paragraph, i
+}
+
+// wikiMacroBody collects what a {name} macro opened at line i holds, rest
+// being what followed it on that line. It returns the line after the macro.
+func wikiMacroBody(lines []string, i int, name, rest string) ([]string, int) {
+	closing := "{" + name + "}"
+	var body []string
+	for line, first := rest, true; ; first = false {
+		if before, _, found := strings.Cut(line, closing); found {
+			if before != "" {
+				body = append(body, before)
+			}
+			return body, i + 1
+		}
+		if !first || line != "" {
+			body = append(body, line)
+		}
+		if i++; i >= len(lines) {
+			return body, i
+		}
+		line = lines[i]
+	}
+}
+
+// wikiLanguage finds the language in the parameters of a code macro, like
+// {code:go} or {code:title=main.go|language=go}.
+func wikiLanguage(name, params string) string {
+	if name != "code" {
+		return ""
+	}
+	for _, param := range strings.Split(params, "|") {
+		key, value, found := strings.Cut(param, "=")
+		if !found {
+			return strings.TrimSpace(key)
+		}
+		if strings.TrimSpace(key) == "language" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// parseWikiList reads the items of a list at the depth starting at line i.
+// The markers of an item are those of all its levels, like "*#".
+func parseWikiList(lines []string, i, depth int) (*Node, int) {
+	first := wikiItemRe.FindStringSubmatch(strings.TrimSpace(lines[i]))[1]
+	kind := wikiListKind(first, depth)
+	list := &Node{Type: kind}
+	for i < len(lines) {
+		match := wikiItemRe.FindStringSubmatch(strings.TrimSpace(lines[i]))
+		if match == nil || len(match[1]) < depth || wikiListKind(match[1], depth) != kind {
+			break
+		}
+		if len(match[1]) > depth {
+			var nested *Node
+			nested, i = parseWikiList(lines, i, depth+1)
+			if len(list.Content) == 0 {
+				list.Content = append(list.Content, &Node{Type: "listItem"})
+			}
+			item := list.Content[len(list.Content)-1]
+			item.Content = append(item.Content, nested)
+			continue
+		}
+		text := []string{match[2]}
+		for i++; i < len(lines); i++ {
+			line := strings.TrimSpace(lines[i])
+			if line == "" || startsWikiBlock(line) {
+				break
+			}
+			text = append(text, line)
+		}
+		list.Content = append(list.Content, &Node{Type: "listItem", Content: []*Node{
+			{Type: "paragraph", Content: parseWikiInline(strings.Join(text, "\n"))},
+		}})
+	}
+	return list, i
+}
+
+func wikiListKind(markers string, depth int) string {
+	if markers[min(depth, len(markers))-1] == '#' {
+		return "orderedList"
+	}
+	return "bulletList"
+}
+
+// parseWikiTable reads the rows of a table starting at line i, cells opened
+// with || are headers.
+func parseWikiTable(lines []string, i int) (*Node, int) {
+	table := &Node{Type: "table"}
+	for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "|"); i++ {
+		row := &Node{Type: "tableRow"}
+		line := strings.TrimSpace(lines[i])
+		for line != "" {
+			cellType := "tableCell"
+			if strings.HasPrefix(line, "||") {
+				cellType = "tableHeader"
+				line = line[2:]
+			} else {
+				line = line[1:]
+			}
+			end := wikiCellEnd(line)
+			if text := strings.TrimSpace(line[:end]); text != "" || end < len(line) {
+				row.Content = append(row.Content, &Node{Type: cellType, Content: []*Node{
+					{Type: "paragraph", Content: parseWikiInline(text)},
+				}})
+			}
+			line = line[end:]
+		}
+		table.Content = append(table.Content, row)
+	}
+	return table, i
+}
+
+// wikiCellEnd finds the | ending a cell, skipping the ones in links, code and
+// escapes.
+func wikiCellEnd(s string) int {
+	for j := 0; j < len(s); j++ {
+		switch {
+		case s[j] == '\\':
+			j++
+		case s[j] == '[':
+			if end := strings.IndexByte(s[j:], ']'); end > 0 {
+				j += end
+			}
+		case strings.HasPrefix(s[j:], "{{"):
+			if end := strings.Index(s[j:], "}}"); end > 0 {
+				j += end + 1
+			}
+		case s[j] == '|':
+			return j
+		}
+	}
+	return len(s)
+}
+
+func parseWikiInline(text string) []*Node {
+	in := &inline{}
+	in.parseWiki(text, nil)
+	return in.nodes
+}
+
+var (
+	// wikiInlineMacroRe matches macros like {color:red} that only format text
+	wikiInlineMacroRe = regexp.MustCompile(`^\{[a-zA-Z]+(?::[^}]*)?\}`)
+	wikiImageRe       = regexp.MustCompile(`^!([^\s!|]+)(?:\|[^!\n]*)?!`)
+	// wikiMarks are the text effects of wiki markup, the ones Markdown can't
+	// show are dropped with their mark
+	wikiMarks = map[byte]string{'*': "strong", '_': "em", '-': "strike", '+': "underline"}
+)
+
+// parseWiki reads inline wiki markup, marks are the ones of the surrounding
+// text.
+func (in *inline) parseWiki(s string, marks []*Mark) {
+	var plain strings.Builder
+	flush := func() {
+		in.text(plain.String(), marks)
+		plain.Reset()
+	}
+	with := func(mark *Mark) []*Mark {
+		return append(append([]*Mark{}, marks...), mark)
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case strings.HasPrefix(s[i:], `\\`):
+			flush()
+			in.nodes = append(in.nodes, &Node{Type: "hardBreak"})
+			i += 2
+			continue
+		case c == '\\' && i+1 < len(s) && isPunct(s[i+1]):
+			plain.WriteByte(s[i+1])
+			i += 2
+			continue
+		case c == '\n':
+			flush()
+			in.nodes = append(in.nodes, &Node{Type: "hardBreak"})
+			i++
+			continue
+		case strings.HasPrefix(s[i:], "{{"):
+			if end := strings.Index(s[i+2:], "}}"); end > 0 {
+				flush()
+				in.text(s[i+2:i+2+end], with(&Mark{Type: "code"}))
+				i += 2 + end + 2
+				continue
+			}
+		case c == '{':
+			if macro := wikiInlineMacroRe.FindString(s[i:]); macro != "" {
+				i += len(macro)
+				continue
+			}
+		case c == '!':
+			if match := wikiImageRe.FindStringSubmatch(s[i:]); match != nil {
+				plain.WriteString(match[1])
+				i += len(match[0])
+				continue
+			}
+		case c == '[':
+			if consumed := in.wikiLink(s[i:], marks, flush); consumed > 0 {
+				i += consumed
+				continue
+			}
+		case wikiMarks[c] != "" && (i == 0 || !isWord(s[i-1])):
+			if end := wikiEffectEnd(s, i); end > 0 {
+				flush()
+				in.parseWiki(s[i+1:end], with(&Mark{Type: wikiMarks[c]}))
+				i = end + 1
+				continue
+			}
+		case c == 'h' && (i == 0 || !isWord(s[i-1])):
+			if url := urlRe.FindString(s[i:]); url != "" {
+				flush()
+				in.text(url, with(linkMark(url)))
+				i += len(url)
+				continue
+			}
+		}
+		plain.WriteByte(c)
+		i++
+	}
+	flush()
+}
+
+// wikiEffectEnd finds the character closing the text effect opened at s[i],
+// on the same line, or returns -1.
+func wikiEffectEnd(s string, i int) int {
+	c := s[i]
+	if i+1 >= len(s) || s[i+1] == ' ' || s[i+1] == '\n' || s[i+1] == c {
+		return -1
+	}
+	for j := i + 2; j < len(s) && s[j] != '\n'; j++ {
+		switch {
+		case s[j] == '\\':
+			j++
+		case s[j] == c && s[j-1] != ' ' && (j+1 == len(s) || !isWord(s[j+1])):
+			return j
+		}
+	}
+	return -1
+}
+
+// wikiLink reads [text|url], [url] or the mention [~username] at the start of
+// s, returning how much of s it took. Links to anchors and attachments keep
+// their text.
+func (in *inline) wikiLink(s string, marks []*Mark, flush func()) int {
+	end := -1
+	for j := 1; j < len(s) && end < 0; j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '\n', '[':
+			return 0
+		case ']':
+			end = j
+		}
+	}
+	if end <= 1 {
+		return 0
+	}
+	flush()
+	content := s[1:end]
+	if name, ok := strings.CutPrefix(content, "~"); ok {
+		in.nodes = append(in.nodes, &Node{Type: "mention", Attrs: map[string]interface{}{"id": name, "text": "@" + name}})
+		return end + 1
+	}
+	text, url, found := strings.Cut(content, "|")
+	if !found {
+		url = content
+	}
+	url, _, _ = strings.Cut(strings.TrimSpace(url), "|")
+	if strings.HasPrefix(url, "#") || strings.HasPrefix(url, "^") {
+		in.parseWiki(strings.TrimLeft(text, "#^"), marks)
+		return end + 1
+	}
+	in.parseWiki(text, append(append([]*Mark{}, marks...), linkMark(url)))
+	return end + 1
+}
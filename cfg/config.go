@@ -1,6 +1,7 @@
 package cfg
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,37 +10,128 @@ import (
 	"gopkg.in/yaml.v2"
 )
 
-const currentFormatVersion = 2
+const currentFormatVersion = 3
 
 type Task struct {
 	IssueID     string
 	Title       string
 	Description string
 	Type        string
+	Done        bool
+	// AcceptanceCriteria is looked up for PR descriptions, never stored
+	AcceptanceCriteria string `yaml:"-"`
+}
+
+// PRTitleOptions are the choices made for a PR title on top of the issue.
+type PRTitleOptions struct {
+	// Prefix is the Conventional Commits type, like feat or fix
+	Prefix   string
+	Scope    string
+	Breaking bool
+}
+
+// JiraAccount is the Jira gg talks to and who it is there. Profiles each
+// have their own.
+type JiraAccount struct {
+	JiraUser  string `yaml:"jira_user"`
+	JiraURL   string `yaml:"jira_url"`
+	JiraToken string `yaml:"jira_token"`
+	// JiraDeployment is JiraCloud or JiraServer, detected when empty
+	JiraDeployment string `yaml:"jira_deployment,omitempty"`
+	// JiraOAuth is the app gg login signs in with instead of the token
+	JiraOAuth *OAuth `yaml:"jira_oauth,omitempty"`
+	// JiraAccountID is looked up once: the account ID on Cloud, the user name
+	// on Server
+	JiraAccountID string `yaml:"jira_account_id,omitempty"`
 }
 
 type Config struct {
-	JiraUser    string `yaml:"jira_user"`
-	JiraURL     string `yaml:"jira_url"`
-	JiraToken   string `yaml:"jira_token"`
+	JiraAccount `yaml:",inline"`
 	JiraProject string `yaml:"jira_project"`
+	// Profiles are further Jira accounts, see cfg/profile.go
+	Profiles map[string]*Profile `yaml:"profiles,omitempty"`
+	// Profile is used unless another one is picked or matches the repository
+	Profile string `yaml:"profile,omitempty"`
+
+	GitHubToken string `yaml:"github_token,omitempty"`
+	GitLabToken string `yaml:"gitlab_token,omitempty"`
+	// BitbucketToken is used for both Bitbucket Cloud and Server
+	BitbucketToken string `yaml:"bitbucket_token,omitempty"`
+	AzureToken     string `yaml:"azure_token,omitempty"`
+	// TokenStore is where the tokens above are kept, see cfg/secrets.go
+	TokenStore string `yaml:"token_store,omitempty"`
+	// TokenCommands print a token instead, e.g. jira_token: pass show jira
+	TokenCommands map[string]string `yaml:"token_commands,omitempty"`
+	// Hosts maps self-hosted git servers to their kind, e.g. git.example.com: gitlab
+	Hosts map[string]string `yaml:"hosts,omitempty"`
+
+	BranchFormat    string            `yaml:"branch_format,omitempty"`
+	BranchLowercase *bool             `yaml:"branch_lowercase,omitempty"`
+	BranchMaxLength int               `yaml:"branch_max_length,omitempty"`
+	BranchTypes     map[string]string `yaml:"branch_types,omitempty"`
+	PRTitleFormat   string            `yaml:"pr_title_format,omitempty"`
+	PRTypes         map[string]string `yaml:"pr_types,omitempty"`
+	PRBodyFormat    string            `yaml:"pr_body_format,omitempty"`
+	BaseBranch      string            `yaml:"base_branch,omitempty"`
+	// AcceptanceCriteriaField is the Jira custom field, like customfield_10035
+	AcceptanceCriteriaField string `yaml:"acceptance_criteria_field,omitempty"`
+	// Transitions are keyed by Jira project since workflows differ between them
+	Transitions map[string]Transitions `yaml:"transitions,omitempty"`
+	Queries     map[string]string      `yaml:"queries,omitempty"`
+	Board       int                    `yaml:"board,omitempty"`
+	// IssueFields are default values of issue fields for gg new, by project
+	IssueFields map[string]map[string]string `yaml:"issue_fields,omitempty"`
 
 	FormatVersion int `yaml:"format_version"`
 
 	Tasks map[string]Task `yaml:"tasks"`
 
 	lock *sync.Mutex
+	// repo holds the overrides from .gg.yml, which are never saved to ~/.gg
+	repo     *Settings
+	repoFile string
+	// profile is the one in use, empty for the account above
+	profile string
+}
+
+type Transitions struct {
+	Start  string `yaml:"start,omitempty"`
+	Review string `yaml:"review,omitempty"`
+	Done   string `yaml:"done,omitempty"`
 }
 
+const (
+	defaultStartTransition = "In Progress"
+	defaultDoneTransition  = "Done"
+)
+
+// OAuth is an OAuth app registered for Jira Cloud. Its client secret and the
+// tokens are kept in the token store.
+type OAuth struct {
+	ClientID string `yaml:"client_id"`
+	// Port is where gg login takes the redirect, as registered for the app
+	Port int `yaml:"port,omitempty"`
+	// CloudID is the Jira site logged in to, empty when logged out
+	CloudID string `yaml:"cloud_id,omitempty"`
+}
+
+// Jira deployments, Data Center works the same as Server.
+const (
+	JiraCloud  = "cloud"
+	JiraServer = "server"
+)
+
+// JiraConfig is what the Jira clients work with: an account of the config,
+// its token from wherever it's kept and the project in effect. The account ID
+// the clients look up goes to the account, to be saved along with it.
 type JiraConfig struct {
-	JiraUser      string  `yaml:"jira_user"`
-	JiraURL       string  `yaml:"jira_url"`
-	JiraToken     string  `yaml:"jira_token"`
-	JiraProject   string  `yaml:"jira_project"`
-	JiraAccountID *string `yaml:"jira_account_id"`
+	*JiraAccount
+	// JiraToken is the token of the account, ~/.gg doesn't have it itself
+	JiraToken   string
+	JiraProject string
 }
 
-type AskForConfig func() *Config
+type AskForConfig func() (*Config, error)
 
 func NewConfg() Config {
 	return Config{
@@ -49,17 +141,26 @@ func NewConfg() Config {
 	}
 }
 
-func LoadOrCreateConfig(askForConfig AskForConfig) (*Config, error) {
+func configPath() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
+		return "", fmt.Errorf("failed to get home directory: %w", err)
 	}
+	return filepath.Join(homeDir, ".gg"), nil
+}
 
-	configPath := filepath.Join(homeDir, ".gg")
-	file, err := os.Open(configPath)
+func LoadOrCreateConfig(askForConfig AskForConfig) (*Config, error) {
+	configPath, err := configPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(configPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			newCfg := askForConfig()
+			newCfg, err := askForConfig()
+			if err != nil {
+				return nil, err
+			}
 			// Initialize fields that NewConfg would, and set current format version
 			newCfg.FormatVersion = currentFormatVersion
 			if newCfg.lock == nil {
@@ -68,33 +169,37 @@ func LoadOrCreateConfig(askForConfig AskForConfig) (*Config, error) {
 			if newCfg.Tasks == nil {
 				newCfg.Tasks = make(map[string]Task)
 			}
+			if _, err := newCfg.moveTokensToStore(); err != nil {
+				return nil, err
+			}
 			// Save will also ensure FormatVersion is currentFormatVersion
 			return newCfg, newCfg.Save()
 		}
 		return nil, fmt.Errorf("failed to open config file: %w", err)
 	}
-	defer func() {
-		_ = file.Close()
-	}()
-
-	var config Config
-	// Initialize non-yaml fields manually before decode
-	config.lock = &sync.Mutex{}
-	// Tasks map will be populated by decoder if present in YAML, otherwise needs init
-	// config.Tasks = make(map[string]Task) // Let decoder handle this or init after.
-
-	decoder := yaml.NewDecoder(file)
-	if err := decoder.Decode(&config); err != nil {
-		// If decoding fails, it might be an empty or malformed file.
-		// For this specific case, we might want to treat it as "not exist"
-		// and create a new one, but the current logic is to error out.
-		// For now, let's stick to the error.
-		return nil, fmt.Errorf("failed to decode config file: %w", err)
+	config, err := decodeConfig(data)
+	if err != nil {
+		return nil, err
 	}
-
-	// After successful decode, handle versioning and ensure essential maps/locks
-	if config.FormatVersion == 0 { // If FormatVersion was missing in the file (or explicitly 0)
-		config.FormatVersion = 1 // This is an old config (version 1)
+	if config.FormatVersion != currentFormatVersion {
+		// older ones are migrated, newer ones turned down before gg loses what it doesn't know
+		if data, err = migrateFile(configPath, data); err != nil {
+			return nil, err
+		}
+		if config, err = decodeConfig(data); err != nil {
+			return nil, err
+		}
+	} else if config.TokenStore == "" {
+		// tokens that had nowhere to go before, until there's a store or token_store says plain
+		moved, err := config.moveTokensToStore()
+		if err != nil {
+			return nil, err
+		}
+		if moved {
+			if err := config.Save(); err != nil {
+				return nil, fmt.Errorf("failed to save config: %w", err)
+			}
+		}
 	}
 
 	// Ensure Tasks map is initialized if it was nil (e.g. empty or old config file)
@@ -103,7 +208,15 @@ func LoadOrCreateConfig(askForConfig AskForConfig) (*Config, error) {
 	}
 	// Lock should have been initialized above.
 
-	return &config, nil
+	return config, nil
+}
+
+func decodeConfig(data []byte) (*Config, error) {
+	config := &Config{lock: &sync.Mutex{}}
+	if err := yaml.NewDecoder(bytes.NewReader(data)).Decode(config); err != nil {
+		return nil, fmt.Errorf("failed to decode config file: %w", err)
+	}
+	return config, nil
 }
 
 func (c *Config) Save() error {
@@ -111,20 +224,26 @@ func (c *Config) Save() error {
 	defer c.lock.Unlock()
 
 	c.FormatVersion = currentFormatVersion
+	return c.write()
+}
 
-	homeDir, err := os.UserHomeDir()
+// write stores the config as is, without upgrading the format version.
+func (c *Config) write() error {
+	configPath, err := configPath()
 	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
+		return err
 	}
-
-	configPath := filepath.Join(homeDir, ".gg")
-	file, err := os.Create(configPath)
+	file, err := os.OpenFile(configPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
 	if err != nil {
 		return fmt.Errorf("failed to create config file: %w", err)
 	}
 	defer func() {
 		_ = file.Close()
 	}()
+	// configs written before we cared about permissions
+	if err := file.Chmod(0o600); err != nil {
+		return fmt.Errorf("failed to restrict config file permissions: %w", err)
+	}
 
 	encoder := yaml.NewEncoder(file)
 	if err := encoder.Encode(c); err != nil {
@@ -134,12 +253,77 @@ func (c *Config) Save() error {
 	return nil
 }
 
-func (c *Config) AddTask(task *Task) {
+func (c *Config) AddTask(task *Task) error {
 	c.Tasks[task.IssueID] = *task
-	_ = c.Save()
+	return c.Save()
+}
+
+func (c *Config) FinishTask(id string) error {
+	task := c.Tasks[id]
+	task.IssueID = id
+	task.Done = true
+	c.Tasks[id] = task
+	return c.Save()
+}
+
+// RememberIssueFields keeps the values given to fields as the defaults for
+// the next issue in the project. Empty values aren't kept, and neither are
+// the fields .gg.yml sets, those are the repository's to change.
+func (c *Config) RememberIssueFields(project string, fields []IssueField) error {
+	fromRepo := func(field IssueField) bool {
+		if c.repo == nil {
+			return false
+		}
+		for key := range c.repo.IssueFields[project] {
+			if field.Matches(key) {
+				return true
+			}
+		}
+		return false
+	}
+	changed := false
+	for _, field := range fields {
+		if field.Value == "" || fromRepo(field) {
+			continue
+		}
+		if c.IssueFields == nil {
+			c.IssueFields = map[string]map[string]string{}
+		}
+		defaults := c.IssueFields[project]
+		if defaults == nil {
+			defaults = map[string]string{}
+			c.IssueFields[project] = defaults
+		}
+		key := field.ID
+		for k := range defaults {
+			if field.Matches(k) {
+				key = k
+			}
+		}
+		changed = changed || defaults[key] != field.Value
+		defaults[key] = field.Value
+	}
+	if !changed {
+		return nil
+	}
+	return c.Save()
 }
 
 func (c *Config) GetTask(id string) *Task {
 	task := c.Tasks[id]
 	return &task
 }
+
+// TransitionsFor returns the workflow transitions for a project. Starting work
+// moves issues to "In Progress" and finishing to "Done" unless configured
+// otherwise, moving them to review only happens when configured.
+func (c *Config) TransitionsFor(project string) Transitions {
+	transitions := c.Settings().Transitions[project]
+	if transitions.Start == "" {
+		transitions.Start = defaultStartTransition
+	}
+	if transitions.Done == "" {
+		transitions.Done = defaultDoneTransition
+	}
+	return transitions
+}
@@ -4,13 +4,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 
 	"gopkg.in/yaml.v2"
 )
 
-const testCurrentFormatVersion = 2
+const testCurrentFormatVersion = 3
 
 // Helper function to create a temporary config file
 func createTempConfigFile(t *testing.T, content string) string {
@@ -73,9 +74,9 @@ tasks: {}
 `
 	configFile := createTempConfigFile(t, configContent)
 
-	mockAskFn := func() *Config {
+	mockAskFn := func() (*Config, error) {
 		t.Error("AskForConfig should not be called when a config file exists")
-		return &Config{FormatVersion: 99, lock: &sync.Mutex{}} // Should not happen
+		return &Config{FormatVersion: 99, lock: &sync.Mutex{}}, nil // Should not happen
 	}
 
 	loadedCfg, err := LoadOrCreateConfig(mockAskFn)
@@ -83,8 +84,12 @@ tasks: {}
 		t.Fatalf("LoadOrCreateConfig failed: %v", err)
 	}
 
-	if loadedCfg.FormatVersion != 1 {
-		t.Errorf("Expected FormatVersion to be 1 for old config, got %d", loadedCfg.FormatVersion)
+	if loadedCfg.FormatVersion != testCurrentFormatVersion {
+		t.Errorf("Expected old config to be migrated to %d, got %d", testCurrentFormatVersion, loadedCfg.FormatVersion)
+	}
+	backup, err := os.ReadFile(configFile + ".v1.bak")
+	if err != nil || !strings.Contains(string(backup), "jira_user: testuser") || strings.Contains(string(backup), "sometoken") {
+		t.Errorf("Expected the old config to be backed up without its token, got %q, %v", backup, err)
 	}
 
 	// Ensure lock is initialized
@@ -131,18 +136,20 @@ func TestCreateNewConfig(t *testing.T) {
 	}
 
 	var askFnCalled bool
-	mockAskFn := func() *Config {
+	mockAskFn := func() (*Config, error) {
 		askFnCalled = true
 		// Create a new config like the main NewConfg would, including setting version
 		return &Config{
-			JiraUser:      "newuser",
-			JiraURL:       "https://new.jira.com",
-			JiraToken:     "newtoken",
+			JiraAccount: JiraAccount{
+				JiraUser:  "newuser",
+				JiraURL:   "https://new.jira.com",
+				JiraToken: "newtoken",
+			},
 			JiraProject:   "NEW",
 			FormatVersion: testCurrentFormatVersion, // NewConfg now sets this
 			Tasks:         map[string]Task{},
 			lock:          &sync.Mutex{},
-		}
+		}, nil
 	}
 
 	createdCfg, err := LoadOrCreateConfig(mockAskFn)
@@ -188,9 +195,9 @@ tasks: {}
 `, testCurrentFormatVersion)
 	configFile := createTempConfigFile(t, configContent)
 
-	mockAskFn := func() *Config {
+	mockAskFn := func() (*Config, error) {
 		t.Error("AskForConfig should not be called when a config file exists")
-		return &Config{FormatVersion: 99, lock: &sync.Mutex{}} // Should not happen
+		return &Config{FormatVersion: 99, lock: &sync.Mutex{}}, nil // Should not happen
 	}
 
 	loadedCfg, err := LoadOrCreateConfig(mockAskFn)
@@ -217,3 +224,21 @@ tasks: {}
 		t.Errorf("Expected FormatVersion in saved file to be %d, got %d", testCurrentFormatVersion, savedCfg.FormatVersion)
 	}
 }
+
+func TestTransitionsFor(t *testing.T) {
+	config := NewConfg()
+	config.Transitions = map[string]Transitions{
+		"OPS": {Start: "Doing", Review: "Code Review"},
+		"WEB": {Review: "In Review"},
+	}
+
+	if got := config.TransitionsFor("OPS"); got.Start != "Doing" || got.Review != "Code Review" {
+		t.Errorf("Expected configured transitions for OPS, got %+v", got)
+	}
+	if got := config.TransitionsFor("WEB"); got.Start != "In Progress" || got.Review != "In Review" {
+		t.Errorf("Expected default start transition for WEB, got %+v", got)
+	}
+	if got := config.TransitionsFor("NONE"); got.Start != "In Progress" || got.Review != "" || got.Done != "Done" {
+		t.Errorf("Expected only the default start and done transitions, got %+v", got)
+	}
+}
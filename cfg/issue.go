@@ -0,0 +1,100 @@
+package cfg
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// IssueDraft is what gg new asks for before it creates an issue.
+type IssueDraft struct {
+	TypeID      string
+	Type        string
+	Title       string
+	Description string
+	// Subtask limits the types to sub-task types, Parent is then required
+	Subtask bool
+	// Parent is the issue a sub-task goes under, or the epic of other issues
+	Parent string
+	Links  []IssueLink
+	// Fields are the other fields to set, with their values
+	Fields []IssueField
+}
+
+// IssueField is a field from the create screen of an issue type, besides the
+// ones gg always sets.
+type IssueField struct {
+	// ID is like priority or customfield_10016
+	ID       string
+	Name     string
+	Required bool
+	// Type is the Jira schema type like string, number, option or array, or
+	// textarea for rich text. Items is the type of the elements of arrays
+	Type  string
+	Items string
+	// Allowed are the names of the values to pick from, if the field has them
+	Allowed []string
+	// Value is what's entered, comma separated for arrays
+	Value string
+}
+
+// Matches tells whether a config key or flag refers to the field, by ID or name.
+func (f IssueField) Matches(key string) bool {
+	return key == f.ID || strings.EqualFold(key, f.Name)
+}
+
+// Multiple tells whether the field takes a list of values.
+func (f IssueField) Multiple() bool {
+	return f.Type == "array"
+}
+
+// Values splits the value of fields taking lists.
+func (f IssueField) Values() []string {
+	var values []string
+	for _, v := range strings.Split(f.Value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// IssueLink links the new issue to another one, like "blocks ABC-12".
+type IssueLink struct {
+	// Relation is how the new issue relates to Issue, one of the inward or
+	// outward descriptions of a Jira link type
+	Relation string `json:"relation" yaml:"relation"`
+	Issue    string `json:"issue" yaml:"issue"`
+}
+
+func (l IssueLink) String() string {
+	return l.Relation + " " + l.Issue
+}
+
+var issueLinkRe = regexp.MustCompile(`^\s*(.*\S)\s+([A-Za-z][A-Za-z0-9_]*-\d+)\s*$`)
+
+// ParseIssueLink reads links written like "blocks ABC-12" or
+// "relates to ABC-3".
+func ParseIssueLink(s string) (IssueLink, error) {
+	match := issueLinkRe.FindStringSubmatch(s)
+	if match == nil {
+		return IssueLink{}, fmt.Errorf("link %q should be a relation and an issue key, like \"blocks ABC-12\"", s)
+	}
+	return IssueLink{Relation: strings.ToLower(match[1]), Issue: strings.ToUpper(match[2])}, nil
+}
+
+// ParseIssueLinks reads a comma separated list of links, empty is none.
+func ParseIssueLinks(s string) ([]IssueLink, error) {
+	var links []IssueLink
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		link, err := ParseIssueLink(part)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, nil
+}
@@ -0,0 +1,20 @@
+package cfg
+
+import "testing"
+
+func TestParseIssueLinks(t *testing.T) {
+	links, err := ParseIssueLinks("blocks abc-12,  Relates to ABC-3 ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []IssueLink{{"blocks", "ABC-12"}, {"relates to", "ABC-3"}}
+	if len(links) != len(want) || links[0] != want[0] || links[1] != want[1] {
+		t.Errorf("ParseIssueLinks() = %+v, want %+v", links, want)
+	}
+
+	for _, bad := range []string{"ABC-12", "blocks", "blocks ABC"} {
+		if _, err := ParseIssueLink(bad); err == nil {
+			t.Errorf("ParseIssueLink(%q) succeeded, want an error", bad)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package cfg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ErrUnknownKey is returned for keys ~/.gg has no place for.
+var ErrUnknownKey = errors.New("unknown key")
+
+// managedKeys are kept by gg itself.
+var managedKeys = []string{"format_version", "tasks", "profiles", "jira_oauth", "jira_account_id"}
+
+// tokenKeys are settings that are kept in the token store.
+var tokenKeys = []string{JiraTokenKey, "github_token", "gitlab_token", "bitbucket_token", "azure_token"}
+
+const sourceTokenStore = "token store"
+
+// SplitProfileKey splits profiles.NAME.key into the profile and the key, other
+// keys belong to the default profile.
+func SplitProfileKey(key string) (string, string) {
+	if rest, ok := strings.CutPrefix(key, "profiles."); ok {
+		if profile, name, ok := strings.Cut(rest, "."); ok {
+			return profile, name
+		}
+	}
+	return DefaultProfile, key
+}
+
+// IsTokenKey tells whether the key is a token, which is kept in the token
+// store rather than ~/.gg.
+func IsTokenKey(key string) bool {
+	_, name := SplitProfileKey(key)
+	return slices.Contains(tokenKeys, name)
+}
+
+// checkKey splits the key into its path in ~/.gg, and the key of the token
+// store for tokens.
+func (c *Config) checkKey(key string) ([]string, string, error) {
+	profile, name := SplitProfileKey(key)
+	if profile != DefaultProfile {
+		if _, ok := c.Profiles[profile]; !ok {
+			return nil, "", fmt.Errorf("unknown profile %q", profile)
+		}
+	}
+	path := strings.Split(name, ".")
+	if slices.Contains(managedKeys, path[0]) {
+		return nil, "", fmt.Errorf("%s is kept by gg, use the gg commands for it", path[0])
+	}
+	if slices.Contains(tokenKeys, name) {
+		if profile != DefaultProfile && name != JiraTokenKey {
+			return nil, "", fmt.Errorf("%w %s, profiles only have their own jira_token", ErrUnknownKey, key)
+		}
+		return nil, ProfileKey(profile, name), nil
+	}
+	// host names have dots in them
+	if path[0] == "hosts" && len(path) > 2 {
+		path = []string{"hosts", strings.Join(path[1:], ".")}
+	}
+	if profile != DefaultProfile {
+		path = append([]string{"profiles", profile}, path...)
+	}
+	return path, "", nil
+}
+
+// Lookup finds the value of a key: tokens where they're kept, settings as in
+// effect and anything else as it is in ~/.gg.
+func (c *Config) Lookup(key string) (Setting, error) {
+	path, tokenKey, err := c.checkKey(key)
+	if err != nil {
+		return Setting{}, err
+	}
+	if tokenKey != "" {
+		token, err := c.token(tokenKey)
+		return Setting{key, token, sourceTokenStore}, err
+	}
+	for _, setting := range c.ListSettings() {
+		if setting.Key == key {
+			return setting, nil
+		}
+	}
+	if !c.knownKey(key) {
+		return Setting{}, fmt.Errorf("%w %s", ErrUnknownKey, key)
+	}
+	doc, err := c.document()
+	if err != nil {
+		return Setting{}, err
+	}
+	value := lookupPath(doc, path)
+	source := sourceGlobal
+	if path[0] == "profiles" {
+		source = "profile " + path[1]
+	}
+	switch value.(type) {
+	case nil:
+		return Setting{key, "", source}, nil
+	case yaml.MapSlice, []any:
+		data, err := yaml.Marshal(value)
+		return Setting{key, strings.TrimSpace(string(data)), source}, err
+	}
+	return Setting{key, fmt.Sprint(value), source}, nil
+}
+
+// Set changes a key of ~/.gg, like board or transitions.WEB.review, and
+// profiles.NAME.key for profiles. Values are YAML when they don't fit as text,
+// so lists can be set as [a, b]. The caller saves the config.
+func (c *Config) Set(key, value string) error {
+	if value == "" {
+		return c.Unset(key)
+	}
+	path, tokenKey, err := c.checkKey(key)
+	if err != nil {
+		return err
+	}
+	if tokenKey != "" {
+		return c.setToken(tokenKey, value)
+	}
+	err = c.change(key, path, value)
+	var parsed any
+	if err == nil || errors.Is(err, ErrUnknownKey) || yaml.Unmarshal([]byte(value), &parsed) != nil {
+		return err
+	}
+	if _, ok := parsed.(string); ok {
+		return err
+	}
+	return c.change(key, path, parsed)
+}
+
+// Unset removes a key from ~/.gg so the default is used again, the caller
+// saves the config.
+func (c *Config) Unset(key string) error {
+	path, tokenKey, err := c.checkKey(key)
+	if err != nil {
+		return err
+	}
+	if tokenKey != "" {
+		return c.deleteToken(tokenKey)
+	}
+	if !c.knownKey(key) {
+		return fmt.Errorf("%w %s", ErrUnknownKey, key)
+	}
+	return c.change(key, path, nil)
+}
+
+// knownKey tells whether ~/.gg has a place for the key, by trying to put
+// something there.
+func (c *Config) knownKey(key string) bool {
+	path, tokenKey, err := c.checkKey(key)
+	if err != nil || tokenKey != "" {
+		return err == nil
+	}
+	scratch := *c
+	err = scratch.change(key, path, "")
+	return !errors.Is(err, ErrUnknownKey)
+}
+
+// Edit hands a copy of ~/.gg to edit and takes the changes when they make a
+// valid config, which check gets to look at first. The copy sits next to
+// ~/.gg, readable only by its owner, and is removed either way.
+func (c *Config) Edit(edit func(path string) error, check func(*Config) error) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	// CreateTemp makes it 0600, it has the tokens of a plain token store
+	file, err := os.CreateTemp(filepath.Dir(path), ".gg-*.yml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	_, err = file.Write(original)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := edit(file.Name()); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(file.Name())
+	if err != nil {
+		return err
+	}
+	changed := NewConfg()
+	if err := yaml.UnmarshalStrict(data, &changed); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	if err := changed.Validate(); err != nil {
+		return err
+	}
+	if err := check(&changed); err != nil {
+		return err
+	}
+	if bytes.Equal(data, original) {
+		return nil
+	}
+	// as written, comments and all
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	c.replace(&changed)
+	return nil
+}
+
+// document is the config as YAML to change keys in.
+func (c *Config) document() (yaml.MapSlice, error) {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.MapSlice
+	return doc, yaml.Unmarshal(data, &doc)
+}
+
+// change sets the value at path, or removes it for nil, and takes the result
+// if it's a valid config.
+func (c *Config) change(key string, path []string, value any) error {
+	doc, err := c.document()
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(setPath(doc, path, value))
+	if err != nil {
+		return err
+	}
+	changed := NewConfg()
+	if err := yaml.UnmarshalStrict(data, &changed); err != nil {
+		return decodeError(key, err)
+	}
+	if err := changed.Validate(); err != nil {
+		return err
+	}
+	c.replace(&changed)
+	return nil
+}
+
+// replace takes over the values of another config, keeping what isn't
+// stored.
+func (c *Config) replace(changed *Config) {
+	lock, repo, repoFile, profile := c.lock, c.repo, c.repoFile, c.profile
+	*c = *changed
+	c.lock, c.repo, c.repoFile = lock, repo, repoFile
+	if _, ok := c.Profiles[profile]; ok {
+		c.profile = profile
+	}
+}
+
+func lookupPath(doc yaml.MapSlice, path []string) any {
+	for _, item := range doc {
+		if fmt.Sprint(item.Key) != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			return item.Value
+		}
+		child, _ := item.Value.(yaml.MapSlice)
+		return lookupPath(child, path[1:])
+	}
+	return nil
+}
+
+func setPath(doc yaml.MapSlice, path []string, value any) yaml.MapSlice {
+	for i, item := range doc {
+		if fmt.Sprint(item.Key) != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			if value == nil {
+				return slices.Delete(doc, i, i+1)
+			}
+			doc[i].Value = value
+			return doc
+		}
+		child, _ := item.Value.(yaml.MapSlice)
+		doc[i].Value = setPath(child, path[1:], value)
+		return doc
+	}
+	if value == nil {
+		return doc
+	}
+	if len(path) == 1 {
+		return append(doc, yaml.MapItem{Key: path[0], Value: value})
+	}
+	return append(doc, yaml.MapItem{Key: path[0], Value: setPath(nil, path[1:], value)})
+}
+
+// decodeError explains why a changed config doesn't decode, without the line
+// numbers of YAML no one has seen.
+func decodeError(key string, err error) error {
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) || len(typeErr.Errors) == 0 {
+		return err
+	}
+	message := typeErr.Errors[0]
+	if _, rest, ok := strings.Cut(message, ": "); ok && strings.HasPrefix(message, "line ") {
+		message = rest
+	}
+	if strings.HasPrefix(message, "field ") && strings.Contains(message, " not found in type") {
+		return fmt.Errorf("%w %s", ErrUnknownKey, key)
+	}
+	return fmt.Errorf("invalid value for %s: %s", key, message)
+}
+
+// Validate checks the values gg can check without asking Jira.
+func (c *Config) Validate() error {
+	switch c.TokenStore {
+	case "", TokenStoreKeyring, TokenStoreFile, TokenStorePlain:
+	default:
+		return fmt.Errorf("unknown token_store %q, use %s, %s or %s",
+			c.TokenStore, TokenStoreKeyring, TokenStoreFile, TokenStorePlain)
+	}
+	accounts := map[string]*JiraAccount{"": &c.JiraAccount}
+	for name, profile := range c.Profiles {
+		if err := CheckProfileName(name); err != nil {
+			return err
+		}
+		if profile == nil {
+			return fmt.Errorf("profile %s is empty", name)
+		}
+		accounts["profiles."+name+"."] = &profile.JiraAccount
+	}
+	for prefix, account := range accounts {
+		switch account.JiraDeployment {
+		case "", JiraCloud, JiraServer:
+		default:
+			return fmt.Errorf("unknown %sjira_deployment %q, use %s or %s", prefix, account.JiraDeployment, JiraCloud, JiraServer)
+		}
+		if account.JiraURL == "" {
+			continue
+		}
+		if u, err := url.Parse(account.JiraURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("%sjira_url %q should be like https://example.atlassian.net", prefix, account.JiraURL)
+		}
+	}
+	if _, ok := c.Profiles[c.Profile]; c.Profile != "" && !ok {
+		return fmt.Errorf("unknown profile %q", c.Profile)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package cfg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetAndUnset(t *testing.T) {
+	config := loadProfiles(t)
+	changes := []struct{ key, value string }{
+		{"jira_project", "NO"},
+		{"branch_lowercase", "true"},
+		{"board", "42"},
+		{"transitions.WEB.review", "Code Review"},
+		{"hosts.git.example.com", "gitlab"},
+		{"profiles.acme.remotes", "[github.com/acme/*, gitlab.com/acme/*]"},
+		{"profile", "acme"},
+	}
+	for _, change := range changes {
+		if err := config.Set(change.key, change.value); err != nil {
+			t.Fatalf("Set(%s) failed: %v", change.key, err)
+		}
+	}
+
+	// text stays text, even when YAML would make it a bool
+	if config.JiraProject != "NO" || config.BranchLowercase == nil || !*config.BranchLowercase || config.Board != 42 {
+		t.Errorf("Set() gave project %q, lowercase %v, board %d", config.JiraProject, config.BranchLowercase, config.Board)
+	}
+	if config.Transitions["WEB"].Review != "Code Review" || config.Hosts["git.example.com"] != "gitlab" {
+		t.Errorf("Set() gave transitions %+v and hosts %+v", config.Transitions, config.Hosts)
+	}
+	if remotes := config.Profiles["acme"].Remotes; len(remotes) != 2 || config.Profile != "acme" {
+		t.Errorf("Set() gave remotes %v and profile %q", remotes, config.Profile)
+	}
+	if config.lock == nil || len(config.Tasks) != 0 {
+		t.Error("Set() lost what isn't stored")
+	}
+
+	if err := config.Unset("board"); err != nil {
+		t.Fatalf("Unset() failed: %v", err)
+	}
+	if err := config.Unset("transitions.WEB.review"); err != nil {
+		t.Fatalf("Unset() failed: %v", err)
+	}
+	if config.Board != 0 || config.Transitions["WEB"].Review != "" {
+		t.Errorf("Unset() left board %d and transitions %+v", config.Board, config.Transitions)
+	}
+}
+
+func TestSetRejects(t *testing.T) {
+	tests := []struct {
+		key, value string
+		unknown    bool
+	}{
+		{key: "nope", value: "x", unknown: true},
+		{key: "transitions.WEB.nope", value: "x", unknown: true},
+		{key: "profiles.acme.github_token", value: "x", unknown: true},
+		{key: "board", value: "abc"},
+		{key: "branch_lowercase", value: "maybe"},
+		{key: "token_store", value: "drawer"},
+		{key: "jira_url", value: "example.com"},
+		{key: "profiles.acme.jira_deployment", value: "cloudy"},
+		{key: "profile", value: "nope"},
+		{key: "profiles.nope.jira_url", value: "https://example.com"},
+		{key: "tasks", value: "{}"},
+		{key: "format_version", value: "3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			config := loadProfiles(t)
+			err := config.Set(tt.key, tt.value)
+			if err == nil {
+				t.Fatalf("Set(%s, %s) succeeded", tt.key, tt.value)
+			}
+			if errors.Is(err, ErrUnknownKey) != tt.unknown {
+				t.Errorf("Set(%s) = %v, unknown key: %v", tt.key, err, tt.unknown)
+			}
+			if strings.Contains(err.Error(), "line ") {
+				t.Errorf("Set(%s) = %v, which points to YAML no one has seen", tt.key, err)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	config := loadProfiles(t)
+	tests := []struct{ key, value, source string }{
+		{"jira_project", "OPS", sourceGlobal},
+		{"pr_title_format", DefaultPRTitleFormat, sourceDefault},
+		{"jira_url", "https://example.atlassian.net", sourceGlobal},
+		{"jira_token", "default-token", sourceTokenStore},
+		{"profiles.acme.jira_token", "acme-token", sourceTokenStore},
+		{"profiles.acme.jira_url", "https://jira.acme.com", "profile acme"},
+		{"profiles.acme.remotes", "- github.com/acme/*", "profile acme"},
+		{"queries.missing", "", sourceGlobal},
+	}
+	for _, tt := range tests {
+		setting, err := config.Lookup(tt.key)
+		if err != nil {
+			t.Errorf("Lookup(%s) failed: %v", tt.key, err)
+			continue
+		}
+		if setting.Value != tt.value || setting.Source != tt.source {
+			t.Errorf("Lookup(%s) = %q from %s, want %q from %s", tt.key, setting.Value, setting.Source, tt.value, tt.source)
+		}
+	}
+	if _, err := config.Lookup("nope"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Lookup(nope) = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestEdit(t *testing.T) {
+	configFile := createTempConfigFile(t, profilesConfig)
+	config := loadProfiles(t)
+	config.JiraToken = ""
+	noCheck := func(*Config) error { return nil }
+
+	err := config.Edit(func(path string) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		edited := strings.Replace(string(data), "jira_project: OPS", "# edited\njira_project: WEB", 1)
+		return os.WriteFile(path, []byte(edited), 0o600)
+	}, noCheck)
+	if err != nil {
+		t.Fatalf("Edit() failed: %v", err)
+	}
+	if config.JiraProject != "WEB" {
+		t.Errorf("Edit() left the project at %q", config.JiraProject)
+	}
+	if data, _ := os.ReadFile(configFile); !strings.Contains(string(data), "# edited") {
+		t.Errorf("Edit() didn't save the file as written:\n%s", data)
+	}
+
+	var copied string
+	err = config.Edit(func(path string) error {
+		copied = path
+		if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+			t.Errorf("Edit() handed %s with %v, %v", path, info.Mode(), err)
+		}
+		return os.WriteFile(path, []byte("jira_project: WEB\nboard: soon\n"), 0o600)
+	}, noCheck)
+	if err == nil || !strings.Contains(err.Error(), "invalid config") {
+		t.Errorf("Edit() with a broken config = %v", err)
+	}
+	if filepath.Dir(copied) != filepath.Dir(configFile) {
+		t.Errorf("Edit() made the copy in %s, not next to %s", copied, configFile)
+	}
+	if _, statErr := os.Stat(copied); !os.IsNotExist(statErr) {
+		t.Errorf("Edit() left the copy behind: %v", statErr)
+	}
+
+	refused := errors.New("no thanks")
+	err = config.Edit(func(path string) error {
+		return os.WriteFile(path, []byte("jira_project: ABC\n"), 0o600)
+	}, func(*Config) error { return refused })
+	if !errors.Is(err, refused) || config.JiraProject != "WEB" {
+		t.Errorf("Edit() = %v with project %q, want the check to stop it", err, config.JiraProject)
+	}
+	if data, _ := os.ReadFile(filepath.Clean(configFile)); strings.Contains(string(data), "ABC") {
+		t.Error("Edit() saved changes the check turned down")
+	}
+}
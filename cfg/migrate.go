@@ -0,0 +1,211 @@
+package cfg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// migration turns ~/.gg of one format version into the next. It works on the
+// YAML as written, so nothing of the old format gets lost in decoding. When a
+// step changes anything the file is written anew, without its comments, which
+// the backup still has.
+type migration struct {
+	from  int
+	apply func(doc yaml.MapSlice) (yaml.MapSlice, error)
+}
+
+// migrations take ~/.gg from format version 1, which had no format_version,
+// to currentFormatVersion, one version at a time and in order.
+var migrations = []migration{
+	// 2 only started writing the format version
+	{from: 1, apply: keep},
+	// 3 keeps the tokens in the token store and remembers the Jira account ID,
+	// which is looked up on first use
+	{from: 2, apply: moveTokens},
+}
+
+func keep(doc yaml.MapSlice) (yaml.MapSlice, error) {
+	return doc, nil
+}
+
+// moveTokens takes the tokens out of ~/.gg and into the token store. Unless
+// token_store is plain, or there's no store to put them in.
+func moveTokens(doc yaml.MapSlice) (yaml.MapSlice, error) {
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	moved, err := config.moveTokensToStore()
+	if err != nil || !moved {
+		return doc, err
+	}
+	for key := range config.plaintextTokens() {
+		path := []string{key}
+		if name, profile, ok := strings.Cut(key, "@"); ok {
+			path = []string{"profiles", profile, name}
+		}
+		doc = setPath(doc, path, nil)
+	}
+	return setPath(doc, []string{"token_store"}, config.TokenStore), nil
+}
+
+// formatVersion is the version ~/.gg was written with.
+func formatVersion(doc yaml.MapSlice) (int, error) {
+	switch version := lookupPath(doc, []string{"format_version"}).(type) {
+	case nil:
+		return 1, nil
+	case int:
+		if version == 0 {
+			return 1, nil
+		}
+		return version, nil
+	default:
+		return 0, fmt.Errorf("format_version %v isn't a number", version)
+	}
+}
+
+var formatVersionLine = regexp.MustCompile(`(?m)^format_version:.*$`)
+
+// migrate brings data up to the target version with the steps, returning the
+// version it had before and whether a step changed anything. Data no step
+// changed only gets its format_version updated, comments and all.
+func migrate(data []byte, target int, steps []migration) ([]byte, int, bool, error) {
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, 0, false, err
+	}
+	from, err := formatVersion(doc)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if from > target {
+		return nil, from, false, fmt.Errorf("~/.gg is format version %d and this gg only knows up to %d, update gg", from, target)
+	}
+	if from == target {
+		return data, from, false, nil
+	}
+	changed := false
+	for version := from; version < target; version++ {
+		i := indexOfStep(steps, version)
+		if i < 0 {
+			return nil, from, false, fmt.Errorf("no way to migrate ~/.gg from format version %d", version)
+		}
+		before, err := yaml.Marshal(doc)
+		if err != nil {
+			return nil, from, false, err
+		}
+		if doc, err = steps[i].apply(doc); err != nil {
+			return nil, from, false, fmt.Errorf("failed to migrate ~/.gg from format version %d: %w", version, err)
+		}
+		after, err := yaml.Marshal(doc)
+		if err != nil {
+			return nil, from, false, err
+		}
+		changed = changed || !bytes.Equal(before, after)
+		doc = setPath(doc, []string{"format_version"}, version+1)
+	}
+	if changed {
+		migrated, err := yaml.Marshal(doc)
+		return migrated, from, true, err
+	}
+	version := fmt.Sprintf("format_version: %d", target)
+	if formatVersionLine.Match(data) {
+		return formatVersionLine.ReplaceAll(data, []byte(version)), from, false, nil
+	}
+	if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+		data = append(data, '\n')
+	}
+	return append(data, version+"\n"...), from, false, nil
+}
+
+func indexOfStep(steps []migration, from int) int {
+	for i, step := range steps {
+		if step.from == from {
+			return i
+		}
+	}
+	return -1
+}
+
+// migrateFile upgrades ~/.gg to the current format version. When a step
+// changed it, the old file is kept next to it as ~/.gg.vN.bak, without the
+// tokens that went to the token store. It returns the data to load.
+func migrateFile(path string, data []byte) ([]byte, error) {
+	migrated, from, changed, err := migrate(data, currentFormatVersion, migrations)
+	if err != nil || from == currentFormatVersion {
+		return migrated, err
+	}
+	if !changed {
+		if err := writePrivate(path, migrated); err != nil {
+			return nil, fmt.Errorf("failed to write config file: %w", err)
+		}
+		return migrated, nil
+	}
+	backup, err := writeBackup(fmt.Sprintf("%s.v%d", path, from), withoutMovedTokens(data, migrated))
+	if err != nil {
+		return nil, fmt.Errorf("failed to back up config file: %w", err)
+	}
+	if err := writePrivate(path, migrated); err != nil {
+		return nil, fmt.Errorf("failed to write config file, the old one is in %s: %w", backup, err)
+	}
+	return migrated, nil
+}
+
+// writePrivate writes a file only its owner can read, also when it was
+// written before we cared about permissions.
+func writePrivate(path string, data []byte) error {
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return err
+	}
+	return os.Chmod(path, 0o600)
+}
+
+// writeBackup writes data to prefix.bak, or prefix.1.bak and so on when there
+// is one already, returning where it went.
+func writeBackup(prefix string, data []byte) (string, error) {
+	for i := 0; ; i++ {
+		path := prefix + ".bak"
+		if i > 0 {
+			path = fmt.Sprintf("%s.%d.bak", prefix, i)
+		}
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		_, err = file.Write(data)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		return path, err
+	}
+}
+
+var tokenLine = regexp.MustCompile(`(?m)^(\s*(?:` + strings.Join(tokenKeys, "|") + `):).*$`)
+
+// withoutMovedTokens blanks the tokens in the old file when the migrated one
+// has none left, they're in the token store now.
+func withoutMovedTokens(old, migrated []byte) []byte {
+	var config Config
+	if err := yaml.Unmarshal(migrated, &config); err != nil {
+		return old
+	}
+	for _, token := range config.plaintextTokens() {
+		if *token != "" {
+			return old
+		}
+	}
+	return tokenLine.ReplaceAll(old, []byte(`$1 ""`))
+}
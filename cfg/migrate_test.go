@@ -0,0 +1,192 @@
+package cfg
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bricktopab/gg/secrets"
+	"gopkg.in/yaml.v2"
+)
+
+// version1Config is ~/.gg as gg wrote it before format_version.
+const version1Config = `# my setup
+jira_user: testuser
+jira_url: https://test.jira.com
+jira_project: TEST
+tasks:
+  TEST-1:
+    issueid: TEST-1
+    title: Old task
+`
+
+// version2Config is ~/.gg with the tokens still in it.
+const version2Config = `# my setup
+jira_user: testuser
+jira_url: https://test.jira.com
+jira_token: sometoken
+github_token: ghp_123
+jira_project: TEST
+profiles:
+  acme:
+    jira_url: https://jira.acme.com
+    jira_token: acmetoken
+format_version: 2
+`
+
+func TestMigrationsInOrder(t *testing.T) {
+	for i, step := range migrations {
+		if step.from != i+1 {
+			t.Fatalf("migration %d is from version %d, want %d", i, step.from, i+1)
+		}
+	}
+	if last := migrations[len(migrations)-1].from + 1; last != currentFormatVersion {
+		t.Errorf("migrations end at version %d, want %d", last, currentFormatVersion)
+	}
+}
+
+// migrateStep runs the one migration from the version.
+func migrateStep(t *testing.T, from int, data string) (string, bool) {
+	t.Helper()
+	step := migrations[indexOfStep(migrations, from)]
+	migrated, version, changed, err := migrate([]byte(data), from+1, []migration{step})
+	if err != nil || version != from {
+		t.Fatalf("migrate() gave version %d, %v", version, err)
+	}
+	var config Config
+	if err := yaml.UnmarshalStrict(migrated, &config); err != nil {
+		t.Fatalf("migrated config doesn't decode: %v\n%s", err, migrated)
+	}
+	if config.FormatVersion != from+1 {
+		t.Errorf("migrated config has version %d", config.FormatVersion)
+	}
+	return string(migrated), changed
+}
+
+func TestMigrateFrom1(t *testing.T) {
+	migrated, changed := migrateStep(t, 1, version1Config)
+	if changed || migrated != version1Config+"format_version: 2\n" {
+		t.Errorf("migrate() changed %v to\n%s", changed, migrated)
+	}
+}
+
+func TestMigrateFrom2(t *testing.T) {
+	createTempConfigFile(t, "")
+	migrated, changed := migrateStep(t, 2, version2Config)
+	if !changed {
+		t.Error("migrate() didn't change a config with tokens")
+	}
+	for _, token := range []string{"sometoken", "ghp_123", "acmetoken"} {
+		if strings.Contains(migrated, token) {
+			t.Errorf("migrate() left %s in the config:\n%s", token, migrated)
+		}
+	}
+	if !strings.Contains(migrated, "token_store: keyring") || !strings.Contains(migrated, "jira_url: https://jira.acme.com") {
+		t.Errorf("migrate() gave\n%s", migrated)
+	}
+	keyring := &secrets.Keyring{}
+	if token, _ := keyring.Get(ProfileKey("acme", JiraTokenKey)); token != "acmetoken" {
+		t.Errorf("profile token in the keyring is %q", token)
+	}
+	if token, _ := keyring.Get("github_token"); token != "ghp_123" {
+		t.Errorf("github token in the keyring is %q", token)
+	}
+
+	plain := strings.Replace(version2Config, "format_version: 2", "token_store: plain\nformat_version: 2", 1)
+	migrated, changed = migrateStep(t, 2, plain)
+	if changed || !strings.HasPrefix(migrated, "# my setup\n") || !strings.Contains(migrated, "jira_token: sometoken") {
+		t.Errorf("migrate() with token_store plain changed %v to\n%s", changed, migrated)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	rename := migration{from: 3, apply: func(doc yaml.MapSlice) (yaml.MapSlice, error) {
+		for i, item := range doc {
+			if item.Key == "jira_project" {
+				doc[i].Key = "project"
+			}
+		}
+		return doc, nil
+	}}
+	steps := append(append([]migration{}, migrations...), rename)
+
+	data, from, changed, err := migrate([]byte(version1Config), 4, steps)
+	if err != nil || from != 1 || !changed {
+		t.Fatalf("migrate() gave version %d, changed %v, %v", from, changed, err)
+	}
+	want := "jira_user: testuser\njira_url: https://test.jira.com\nproject: TEST\n"
+	if !strings.HasPrefix(string(data), want) || !strings.HasSuffix(string(data), "format_version: 4\n") {
+		t.Errorf("migrate() gave\n%s", data)
+	}
+
+	current := fmt.Sprintf("format_version: %d\n", currentFormatVersion)
+	if data, _, _, err := migrate([]byte(current), currentFormatVersion, migrations); err != nil || string(data) != current {
+		t.Errorf("migrate() changed a current config to %q, %v", data, err)
+	}
+	if _, _, _, err := migrate([]byte("format_version: 9\n"), currentFormatVersion, migrations); err == nil ||
+		!strings.Contains(err.Error(), "update gg") {
+		t.Errorf("migrate() of a newer config gave %v", err)
+	}
+	if _, _, _, err := migrate([]byte(version1Config), 5, steps); err == nil {
+		t.Error("migrate() went past the last step")
+	}
+}
+
+func TestLoadMigratesConfig(t *testing.T) {
+	configFile := createTempConfigFile(t, version2Config)
+	// from an earlier upgrade that failed
+	if err := os.WriteFile(configFile+".v2.bak", []byte("earlier"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadOrCreateConfig(func() (*Config, error) {
+		t.Fatal("AskForConfig should not be called when a config file exists")
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("LoadOrCreateConfig failed: %v", err)
+	}
+	if config.FormatVersion != currentFormatVersion || config.TokenStore != TokenStoreKeyring {
+		t.Errorf("loaded version %d with token_store %q", config.FormatVersion, config.TokenStore)
+	}
+	if token, _ := config.Token(JiraTokenKey); token != "sometoken" {
+		t.Errorf("Token() = %q", token)
+	}
+	if earlier, _ := os.ReadFile(configFile + ".v2.bak"); string(earlier) != "earlier" {
+		t.Errorf("earlier backup was overwritten with %q", earlier)
+	}
+	backup, err := os.ReadFile(configFile + ".v2.1.bak")
+	if err != nil || !strings.HasPrefix(string(backup), "# my setup\n") || strings.Contains(string(backup), "sometoken") {
+		t.Errorf("backup is %q, %v", backup, err)
+	}
+	if saved := loadConfigFromFile(t, configFile); saved.FormatVersion != currentFormatVersion || saved.JiraToken != "" {
+		t.Errorf("migrated file has version %d and token %q", saved.FormatVersion, saved.JiraToken)
+	}
+}
+
+func TestLoadKeepsUnchangedConfig(t *testing.T) {
+	configFile := createTempConfigFile(t, version1Config)
+
+	if _, err := LoadOrCreateConfig(func() (*Config, error) { return nil, nil }); err != nil {
+		t.Fatalf("LoadOrCreateConfig failed: %v", err)
+	}
+	want := version1Config + fmt.Sprintf("format_version: %d\n", currentFormatVersion)
+	if data, _ := os.ReadFile(configFile); string(data) != want {
+		t.Errorf("config file is\n%s", data)
+	}
+	if _, err := os.Stat(configFile + ".v1.bak"); !os.IsNotExist(err) {
+		t.Errorf("backup of a config no step changed: %v", err)
+	}
+}
+
+func TestLoadRejectsNewerConfig(t *testing.T) {
+	configFile := createTempConfigFile(t, "format_version: 99\njira_user: testuser\n")
+
+	if _, err := LoadOrCreateConfig(func() (*Config, error) { return nil, nil }); err == nil {
+		t.Fatal("LoadOrCreateConfig took a config of a newer gg")
+	}
+	if data, _ := os.ReadFile(configFile); string(data) != "format_version: 99\njira_user: testuser\n" {
+		t.Errorf("config file changed to %q", data)
+	}
+}
@@ -0,0 +1,226 @@
+package cfg
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// DefaultProfile names the account at the top of ~/.gg, which is used when
+// no other profile is.
+const DefaultProfile = "default"
+
+// Profile is another Jira account, for working on more than one Jira site.
+// It's picked with --profile, by matching the repository or as the default.
+type Profile struct {
+	JiraAccount `yaml:",inline"`
+	// Settings override ~/.gg while the profile is in use, .gg.yml still wins
+	Settings `yaml:",inline"`
+	// Remotes are patterns of the origin repository, like github.com/acme/*
+	Remotes []string `yaml:"remotes,omitempty"`
+	// Dirs are patterns of directories the profile is for, subdirectories
+	// included, like ~/work/acme
+	Dirs []string `yaml:"dirs,omitempty"`
+}
+
+// ProfileInfo is a profile as shown to users.
+type ProfileInfo struct {
+	Name     string   `json:"name" yaml:"name"`
+	JiraURL  string   `json:"jira_url" yaml:"jira_url"`
+	JiraUser string   `json:"jira_user" yaml:"jira_user"`
+	Remotes  []string `json:"remotes,omitempty" yaml:"remotes,omitempty"`
+	Dirs     []string `json:"dirs,omitempty" yaml:"dirs,omitempty"`
+	Default  bool     `json:"default" yaml:"default"`
+	Active   bool     `json:"active" yaml:"active"`
+}
+
+// jiraTokenKeys are the tokens each profile has its own of.
+var jiraTokenKeys = []string{JiraTokenKey, JiraOAuthTokenKey, JiraOAuthSecretKey}
+
+// ProfileKey is the key a Jira token of a profile is kept under, like
+// jira_token@acme.
+func ProfileKey(profile, key string) string {
+	if profile == "" || profile == DefaultProfile {
+		return key
+	}
+	return key + "@" + profile
+}
+
+// tokenKey points the Jira tokens at the ones of the profile in use.
+func (c *Config) tokenKey(key string) string {
+	if slices.Contains(jiraTokenKeys, key) {
+		return ProfileKey(c.profile, key)
+	}
+	return key
+}
+
+// SelectProfile picks the profile to use: the named one, otherwise the first
+// whose patterns match the directory or origin repository (host/owner/name),
+// otherwise the default set with gg profile use.
+func (c *Config) SelectProfile(name, dir, repo string) error {
+	if name != "" {
+		return c.UseProfile(name)
+	}
+	for _, candidate := range sortedKeys(c.Profiles) {
+		if c.Profiles[candidate].matches(dir, repo) {
+			return c.UseProfile(candidate)
+		}
+	}
+	if c.Profile != "" {
+		return c.UseProfile(c.Profile)
+	}
+	return nil
+}
+
+// UseProfile switches to the named profile for the rest of the run.
+func (c *Config) UseProfile(name string) error {
+	if name == DefaultProfile {
+		c.profile = ""
+		return nil
+	}
+	if _, ok := c.Profiles[name]; !ok {
+		return fmt.Errorf("unknown profile %q, add it with gg profile add", name)
+	}
+	c.profile = name
+	return nil
+}
+
+// ActiveProfile is the name of the profile in use.
+func (c *Config) ActiveProfile() string {
+	if c.profile == "" {
+		return DefaultProfile
+	}
+	return c.profile
+}
+
+// Account is the Jira account of the profile in use.
+func (c *Config) Account() *JiraAccount {
+	return c.ProfileAccount(c.ActiveProfile())
+}
+
+// ProfileAccount is the Jira account of a profile, nil for unknown ones.
+func (c *Config) ProfileAccount(name string) *JiraAccount {
+	if name == DefaultProfile {
+		return &c.JiraAccount
+	}
+	if profile, ok := c.Profiles[name]; ok {
+		return &profile.JiraAccount
+	}
+	return nil
+}
+
+// CheckProfileName tells whether a profile can go by name.
+func CheckProfileName(name string) error {
+	if name == "" || name == DefaultProfile || strings.ContainsAny(name, "@ ") {
+		return fmt.Errorf("can't name a profile %q", name)
+	}
+	return nil
+}
+
+// AddProfile adds or replaces a profile, the caller saves the config.
+func (c *Config) AddProfile(name string, profile *Profile) error {
+	if err := CheckProfileName(name); err != nil {
+		return err
+	}
+	if c.Profiles == nil {
+		c.Profiles = map[string]*Profile{}
+	}
+	c.Profiles[name] = profile
+	return nil
+}
+
+// RemoveProfile removes a profile along with its tokens, the caller saves
+// the config.
+func (c *Config) RemoveProfile(name string) error {
+	if _, ok := c.Profiles[name]; !ok {
+		return fmt.Errorf("unknown profile %q", name)
+	}
+	for _, key := range jiraTokenKeys {
+		if err := c.DeleteToken(ProfileKey(name, key)); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", ProfileKey(name, key), err)
+		}
+	}
+	delete(c.Profiles, name)
+	if c.Profile == name {
+		c.Profile = ""
+	}
+	if c.profile == name {
+		c.profile = ""
+	}
+	return nil
+}
+
+// SetDefaultProfile makes the named profile the one used unless another is
+// picked, the caller saves the config.
+func (c *Config) SetDefaultProfile(name string) error {
+	if err := c.UseProfile(name); err != nil {
+		return err
+	}
+	c.Profile = c.profile
+	return nil
+}
+
+// ListProfiles returns the default account followed by the profiles.
+func (c *Config) ListProfiles() []ProfileInfo {
+	defaultName := c.Profile
+	if defaultName == "" {
+		defaultName = DefaultProfile
+	}
+	list := []ProfileInfo{{
+		Name:     DefaultProfile,
+		JiraURL:  c.JiraURL,
+		JiraUser: c.JiraUser,
+	}}
+	for _, name := range sortedKeys(c.Profiles) {
+		profile := c.Profiles[name]
+		list = append(list, ProfileInfo{
+			Name:     name,
+			JiraURL:  profile.JiraURL,
+			JiraUser: profile.JiraUser,
+			Remotes:  profile.Remotes,
+			Dirs:     profile.Dirs,
+		})
+	}
+	for i := range list {
+		list[i].Default = list[i].Name == defaultName
+		list[i].Active = list[i].Name == c.ActiveProfile()
+	}
+	return list
+}
+
+func (p *Profile) matches(dir, repo string) bool {
+	for _, pattern := range p.Remotes {
+		if ok, _ := path.Match(pattern, repo); ok && repo != "" {
+			return true
+		}
+	}
+	for _, pattern := range p.Dirs {
+		pattern = expandHome(pattern)
+		for current := dir; current != ""; {
+			if ok, _ := filepath.Match(pattern, current); ok {
+				return true
+			}
+			parent := filepath.Dir(current)
+			if parent == current {
+				break
+			}
+			current = parent
+		}
+	}
+	return false
+}
+
+func expandHome(pattern string) string {
+	rest, ok := strings.CutPrefix(pattern, "~")
+	if !ok || (rest != "" && !strings.HasPrefix(rest, "/")) {
+		return pattern
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return pattern
+	}
+	return filepath.Join(homeDir, rest)
+}
@@ -0,0 +1,143 @@
+package cfg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+const profilesConfig = `
+jira_user: me@example.com
+jira_url: https://example.atlassian.net
+jira_project: OPS
+branch_format: "{{.Key}}-{{.Slug}}"
+token_store: plain
+profiles:
+  acme:
+    jira_user: contractor@acme.com
+    jira_url: https://jira.acme.com
+    jira_token: acme-token
+    jira_project: ACME
+    remotes: [github.com/acme/*]
+    dirs: [~/work/acme]
+`
+
+func loadProfiles(t *testing.T) *Config {
+	t.Helper()
+	config := NewConfg()
+	if err := yaml.Unmarshal([]byte(profilesConfig), &config); err != nil {
+		t.Fatalf("Failed to decode config: %v", err)
+	}
+	config.JiraToken = "default-token"
+	return &config
+}
+
+func TestSelectProfile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	acmeDir := filepath.Join(home, "work", "acme", "api")
+
+	tests := []struct {
+		name, flag, dir, repo, configured string
+		want                              string
+	}{
+		{name: "nothing matches", dir: "/src", repo: "github.com/other/api", want: DefaultProfile},
+		{name: "remote", dir: "/src", repo: "github.com/acme/api", want: "acme"},
+		{name: "directory", dir: acmeDir, want: "acme"},
+		{name: "flag wins", flag: DefaultProfile, dir: acmeDir, want: DefaultProfile},
+		{name: "configured default", dir: "/src", configured: "acme", want: "acme"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := loadProfiles(t)
+			config.Profile = tt.configured
+			if err := config.SelectProfile(tt.flag, tt.dir, tt.repo); err != nil {
+				t.Fatalf("SelectProfile() failed: %v", err)
+			}
+			if got := config.ActiveProfile(); got != tt.want {
+				t.Errorf("SelectProfile() picked %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if err := loadProfiles(t).SelectProfile("nope", "/src", ""); err == nil {
+		t.Error("SelectProfile() with an unknown profile succeeded")
+	}
+}
+
+func TestProfileAccountAndSettings(t *testing.T) {
+	config := loadProfiles(t)
+	if err := config.UseProfile("acme"); err != nil {
+		t.Fatal(err)
+	}
+
+	if account := config.Account(); account.JiraURL != "https://jira.acme.com" || account.JiraUser != "contractor@acme.com" {
+		t.Errorf("Account() = %+v, want the acme account", account)
+	}
+	if token, err := config.Token(JiraTokenKey); err != nil || token != "acme-token" {
+		t.Errorf("Token() = %q, %v, want the token of the profile", token, err)
+	}
+	settings := config.Settings()
+	if settings.JiraProject != "ACME" || settings.BranchFormat != "{{.Key}}-{{.Slug}}" {
+		t.Errorf("Settings() = %+v, want the profile's project over ~/.gg", settings)
+	}
+	for _, s := range config.ListSettings() {
+		if s.Key == "jira_project" && s.Source != "profile acme" {
+			t.Errorf("jira_project comes from %q, want the profile", s.Source)
+		}
+	}
+
+	if err := config.UseProfile(DefaultProfile); err != nil {
+		t.Fatal(err)
+	}
+	if token, _ := config.Token(JiraTokenKey); token != "default-token" || config.Settings().JiraProject != "OPS" {
+		t.Errorf("The default profile got token %q and project %q", token, config.Settings().JiraProject)
+	}
+}
+
+func TestAddAndRemoveProfile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	config := loadProfiles(t)
+
+	for _, name := range []string{"", DefaultProfile, "a@b"} {
+		if err := config.AddProfile(name, &Profile{}); err == nil {
+			t.Errorf("AddProfile(%q) succeeded", name)
+		}
+	}
+	if err := config.AddProfile("beta", &Profile{JiraAccount: JiraAccount{JiraURL: "https://beta.example.com"}}); err != nil {
+		t.Fatalf("AddProfile() failed: %v", err)
+	}
+	if err := config.SetToken(ProfileKey("beta", JiraTokenKey), "beta-token"); err != nil {
+		t.Fatalf("SetToken() failed: %v", err)
+	}
+	if err := config.SetDefaultProfile("beta"); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(os.Getenv("HOME"), ".gg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := NewConfg()
+	if err := yaml.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved.Profile != "beta" || saved.Profiles["beta"].JiraToken != "beta-token" || saved.Profiles["acme"].JiraProject != "ACME" {
+		t.Errorf("Saved profiles don't round trip:\n%s", data)
+	}
+
+	if err := config.RemoveProfile("beta"); err != nil {
+		t.Fatalf("RemoveProfile() failed: %v", err)
+	}
+	if _, ok := config.Profiles["beta"]; ok || config.Profile != "" {
+		t.Errorf("RemoveProfile() left %+v, default %q", config.Profiles, config.Profile)
+	}
+	if err := config.RemoveProfile("beta"); err == nil {
+		t.Error("RemoveProfile() of a missing profile succeeded")
+	}
+}
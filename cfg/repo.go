@@ -0,0 +1,305 @@
+package cfg
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"gopkg.in/yaml.v2"
+)
+
+const RepoConfigFile = ".gg.yml"
+
+const (
+	DefaultBranchFormat = "{{.Key}}_{{.Slug}}"
+	// DefaultPRTitleFormat names one of the built-in PR title formats
+	DefaultPRTitleFormat = "conventional"
+	// DefaultPRBodyFormat names the built-in PR description
+	DefaultPRBodyFormat = "default"
+)
+
+// Queries mine and unassigned are what gg issue offers unless configured
+// otherwise. Queries are templates with the Jira project as {{.Project}} and,
+// when a board is configured, its active sprint as {{.Sprint}}.
+const (
+	QueryMine       = "mine"
+	QueryUnassigned = "unassigned"
+)
+
+var defaultQueries = map[string]string{
+	QueryMine: `project = "{{.Project}}" AND assignee = currentUser() AND statusCategory in ("To Do", "In Progress") ` +
+		`ORDER BY updated DESC`,
+	QueryUnassigned: `project = "{{.Project}}" AND assignee is EMPTY AND statusCategory in ("To Do", "In Progress") ` +
+		`ORDER BY updated DESC`,
+}
+
+// defaultPRTypes pick the PR title prefix from the Jira issue type.
+var defaultPRTypes = map[string]string{
+	"Bug":   "fix",
+	"Story": "feat",
+}
+
+// Settings are the parts of the config a repository can override with a
+// .gg.yml in its root. Values there win over ~/.gg, which wins over the
+// defaults.
+type Settings struct {
+	JiraProject  string `yaml:"jira_project,omitempty"`
+	BranchFormat string `yaml:"branch_format,omitempty"`
+	// BranchLowercase is a pointer so a repo can turn it off again
+	BranchLowercase *bool `yaml:"branch_lowercase,omitempty"`
+	BranchMaxLength int   `yaml:"branch_max_length,omitempty"`
+	// BranchTypes maps Jira issue types to what {{.Type}} becomes in branch names
+	BranchTypes   map[string]string `yaml:"branch_types,omitempty"`
+	PRTitleFormat string            `yaml:"pr_title_format,omitempty"`
+	// PRTypes maps Jira issue types to the default PR title prefix
+	PRTypes      map[string]string `yaml:"pr_types,omitempty"`
+	PRBodyFormat string            `yaml:"pr_body_format,omitempty"`
+	// AcceptanceCriteriaField is the Jira custom field holding acceptance criteria
+	AcceptanceCriteriaField string                 `yaml:"acceptance_criteria_field,omitempty"`
+	BaseBranch              string                 `yaml:"base_branch,omitempty"`
+	Transitions             map[string]Transitions `yaml:"transitions,omitempty"`
+	// Queries are named JQL searches for gg issue
+	Queries map[string]string `yaml:"queries,omitempty"`
+	// Board is the ID of the Jira board whose active sprint gg works with
+	Board int `yaml:"board,omitempty"`
+	// IssueFields are by project, then field ID or name. Fields listed are
+	// asked for by gg new besides the required ones.
+	IssueFields map[string]map[string]string `yaml:"issue_fields,omitempty"`
+}
+
+// Lowercase tells whether branch name slugs should be lowercased.
+func (s *Settings) Lowercase() bool {
+	return s.BranchLowercase != nil && *s.BranchLowercase
+}
+
+// Setting is an effective value and where it came from, for showing users.
+type Setting struct {
+	Key    string `json:"key" yaml:"key"`
+	Value  string `json:"value" yaml:"value"`
+	Source string `json:"source" yaml:"source"`
+}
+
+const (
+	sourceDefault = "default"
+	sourceGlobal  = "~/.gg"
+)
+
+// findGitRoot walks up from dir to the directory containing .git, which is a
+// file rather than a directory in worktrees and submodules.
+func findGitRoot(dir string) (string, bool) {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// LoadRepoConfig reads .gg.yml from the root of the git repository dir is in,
+// it's fine for both the repository and the file to be missing.
+func (c *Config) LoadRepoConfig(dir string) error {
+	root, ok := findGitRoot(dir)
+	if !ok {
+		return nil
+	}
+	path := filepath.Join(root, RepoConfigFile)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read repo config: %w", err)
+	}
+
+	var settings Settings
+	if err := yaml.UnmarshalStrict(data, &settings); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	c.repo = &settings
+	c.repoFile = path
+	return nil
+}
+
+// layer is one of the config sources, lowest precedence first.
+type layer struct {
+	source   string
+	settings Settings
+}
+
+func (c *Config) layers() []layer {
+	layers := []layer{
+		{sourceDefault, Settings{
+			BranchFormat:  DefaultBranchFormat,
+			PRTitleFormat: DefaultPRTitleFormat,
+			PRTypes:       defaultPRTypes,
+			PRBodyFormat:  DefaultPRBodyFormat,
+			Queries:       defaultQueries,
+		}},
+		{sourceGlobal, Settings{
+			JiraProject:     c.JiraProject,
+			BranchFormat:    c.BranchFormat,
+			BranchLowercase: c.BranchLowercase,
+			BranchMaxLength: c.BranchMaxLength,
+			BranchTypes:     c.BranchTypes,
+			PRTitleFormat:   c.PRTitleFormat,
+			PRTypes:         c.PRTypes,
+			PRBodyFormat:    c.PRBodyFormat,
+			BaseBranch:      c.BaseBranch,
+			Transitions:     c.Transitions,
+			Queries:         c.Queries,
+			Board:           c.Board,
+			IssueFields:     c.IssueFields,
+
+			AcceptanceCriteriaField: c.AcceptanceCriteriaField,
+		}},
+	}
+	if profile, ok := c.Profiles[c.profile]; ok {
+		layers = append(layers, layer{"profile " + c.profile, profile.Settings})
+	}
+	if c.repo != nil {
+		layers = append(layers, layer{c.repoFile, *c.repo})
+	}
+	return layers
+}
+
+// Settings merges the config sources into the values in effect. Transitions
+// are merged one field at a time, so a repo can change just the review step.
+func (c *Config) Settings() Settings {
+	settings, _ := c.mergeSettings()
+	return settings
+}
+
+func (c *Config) mergeSettings() (Settings, map[string]string) {
+	merged := Settings{
+		BranchTypes: map[string]string{},
+		PRTypes:     map[string]string{},
+		Transitions: map[string]Transitions{},
+		Queries:     map[string]string{},
+		IssueFields: map[string]map[string]string{},
+	}
+	sources := map[string]string{}
+	set := func(key string, dst *string, value, source string) {
+		if value != "" {
+			*dst = value
+			sources[key] = source
+		}
+	}
+	for _, l := range c.layers() {
+		set("jira_project", &merged.JiraProject, l.settings.JiraProject, l.source)
+		set("branch_format", &merged.BranchFormat, l.settings.BranchFormat, l.source)
+		if l.settings.BranchLowercase != nil {
+			merged.BranchLowercase = l.settings.BranchLowercase
+			sources["branch_lowercase"] = l.source
+		}
+		if l.settings.BranchMaxLength != 0 {
+			merged.BranchMaxLength = l.settings.BranchMaxLength
+			sources["branch_max_length"] = l.source
+		}
+		for issueType, value := range l.settings.BranchTypes {
+			merged.BranchTypes[issueType] = value
+			sources["branch_types."+issueType] = l.source
+		}
+		set("pr_title_format", &merged.PRTitleFormat, l.settings.PRTitleFormat, l.source)
+		for issueType, value := range l.settings.PRTypes {
+			merged.PRTypes[issueType] = value
+			sources["pr_types."+issueType] = l.source
+		}
+		set("pr_body_format", &merged.PRBodyFormat, l.settings.PRBodyFormat, l.source)
+		set("acceptance_criteria_field", &merged.AcceptanceCriteriaField, l.settings.AcceptanceCriteriaField, l.source)
+		set("base_branch", &merged.BaseBranch, l.settings.BaseBranch, l.source)
+		if l.settings.Board != 0 {
+			merged.Board = l.settings.Board
+			sources["board"] = l.source
+		}
+		for name, jql := range l.settings.Queries {
+			merged.Queries[name] = jql
+			sources["queries."+name] = l.source
+		}
+		for project, fields := range l.settings.IssueFields {
+			if merged.IssueFields[project] == nil {
+				merged.IssueFields[project] = map[string]string{}
+			}
+			for field, value := range fields {
+				merged.IssueFields[project][field] = value
+				sources["issue_fields."+project+"."+field] = l.source
+			}
+		}
+		for project, t := range l.settings.Transitions {
+			current := merged.Transitions[project]
+			prefix := "transitions." + project + "."
+			set(prefix+"start", &current.Start, t.Start, l.source)
+			set(prefix+"review", &current.Review, t.Review, l.source)
+			set(prefix+"done", &current.Done, t.Done, l.source)
+			merged.Transitions[project] = current
+		}
+	}
+	return merged, sources
+}
+
+// ListSettings returns the effective settings along with their source.
+func (c *Config) ListSettings() []Setting {
+	settings, sources := c.mergeSettings()
+	list := []Setting{
+		{"jira_project", settings.JiraProject, sources["jira_project"]},
+		{"branch_format", settings.BranchFormat, sources["branch_format"]},
+		{"branch_lowercase", strconv.FormatBool(settings.Lowercase()), sources["branch_lowercase"]},
+		{"branch_max_length", strconv.Itoa(settings.BranchMaxLength), sources["branch_max_length"]},
+		{"pr_title_format", settings.PRTitleFormat, sources["pr_title_format"]},
+		{"pr_body_format", settings.PRBodyFormat, sources["pr_body_format"]},
+		{"acceptance_criteria_field", settings.AcceptanceCriteriaField, sources["acceptance_criteria_field"]},
+		{"base_branch", settings.BaseBranch, sources["base_branch"]},
+		{"board", strconv.Itoa(settings.Board), sources["board"]},
+	}
+	for _, issueType := range sortedKeys(settings.BranchTypes) {
+		key := "branch_types." + issueType
+		list = append(list, Setting{key, settings.BranchTypes[issueType], sources[key]})
+	}
+	for i := range list {
+		if list[i].Source == "" {
+			list[i].Source = sourceDefault
+		}
+	}
+	for _, issueType := range sortedKeys(settings.PRTypes) {
+		key := "pr_types." + issueType
+		list = append(list, Setting{key, settings.PRTypes[issueType], sources[key]})
+	}
+	for _, name := range sortedKeys(settings.Queries) {
+		key := "queries." + name
+		list = append(list, Setting{key, settings.Queries[name], sources[key]})
+	}
+	for _, project := range sortedKeys(settings.IssueFields) {
+		for _, field := range sortedKeys(settings.IssueFields[project]) {
+			key := "issue_fields." + project + "." + field
+			list = append(list, Setting{key, settings.IssueFields[project][field], sources[key]})
+		}
+	}
+	for _, project := range sortedKeys(settings.Transitions) {
+		t := c.TransitionsFor(project)
+		for _, step := range []struct{ name, value string }{{"start", t.Start}, {"review", t.Review}, {"done", t.Done}} {
+			key := "transitions." + project + "." + step.name
+			source := sources[key]
+			if source == "" && step.value != "" {
+				source = sourceDefault
+			}
+			list = append(list, Setting{key, step.value, source})
+		}
+	}
+	return list
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,121 @@
+package cfg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func createRepoConfig(t *testing.T, content string) (string, string) {
+	t.Helper()
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0o750); err != nil {
+		t.Fatalf("Failed to create .git: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, RepoConfigFile), []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write repo config: %v", err)
+	}
+	subDir := filepath.Join(root, "cmd", "gg")
+	if err := os.MkdirAll(subDir, 0o750); err != nil {
+		t.Fatalf("Failed to create sub directory: %v", err)
+	}
+	return root, subDir
+}
+
+func TestRepoConfigOverridesGlobal(t *testing.T) {
+	root, subDir := createRepoConfig(t, `
+jira_project: WEB
+base_branch: develop
+transitions:
+  WEB:
+    review: Code Review
+`)
+	config := NewConfg()
+	config.JiraProject = "OPS"
+	config.BranchFormat = "{{.Key}}-{{.Slug}}"
+	config.Transitions = map[string]Transitions{"WEB": {Start: "Doing", Review: "In Review"}}
+
+	if err := config.LoadRepoConfig(subDir); err != nil {
+		t.Fatalf("LoadRepoConfig failed: %v", err)
+	}
+
+	settings := config.Settings()
+	if settings.JiraProject != "WEB" || settings.BaseBranch != "develop" {
+		t.Errorf("Expected repo values to win, got %+v", settings)
+	}
+	if settings.BranchFormat != "{{.Key}}-{{.Slug}}" {
+		t.Errorf("Expected global branch format, got %q", settings.BranchFormat)
+	}
+	if settings.PRTitleFormat != DefaultPRTitleFormat {
+		t.Errorf("Expected default PR title format, got %q", settings.PRTitleFormat)
+	}
+	if got := config.TransitionsFor("WEB"); got.Start != "Doing" || got.Review != "Code Review" || got.Done != "Done" {
+		t.Errorf("Expected transitions merged field by field, got %+v", got)
+	}
+	if config.JiraProject != "OPS" {
+		t.Errorf("Repo config must not leak into the global config, got %q", config.JiraProject)
+	}
+
+	sources := map[string]string{}
+	for _, s := range config.ListSettings() {
+		sources[s.Key] = s.Source
+	}
+	repoFile := filepath.Join(root, RepoConfigFile)
+	want := map[string]string{
+		"jira_project":           repoFile,
+		"branch_format":          "~/.gg",
+		"pr_title_format":        "default",
+		"transitions.WEB.start":  "~/.gg",
+		"transitions.WEB.review": repoFile,
+		"transitions.WEB.done":   "default",
+	}
+	for key, source := range want {
+		if sources[key] != source {
+			t.Errorf("Expected %s to come from %s, got %s", key, source, sources[key])
+		}
+	}
+}
+
+func TestRepoConfigMissing(t *testing.T) {
+	config := NewConfg()
+	config.JiraProject = "OPS"
+	if err := config.LoadRepoConfig(t.TempDir()); err != nil {
+		t.Fatalf("LoadRepoConfig failed outside a repo: %v", err)
+	}
+	if got := config.Settings().JiraProject; got != "OPS" {
+		t.Errorf("Expected global project, got %q", got)
+	}
+}
+
+func TestRepoConfigUnknownKey(t *testing.T) {
+	_, subDir := createRepoConfig(t, "jira_projcet: WEB\n")
+	config := NewConfg()
+	if err := config.LoadRepoConfig(subDir); err == nil {
+		t.Error("Expected an error for a misspelled key")
+	}
+}
+
+func TestRememberIssueFieldsSkipsRepo(t *testing.T) {
+	_, subDir := createRepoConfig(t, `
+issue_fields:
+  WEB:
+    components: API
+`)
+	createTempConfigFile(t, "")
+	config := NewConfg()
+	if err := config.LoadRepoConfig(subDir); err != nil {
+		t.Fatalf("LoadRepoConfig failed: %v", err)
+	}
+
+	fields := []IssueField{
+		{ID: "components", Name: "Components", Value: "API"},
+		{ID: "priority", Name: "Priority", Value: "High"},
+		{ID: "customfield_10016", Name: "Story Points"},
+	}
+	if err := config.RememberIssueFields("WEB", fields); err != nil {
+		t.Fatalf("RememberIssueFields failed: %v", err)
+	}
+	if got := config.IssueFields["WEB"]; len(got) != 1 || got["priority"] != "High" {
+		t.Errorf("Expected only the priority in ~/.gg, got %v", got)
+	}
+}
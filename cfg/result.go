@@ -0,0 +1,43 @@
+package cfg
+
+// Result is what a command did, shown as a summary or printed as JSON or YAML
+// for scripts.
+type Result struct {
+	Command  string `json:"command" yaml:"command"`
+	Issue    string `json:"issue" yaml:"issue"`
+	Title    string `json:"title,omitempty" yaml:"title,omitempty"`
+	Type     string `json:"type,omitempty" yaml:"type,omitempty"`
+	IssueURL string `json:"issue_url,omitempty" yaml:"issue_url,omitempty"`
+	Branch   string `json:"branch,omitempty" yaml:"branch,omitempty"`
+	Base     string `json:"base,omitempty" yaml:"base,omitempty"`
+	PRNumber int    `json:"pr_number,omitempty" yaml:"pr_number,omitempty"`
+	PRURL    string `json:"pr_url,omitempty" yaml:"pr_url,omitempty"`
+	// Assigned tells if the issue got assigned to you
+	Assigned bool `json:"assigned,omitempty" yaml:"assigned,omitempty"`
+	// Transitions are the statuses the issue was moved to
+	Transitions   []string `json:"transitions,omitempty" yaml:"transitions,omitempty"`
+	BranchDeleted bool     `json:"branch_deleted,omitempty" yaml:"branch_deleted,omitempty"`
+	// Sprint is the sprint a new issue was added to
+	Sprint string `json:"sprint,omitempty" yaml:"sprint,omitempty"`
+	// Parent and Links are what a new issue was created under and linked to
+	Parent string   `json:"parent,omitempty" yaml:"parent,omitempty"`
+	Links  []string `json:"links,omitempty" yaml:"links,omitempty"`
+}
+
+// Sprint is the active sprint of a board with its issues, for gg sprint.
+type Sprint struct {
+	ID     int           `json:"id" yaml:"id"`
+	Name   string        `json:"name" yaml:"name"`
+	Goal   string        `json:"goal,omitempty" yaml:"goal,omitempty"`
+	Issues []SprintIssue `json:"issues" yaml:"issues"`
+}
+
+type SprintIssue struct {
+	Key    string `json:"key" yaml:"key"`
+	Title  string `json:"title" yaml:"title"`
+	Type   string `json:"type" yaml:"type"`
+	Status string `json:"status" yaml:"status"`
+	// StatusCategory is To Do, In Progress or Done, for ordering statuses
+	StatusCategory string `json:"status_category" yaml:"status_category"`
+	Assignee       string `json:"assignee,omitempty" yaml:"assignee,omitempty"`
+}
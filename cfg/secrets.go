@@ -0,0 +1,219 @@
+package cfg
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/bricktopab/gg/secrets"
+)
+
+const (
+	TokenStoreKeyring = "keyring"
+	TokenStoreFile    = "file"
+	TokenStorePlain   = "plain"
+
+	JiraTokenKey = "jira_token"
+	// JiraOAuthTokenKey holds the tokens of gg login as JSON
+	JiraOAuthTokenKey  = "jira_oauth_token"
+	JiraOAuthSecretKey = "jira_oauth_client_secret"
+)
+
+// hostingTokenKeys maps hosting provider kinds to the key of their token.
+var hostingTokenKeys = map[string]string{
+	"github":           "github_token",
+	"gitlab":           "gitlab_token",
+	"bitbucket":        "bitbucket_token",
+	"bitbucket-server": "bitbucket_token",
+	"azure":            "azure_token",
+}
+
+var hostingTokenEnv = map[string]string{
+	"github":           "GH_TOKEN",
+	"gitlab":           "GITLAB_TOKEN",
+	"bitbucket":        "BITBUCKET_TOKEN",
+	"bitbucket-server": "BITBUCKET_TOKEN",
+	"azure":            "AZURE_DEVOPS_EXT_PAT",
+}
+
+// plaintextTokens are the token fields as found in ~/.gg, which stay empty
+// once the tokens have moved to a secret store.
+func (c *Config) plaintextTokens() map[string]*string {
+	tokens := map[string]*string{
+		JiraTokenKey:      &c.JiraToken,
+		"github_token":    &c.GitHubToken,
+		"gitlab_token":    &c.GitLabToken,
+		"bitbucket_token": &c.BitbucketToken,
+		"azure_token":     &c.AzureToken,
+	}
+	for name, profile := range c.Profiles {
+		tokens[ProfileKey(name, JiraTokenKey)] = &profile.JiraToken
+	}
+	return tokens
+}
+
+func secretsFilePath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".gg-secrets"), nil
+}
+
+// secretStore returns the configured store and its kind. Without a choice in
+// the config the OS keyring is used when there is one, the encrypted file
+// otherwise.
+func (c *Config) secretStore() (secrets.Store, string, error) {
+	kind := c.TokenStore
+	if kind == "" {
+		kind = TokenStoreFile
+		if secrets.KeyringAvailable() {
+			kind = TokenStoreKeyring
+		}
+	}
+	switch kind {
+	case TokenStoreKeyring:
+		return &secrets.Keyring{}, kind, nil
+	case TokenStoreFile:
+		path, err := secretsFilePath()
+		if err != nil {
+			return nil, kind, err
+		}
+		file, err := secrets.NewFileFromEnv(path)
+		return file, kind, err
+	}
+	return nil, kind, fmt.Errorf("unknown token_store: %s", kind)
+}
+
+// Token looks up a token by key, e.g. "jira_token". A credential command
+// configured for the key wins, then comes a plaintext value in ~/.gg and
+// last the secret store. A token that's nowhere to be found is empty, also
+// when there's no store to look in. Jira tokens are those of the profile in
+// use.
+func (c *Config) Token(key string) (string, error) {
+	return c.token(c.tokenKey(key))
+}
+
+func (c *Config) token(key string) (string, error) {
+	if command, ok := c.TokenCommands[key]; ok {
+		return (&secrets.Command{Commands: map[string]string{key: command}}).Get(key)
+	}
+	if field, ok := c.plaintextTokens()[key]; ok && *field != "" {
+		return *field, nil
+	}
+	if c.TokenStore == TokenStorePlain {
+		return "", nil
+	}
+	store, _, err := c.secretStore()
+	if err != nil {
+		if c.TokenStore == "" {
+			// no keyring and no passphrase, so no token can have gone to a store
+			return "", nil
+		}
+		return "", err
+	}
+	token, err := store.Get(key)
+	if errors.Is(err, secrets.ErrNotFound) {
+		return "", nil
+	}
+	return token, err
+}
+
+// SetToken keeps a token in the secret store. With token_store: plain only
+// the tokens ~/.gg has a field for can be kept, the caller saves the config.
+func (c *Config) SetToken(key, value string) error {
+	return c.setToken(c.tokenKey(key), value)
+}
+
+func (c *Config) setToken(key, value string) error {
+	if c.TokenStore == TokenStorePlain {
+		field, ok := c.plaintextTokens()[key]
+		if !ok {
+			return fmt.Errorf("token_store plain can't keep %s, use %s or %s", key, TokenStoreKeyring, TokenStoreFile)
+		}
+		*field = value
+		return nil
+	}
+	store, _, err := c.secretStore()
+	if err != nil {
+		return err
+	}
+	return store.Set(key, value)
+}
+
+// DeleteToken removes a token from wherever SetToken put it.
+func (c *Config) DeleteToken(key string) error {
+	return c.deleteToken(c.tokenKey(key))
+}
+
+func (c *Config) deleteToken(key string) error {
+	if field, ok := c.plaintextTokens()[key]; ok {
+		*field = ""
+	}
+	if c.TokenStore == TokenStorePlain {
+		return nil
+	}
+	store, _, err := c.secretStore()
+	if err != nil {
+		if c.TokenStore == "" {
+			return nil
+		}
+		return err
+	}
+	return store.Delete(key)
+}
+
+// HostingToken returns the API token for a hosting provider kind, falling
+// back on the environment variable the provider's own CLI uses.
+func (c *Config) HostingToken(kind string) (string, error) {
+	key, ok := hostingTokenKeys[kind]
+	if !ok {
+		return "", nil
+	}
+	token, err := c.Token(key)
+	if err != nil || token != "" {
+		return token, err
+	}
+	return os.Getenv(hostingTokenEnv[kind]), nil
+}
+
+// moveTokensToStore takes plaintext tokens out of the config and into the
+// secret store, reporting whether anything moved so the config gets saved.
+// Without an explicit token_store and nowhere to put them, tokens stay put,
+// and gg says so.
+func (c *Config) moveTokensToStore() (bool, error) {
+	if c.TokenStore == TokenStorePlain {
+		return false, nil
+	}
+	plaintext := map[string]*string{}
+	for key, field := range c.plaintextTokens() {
+		if *field != "" {
+			plaintext[key] = field
+		}
+	}
+	if len(plaintext) == 0 {
+		return false, nil
+	}
+
+	store, kind, err := c.secretStore()
+	if err != nil {
+		if c.TokenStore == "" {
+			log.Printf("No keyring found and %s isn't set, so your tokens stay in ~/.gg as plain text. "+
+				"Set %s to have them moved to ~/.gg-secrets, or token_store: plain to keep them where they are.",
+				secrets.PassphraseEnv, secrets.PassphraseEnv)
+			return false, nil
+		}
+		return false, err
+	}
+	for key, field := range plaintext {
+		if err := store.Set(key, *field); err != nil {
+			return false, fmt.Errorf("failed to store %s: %w", key, err)
+		}
+		*field = ""
+	}
+	// stick with the store the tokens are in
+	c.TokenStore = kind
+	return true, nil
+}
@@ -0,0 +1,204 @@
+package cfg
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bricktopab/gg/secrets"
+	"github.com/zalando/go-keyring"
+)
+
+func TestMain(m *testing.M) {
+	// never touch the keyring of whoever runs the tests
+	keyring.MockInit()
+	os.Exit(m.Run())
+}
+
+func TestPlaintextTokensMoveToKeyring(t *testing.T) {
+	configFile := createTempConfigFile(t, `
+jira_user: testuser
+jira_url: https://test.jira.com
+jira_token: sometoken
+jira_project: TEST
+github_token: ghp_123
+format_version: 2
+`)
+
+	loadedCfg, err := LoadOrCreateConfig(func() (*Config, error) {
+		t.Error("AskForConfig should not be called when a config file exists")
+		return nil, errors.New("not called")
+	})
+	if err != nil {
+		t.Fatalf("LoadOrCreateConfig failed: %v", err)
+	}
+
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatalf("Failed to read config file: %v", err)
+	}
+	if strings.Contains(string(data), "sometoken") || strings.Contains(string(data), "ghp_123") {
+		t.Errorf("Expected tokens to be gone from the config file:\n%s", data)
+	}
+	if info, _ := os.Stat(configFile); info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected config file to be private, got %v", info.Mode().Perm())
+	}
+	if loadedCfg.TokenStore != TokenStoreKeyring {
+		t.Errorf("Expected token_store to be pinned to keyring, got %q", loadedCfg.TokenStore)
+	}
+
+	if token, err := loadedCfg.Token(JiraTokenKey); err != nil || token != "sometoken" {
+		t.Errorf("Token() = %q, %v, want sometoken", token, err)
+	}
+	if token, err := loadedCfg.HostingToken("github"); err != nil || token != "ghp_123" {
+		t.Errorf("HostingToken() = %q, %v, want ghp_123", token, err)
+	}
+	if stored, _ := (&secrets.Keyring{}).Get(JiraTokenKey); stored != "sometoken" {
+		t.Errorf("Expected token in the keyring, got %q", stored)
+	}
+}
+
+func TestPlainTokenStoreKeepsTokens(t *testing.T) {
+	configFile := createTempConfigFile(t, `
+jira_token: plaintoken
+token_store: plain
+format_version: 2
+`)
+	loadedCfg, err := LoadOrCreateConfig(func() (*Config, error) { return nil, errors.New("not called") })
+	if err != nil {
+		t.Fatalf("LoadOrCreateConfig failed: %v", err)
+	}
+	if token, _ := loadedCfg.Token(JiraTokenKey); token != "plaintoken" {
+		t.Errorf("Expected plaintext token, got %q", token)
+	}
+	if saved := loadConfigFromFile(t, configFile); saved.JiraToken != "plaintoken" {
+		t.Errorf("Expected token to stay in the config file, got %q", saved.JiraToken)
+	}
+}
+
+func TestFileTokenStore(t *testing.T) {
+	createTempConfigFile(t, `
+jira_token: filetoken
+token_store: file
+format_version: 2
+`)
+	t.Setenv(secrets.PassphraseEnv, "correct horse")
+
+	loadedCfg, err := LoadOrCreateConfig(func() (*Config, error) { return nil, errors.New("not called") })
+	if err != nil {
+		t.Fatalf("LoadOrCreateConfig failed: %v", err)
+	}
+	if loadedCfg.JiraToken != "" {
+		t.Errorf("Expected token to move out of the config, got %q", loadedCfg.JiraToken)
+	}
+	if token, err := loadedCfg.Token(JiraTokenKey); err != nil || token != "filetoken" {
+		t.Errorf("Token() = %q, %v, want filetoken", token, err)
+	}
+}
+
+func TestTokenCommandWins(t *testing.T) {
+	config := NewConfg()
+	config.JiraToken = "plaintoken"
+	config.TokenCommands = map[string]string{JiraTokenKey: "echo commandtoken"}
+	if token, err := config.Token(JiraTokenKey); err != nil || token != "commandtoken" {
+		t.Errorf("Token() = %q, %v, want commandtoken", token, err)
+	}
+}
+
+func TestHostingTokenFromEnv(t *testing.T) {
+	t.Setenv("GITLAB_TOKEN", "glpat-123")
+	config := NewConfg()
+	config.TokenStore = TokenStorePlain
+	if token, err := config.HostingToken("gitlab"); err != nil || token != "glpat-123" {
+		t.Errorf("HostingToken() = %q, %v, want glpat-123", token, err)
+	}
+}
+
+func TestSetToken(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(secrets.PassphraseEnv, "correct horse")
+	config := NewConfg()
+	config.TokenStore = TokenStoreFile
+
+	if err := config.SetToken(JiraOAuthTokenKey, `{"access_token":"a"}`); err != nil {
+		t.Fatalf("SetToken() failed: %v", err)
+	}
+	if token, err := config.Token(JiraOAuthTokenKey); err != nil || token != `{"access_token":"a"}` {
+		t.Errorf("Token() = %q, %v after SetToken()", token, err)
+	}
+	if err := config.DeleteToken(JiraOAuthTokenKey); err != nil {
+		t.Fatalf("DeleteToken() failed: %v", err)
+	}
+	if token, err := config.Token(JiraOAuthTokenKey); err != nil || token != "" {
+		t.Errorf("Token() = %q, %v after DeleteToken(), want nothing", token, err)
+	}
+
+	config.TokenStore = TokenStorePlain
+	if err := config.SetToken(JiraOAuthTokenKey, "a"); err == nil {
+		t.Error("SetToken() with the plain store succeeded for a token without a field")
+	}
+}
+
+func TestTokensWithoutStore(t *testing.T) {
+	keyring.MockInitWithError(errors.New("no keyring here"))
+	t.Cleanup(keyring.MockInit)
+	t.Setenv(secrets.PassphraseEnv, "")
+	var logged strings.Builder
+	log.SetOutput(&logged)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	configFile := createTempConfigFile(t, fmt.Sprintf("jira_token: plaintoken\nformat_version: %d\n", currentFormatVersion))
+	load := func() *Config {
+		t.Helper()
+		config, err := LoadOrCreateConfig(func() (*Config, error) { return nil, errors.New("not called") })
+		if err != nil {
+			t.Fatalf("LoadOrCreateConfig failed: %v", err)
+		}
+		return config
+	}
+
+	if token, _ := load().Token(JiraTokenKey); token != "plaintoken" {
+		t.Errorf("Token() = %q, want plaintoken", token)
+	}
+	if !strings.Contains(logged.String(), "plain text") {
+		t.Errorf("no warning about the plaintext tokens, logged %q", logged.String())
+	}
+
+	t.Setenv(secrets.PassphraseEnv, "correct horse")
+	config := load()
+	if token, err := config.Token(JiraTokenKey); err != nil || token != "plaintoken" {
+		t.Errorf("Token() = %q, %v, want plaintoken", token, err)
+	}
+	if saved := loadConfigFromFile(t, configFile); saved.JiraToken != "" || saved.TokenStore != TokenStoreFile {
+		t.Errorf("tokens weren't moved once there was a store, token_store %q", saved.TokenStore)
+	}
+}
+
+func TestTokenLookupWithoutStore(t *testing.T) {
+	keyring.MockInitWithError(errors.New("no keyring here"))
+	t.Cleanup(keyring.MockInit)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(secrets.PassphraseEnv, "")
+	t.Setenv("GH_TOKEN", "ghp_env")
+	config := NewConfg()
+
+	if token, err := config.Token(JiraTokenKey); err != nil || token != "" {
+		t.Errorf("Token() = %q, %v, want nothing", token, err)
+	}
+	if token, err := config.HostingToken("github"); err != nil || token != "ghp_env" {
+		t.Errorf("HostingToken() = %q, %v, want ghp_env", token, err)
+	}
+	if err := config.DeleteToken(JiraTokenKey); err != nil {
+		t.Errorf("DeleteToken() = %v", err)
+	}
+	if err := config.SetToken(JiraOAuthTokenKey, "a"); err == nil {
+		t.Error("SetToken() succeeded without a store to keep the token in")
+	}
+
+	config.TokenStore = TokenStoreFile
+	if _, err := config.Token(JiraTokenKey); err == nil {
+		t.Error("Token() with token_store file and no passphrase succeeded")
+	}
+}
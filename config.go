@@ -0,0 +1,209 @@
+package main
+
+import (
+	"cmp"
+	"log"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/bricktopab/gg/cfg"
+)
+
+// jiraAccountKeys decide how gg gets into Jira, changes to them are tried on
+// Jira before they're saved.
+var jiraAccountKeys = []string{"jira_user", "jira_url", cfg.JiraTokenKey, "jira_deployment"}
+
+// connectJira makes the Jira client for checking the config, tests take its
+// place.
+var connectJira = newJiraClient
+
+// GetConfig shows the value of a key, see cfg.Config.Lookup.
+func (g *GG) GetConfig(key string) error {
+	setting, err := g.Config.Lookup(key)
+	if err != nil {
+		return configError("%w", err)
+	}
+	return g.Gui.ShowSetting(setting)
+}
+
+// SetConfig changes a key of ~/.gg or the token store, after checking the
+// value and, for the Jira account, that Jira takes it.
+func (g *GG) SetConfig(key, value string) error {
+	profile, name := cfg.SplitProfileKey(key)
+	if !slices.Contains(jiraAccountKeys, name) {
+		if err := g.Config.Set(key, value); err != nil {
+			return configError("%w", err)
+		}
+		if err := checkFormats(g.Config.Settings()); err != nil {
+			return err
+		}
+		if err := g.saveConfig(key); err != nil {
+			return err
+		}
+		if setting, err := g.Config.Lookup(key); err == nil && setting.Value != value {
+			log.Printf("%s from %s still wins here\n", key, setting.Source)
+		}
+		return nil
+	}
+
+	if err := g.Config.UseProfile(profile); err != nil {
+		return configError("%w", err)
+	}
+	token, err := g.Config.Token(cfg.JiraTokenKey)
+	if err != nil {
+		return configError("failed to get Jira token: %w", err)
+	}
+	if name == cfg.JiraTokenKey {
+		token = value
+	} else {
+		if err := g.Config.Set(key, value); err != nil {
+			return configError("%w", err)
+		}
+		if name == "jira_url" {
+			// another Jira, which may not be what the old one was
+			g.Config.Account().JiraDeployment = ""
+		}
+	}
+	if err := checkJira(g.Config, token); err != nil {
+		return err
+	}
+	if name == cfg.JiraTokenKey {
+		if err := g.Config.Set(key, value); err != nil {
+			return configError("failed to store the token: %w", err)
+		}
+	}
+	return g.saveConfig(key)
+}
+
+// UnsetConfig removes a key, so its default is used again.
+func (g *GG) UnsetConfig(key string) error {
+	if err := g.Config.Unset(key); err != nil {
+		return configError("%w", err)
+	}
+	if err := checkFormats(g.Config.Settings()); err != nil {
+		return err
+	}
+	return g.saveConfig(key)
+}
+
+func (g *GG) saveConfig(key string) error {
+	if err := g.Config.Save(); err != nil {
+		return configError("failed to save config: %w", err)
+	}
+	log.Printf("Saved %s\n", key)
+	return nil
+}
+
+// EditConfig opens ~/.gg in $VISUAL or $EDITOR and saves the changes when
+// they make a valid config.
+func (g *GG) EditConfig() error {
+	edit := func(path string) error {
+		editor := strings.Fields(cmp.Or(os.Getenv("VISUAL"), os.Getenv("EDITOR"), "vi"))
+		cmd := exec.Command(editor[0], append(editor[1:], path)...) // #nosec G204
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			return configError("failed to run %s: %w", editor[0], err)
+		}
+		return nil
+	}
+	// Edit keeps the file as written, unless an account ID has to be updated
+	refreshed := false
+	check := func(changed *cfg.Config) error {
+		if err := checkFormats(changed.Settings()); err != nil {
+			return err
+		}
+		for _, profile := range changed.ListProfiles() {
+			if !accountChanged(g.Config.ProfileAccount(profile.Name), changed.ProfileAccount(profile.Name)) {
+				continue
+			}
+			if err := changed.UseProfile(profile.Name); err != nil {
+				return err
+			}
+			token, err := changed.Token(cfg.JiraTokenKey)
+			if err != nil {
+				return err
+			}
+			accountID := changed.Account().JiraAccountID
+			if err := checkJira(changed, token); err != nil {
+				return err
+			}
+			refreshed = refreshed || changed.Account().JiraAccountID != accountID
+		}
+		return nil
+	}
+	if err := g.Config.Edit(edit, check); err != nil {
+		return configError("%w", err)
+	}
+	if refreshed {
+		if err := g.Config.Save(); err != nil {
+			return configError("failed to save config: %w", err)
+		}
+	}
+	log.Println("Saved ~/.gg")
+	return nil
+}
+
+// InitConfig runs the setup of the first run again, starting from the config
+// as it is. The token stays unless a new one is given.
+func (g *GG) InitConfig() error {
+	if err := g.Config.UseProfile(cfg.DefaultProfile); err != nil {
+		return configError("%w", err)
+	}
+	token, err := g.Config.Token(cfg.JiraTokenKey)
+	if err != nil {
+		return configError("failed to get Jira token: %w", err)
+	}
+	previousURL := g.Config.JiraURL
+	// the form doesn't show the token, with token_store plain it's in there
+	plaintextToken := g.Config.JiraToken
+	g.Config.JiraToken = ""
+	if err := g.Gui.EditConfig(g.Config); err != nil {
+		return err
+	}
+	newToken := g.Config.JiraToken
+	g.Config.JiraToken = plaintextToken
+	if newToken != "" {
+		token = newToken
+	}
+	if g.Config.JiraURL != previousURL {
+		g.Config.JiraDeployment = ""
+	}
+	if err := g.Config.Validate(); err != nil {
+		return configError("%w", err)
+	}
+	if err := checkJira(g.Config, token); err != nil {
+		return err
+	}
+	if newToken != "" {
+		if err := g.Config.SetToken(cfg.JiraTokenKey, newToken); err != nil {
+			return configError("failed to store the token: %w", err)
+		}
+	}
+	if err := g.Config.Save(); err != nil {
+		return configError("failed to save config: %w", err)
+	}
+	log.Println("Saved ~/.gg")
+	return nil
+}
+
+// checkJira makes sure Jira takes the account of the profile in use.
+func checkJira(config *cfg.Config, token string) error {
+	jira, err := connectJira(config, token)
+	if err != nil {
+		return err
+	}
+	id, err := jira.LookupMyAccountID()
+	if err != nil {
+		return jiraErrorf("Jira turned down the account, nothing was saved: %w", err)
+	}
+	// the account may be another one now
+	config.Account().JiraAccountID = *id
+	return nil
+}
+
+func accountChanged(before, after *cfg.JiraAccount) bool {
+	return before == nil || before.JiraURL != after.JiraURL || before.JiraUser != after.JiraUser ||
+		before.JiraToken != after.JiraToken || before.JiraDeployment != after.JiraDeployment
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bricktopab/gg/cfg"
+)
+
+// fakeConnect stands in for Jira when the config is checked, turning down
+// the token "wrong".
+func fakeConnect(t *testing.T) *[]string {
+	t.Helper()
+	var connected []string
+	connectJira = func(config *cfg.Config, token string) (Jira, error) {
+		connected = append(connected, config.Account().JiraURL+" "+token)
+		if token == "wrong" {
+			return &fakeJira{err: jiraErrorf("401 Unauthorized")}, nil
+		}
+		return &fakeJira{}, nil
+	}
+	t.Cleanup(func() {
+		connectJira = newJiraClient
+	})
+	return &connected
+}
+
+func TestSetConfig(t *testing.T) {
+	connected := fakeConnect(t)
+	gg, _ := newTestGG(t, &fakeJira{}, &fakeGit{})
+	gg.Config.TokenStore = cfg.TokenStorePlain
+	gg.Config.JiraDeployment = cfg.JiraCloud
+	gg.Config.JiraAccountID = "someone-else"
+
+	if err := gg.SetConfig("board", "12"); err != nil || gg.Config.Board != 12 {
+		t.Fatalf("SetConfig(board) = %v, board %d", err, gg.Config.Board)
+	}
+	if len(*connected) != 0 {
+		t.Errorf("SetConfig(board) went to Jira: %v", *connected)
+	}
+	if err := gg.SetConfig("branch_format", "{{.Slug}}"); exitCode(err) != exitConfig {
+		t.Errorf("SetConfig() with a branch format that loses the key = %v, want a config error", err)
+	}
+
+	if err := gg.SetConfig("jira_url", "https://other.atlassian.net"); err != nil {
+		t.Fatalf("SetConfig(jira_url) failed: %v", err)
+	}
+	if gg.Config.JiraDeployment != "" || len(*connected) != 1 || (*connected)[0] != "https://other.atlassian.net " {
+		t.Errorf("SetConfig(jira_url) checked %v, deployment %q", *connected, gg.Config.JiraDeployment)
+	}
+	if gg.Config.JiraAccountID != "me" {
+		t.Errorf("SetConfig(jira_url) kept account ID %q", gg.Config.JiraAccountID)
+	}
+
+	if err := gg.SetConfig("jira_token", "wrong"); exitCode(err) != exitJira {
+		t.Errorf("SetConfig() with a token Jira turns down = %v, want a Jira error", err)
+	}
+	if token, _ := gg.Config.Token(cfg.JiraTokenKey); token != "" {
+		t.Errorf("SetConfig() stored the token Jira turned down: %q", token)
+	}
+	if err := gg.SetConfig("jira_token", "right"); err != nil {
+		t.Fatalf("SetConfig(jira_token) failed: %v", err)
+	}
+	if token, _ := gg.Config.Token(cfg.JiraTokenKey); token != "right" {
+		t.Errorf("SetConfig(jira_token) stored %q", token)
+	}
+}
+
+func TestUnsetConfig(t *testing.T) {
+	gg, _ := newTestGG(t, &fakeJira{}, &fakeGit{})
+	gg.Config.Board = 12
+	if err := gg.UnsetConfig("board"); err != nil || gg.Config.Board != 0 {
+		t.Errorf("UnsetConfig(board) = %v, board %d", err, gg.Config.Board)
+	}
+	if err := gg.UnsetConfig("nope"); !errors.Is(err, cfg.ErrUnknownKey) {
+		t.Errorf("UnsetConfig(nope) = %v, want an unknown key", err)
+	}
+}
+
+func TestInitConfig(t *testing.T) {
+	connected := fakeConnect(t)
+	gg, ui := newTestGG(t, &fakeJira{}, &fakeGit{})
+	gg.Config.TokenStore = cfg.TokenStorePlain
+	gg.Config.JiraUser = "me"
+	gg.Config.JiraToken = "old"
+	gg.Config.JiraDeployment = cfg.JiraCloud
+
+	ui.edit = func(config *cfg.Config) {
+		if config.JiraUser != "me" || config.JiraToken != "" {
+			t.Errorf("EditConfig() got %+v, want the current values without the token", config.JiraAccount)
+		}
+		config.JiraProject = "NEW"
+	}
+	if err := gg.InitConfig(); err != nil {
+		t.Fatalf("InitConfig() failed: %v", err)
+	}
+	if token, _ := gg.Config.Token(cfg.JiraTokenKey); token != "old" || gg.Config.JiraProject != "NEW" {
+		t.Errorf("InitConfig() left token %q and project %q", token, gg.Config.JiraProject)
+	}
+	if len(*connected) != 1 || (*connected)[0] != "https://example.atlassian.net old" {
+		t.Errorf("InitConfig() checked %v", *connected)
+	}
+
+	ui.edit = func(config *cfg.Config) {
+		config.JiraToken = "wrong"
+	}
+	if err := gg.InitConfig(); exitCode(err) != exitJira {
+		t.Errorf("InitConfig() with a token Jira turns down = %v, want a Jira error", err)
+	}
+}
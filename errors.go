@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bricktopab/gg/gui"
+)
+
+// Exit codes tell scripts what went wrong without parsing messages.
+const (
+	exitFailure   = 1
+	exitConfig    = 2
+	exitJira      = 3
+	exitGit       = 4
+	exitCancelled = 130
+)
+
+// codedError decides the exit code gg ends with.
+type codedError struct {
+	code int
+	err  error
+}
+
+func (e *codedError) Error() string { return e.err.Error() }
+
+func (e *codedError) Unwrap() error { return e.err }
+
+func configError(format string, args ...any) error {
+	return &codedError{code: exitConfig, err: fmt.Errorf(format, args...)}
+}
+
+func jiraErrorf(format string, args ...any) error {
+	return &codedError{code: exitJira, err: fmt.Errorf(format, args...)}
+}
+
+func gitError(format string, args ...any) error {
+	return &codedError{code: exitGit, err: fmt.Errorf(format, args...)}
+}
+
+func exitCode(err error) int {
+	if errors.Is(err, gui.ErrCancelled) {
+		return exitCancelled
+	}
+	var coded *codedError
+	if errors.As(err, &coded) {
+		return coded.code
+	}
+	return exitFailure
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/bricktopab/gg/cfg"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+var stripOddNameChars = regexp.MustCompile(`[^\w\-\.~]`)
+var stripOddBranchChars = regexp.MustCompile(`[^\w\-\.~/]`)
+var repeatedDashes = regexp.MustCompile(`-{2,}`)
+
+// Jira project keys start with a letter and may contain digits and underscores
+var taskIDRe = regexp.MustCompile(`([A-Z][A-Z0-9_]+-\d+)`)
+var lowerTaskIDRe = regexp.MustCompile(`(?i)\b([a-z][a-z0-9_]+-\d+)`)
+
+type branchNameData struct {
+	Key   string
+	Title string
+	Slug  string
+	Type  string
+}
+
+type prTitleData struct {
+	Key      string
+	Title    string
+	Type     string
+	Prefix   string
+	Scope    string
+	Breaking bool
+}
+
+// conventionalTypes are the Conventional Commits types offered as PR title
+// prefixes.
+var conventionalTypes = []string{
+	"feat", "fix", "docs", "style", "refactor", "perf", "test", "build", "ci", "chore", "revert",
+}
+
+// prTitlePresets can be used by name as pr_title_format.
+var prTitlePresets = map[string]string{
+	// feat(ABC-12, api)!: Title, or ABC-12: Title without a prefix
+	"conventional": "{{if .Prefix}}{{.Prefix}}({{.Key}}{{if .Scope}}, {{.Scope}}{{end}}){{if .Breaking}}!{{end}}" +
+		"{{else}}{{.Key}}{{end}}: {{.Title}}",
+	// [ABC-12] Title
+	"brackets": "[{{.Key}}] {{.Title}}",
+	// ABC-12 | feat(api)!: Title
+	"pipe": "{{.Key}} | {{if .Prefix}}{{.Prefix}}{{if .Scope}}({{.Scope}}){{end}}{{if .Breaking}}!{{end}}: {{end}}{{.Title}}",
+}
+
+type prBodyData struct {
+	Key                string
+	URL                string
+	Title              string
+	Description        string
+	AcceptanceCriteria string
+	Commits            []string
+	// Template is the repository's pull request template, if any
+	Template string
+}
+
+// prBodyPresets can be used by name as pr_body_format.
+var prBodyPresets = map[string]string{
+	"default": `[{{.Key}}]({{.URL}}): {{.Title}}
+{{- if .Description}}
+
+## Description
+
+{{.Description}}
+{{- end}}
+{{- if .AcceptanceCriteria}}
+
+## Acceptance criteria
+
+{{.AcceptanceCriteria}}
+{{- end}}
+{{- if .Commits}}
+
+## Commits
+{{range .Commits}}
+- {{.}}
+{{- end}}
+{{- end}}
+
+{{if .Template}}{{.Template}}{{else}}## Checklist
+
+- [ ] Tests added or updated
+- [ ] Documentation updated
+- [ ] Acceptance criteria met{{end}}
+`,
+}
+
+var templateFuncs = template.FuncMap{
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// render fills in a template from the config, so a broken one is a config error.
+func render(name, format string, data any) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(templateFuncs).Parse(format)
+	if err != nil {
+		return "", configError("invalid %s: %w", name, err)
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", configError("invalid %s: %w", name, err)
+	}
+	return sb.String(), nil
+}
+
+// findTaskID digs the issue key out of a branch name, also when a branch
+// format has lowercased it.
+func findTaskID(branch string) string {
+	if taskID := taskIDRe.FindString(branch); taskID != "" {
+		return taskID
+	}
+	return strings.ToUpper(lowerTaskIDRe.FindString(branch))
+}
+
+// specialLetters don't decompose into a base letter and accents.
+var specialLetters = strings.NewReplacer(
+	"ß", "ss", "Æ", "AE", "æ", "ae", "Ø", "O", "ø", "o", "Œ", "OE", "œ", "oe",
+	"Ł", "L", "ł", "l", "Đ", "D", "đ", "d", "Þ", "TH", "þ", "th",
+)
+
+// transliterate turns "Fix Ångström på händelser" into "Fix Angstrom pa handelser".
+func transliterate(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	result, _, err := transform.String(t, specialLetters.Replace(s))
+	if err != nil {
+		return s
+	}
+	return result
+}
+
+func slugify(s string, lowercase bool) string {
+	slug := transliterate(strings.TrimSpace(s))
+	if lowercase {
+		slug = strings.ToLower(slug)
+	}
+	slug = stripOddNameChars.ReplaceAllString(strings.Join(strings.Fields(slug), "-"), "")
+	return strings.Trim(repeatedDashes.ReplaceAllString(slug, "-"), "-")
+}
+
+// truncateSlug cuts a slug to at most maxLen characters, preferably between words.
+func truncateSlug(slug string, maxLen int) string {
+	if len(slug) <= maxLen {
+		return slug
+	}
+	if maxLen <= 0 {
+		return ""
+	}
+	cut := slug[:maxLen]
+	if slug[maxLen] != '-' {
+		if idx := strings.LastIndex(cut, "-"); idx > 0 {
+			cut = cut[:idx]
+		}
+	}
+	return strings.TrimRight(cut, "-.")
+}
+
+func formatBranchName(settings cfg.Settings, task *cfg.Task) (string, error) {
+	branchType, ok := settings.BranchTypes[task.Type]
+	if !ok {
+		branchType = slugify(task.Type, true)
+	}
+	data := branchNameData{
+		Key:   task.IssueID,
+		Title: task.Title,
+		Slug:  slugify(task.Title, settings.Lowercase()),
+		Type:  branchType,
+	}
+	branchName, err := renderBranchName(settings.BranchFormat, data)
+	if err != nil {
+		return "", err
+	}
+
+	if settings.BranchMaxLength > 0 && len(branchName) > settings.BranchMaxLength {
+		data.Slug = truncateSlug(data.Slug, len(data.Slug)-(len(branchName)-settings.BranchMaxLength))
+		if branchName, err = renderBranchName(settings.BranchFormat, data); err != nil {
+			return "", err
+		}
+	}
+
+	if findTaskID(branchName) != task.IssueID {
+		return "", configError("branch_format %q must keep the issue key in branch names, got %s", settings.BranchFormat, branchName)
+	}
+	return branchName, nil
+}
+
+func renderBranchName(format string, data branchNameData) (string, error) {
+	branchName, err := render("branch_format", format, data)
+	if err != nil {
+		return "", err
+	}
+	branchName = stripOddBranchChars.ReplaceAllString(strings.ReplaceAll(branchName, " ", "-"), "")
+	// an empty slug or type leaves separators behind
+	branchName = repeatedDashes.ReplaceAllString(branchName, "-")
+	return strings.Trim(strings.ReplaceAll(branchName, "//", "/"), "/-_"), nil
+}
+
+func formatPRTitle(format string, task *cfg.Task, options cfg.PRTitleOptions) (string, error) {
+	if preset, ok := prTitlePresets[format]; ok {
+		format = preset
+	}
+	return render("pr_title_format", format, prTitleData{
+		Key:      task.IssueID,
+		Title:    task.Title,
+		Type:     task.Type,
+		Prefix:   options.Prefix,
+		Scope:    strings.TrimSpace(options.Scope),
+		Breaking: options.Breaking,
+	})
+}
+
+func formatPRBody(format string, data prBodyData) (string, error) {
+	if preset, ok := prBodyPresets[format]; ok {
+		format = preset
+	}
+	body, err := render("pr_body_format", format, data)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(body) + "\n", nil
+}
+
+// checkFormats tries the formats out on a made-up issue, so broken ones are
+// caught when they're set rather than on the next branch or PR.
+func checkFormats(settings cfg.Settings) error {
+	task := &cfg.Task{IssueID: "ABC-12", Title: "Check the formats", Type: "Story"}
+	if _, err := formatBranchName(settings, task); err != nil {
+		return err
+	}
+	if _, err := formatPRTitle(settings.PRTitleFormat, task, cfg.PRTitleOptions{Prefix: "feat"}); err != nil {
+		return err
+	}
+	_, err := formatPRBody(settings.PRBodyFormat, prBodyData{Key: task.IssueID, Title: task.Title})
+	return err
+}
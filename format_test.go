@@ -0,0 +1,164 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/bricktopab/gg/cfg"
+)
+
+func TestFormatBranchName(t *testing.T) {
+	lowercase := true
+	tests := []struct {
+		name     string
+		settings cfg.Settings
+		task     cfg.Task
+		want     string
+	}{
+		{
+			name:     "default format",
+			settings: cfg.Settings{BranchFormat: cfg.DefaultBranchFormat},
+			task:     cfg.Task{IssueID: "ISSUE-123", Title: "CLI for easy life"},
+			want:     "ISSUE-123_CLI-for-easy-life",
+		},
+		{
+			name: "type prefix and lowercase slug",
+			settings: cfg.Settings{
+				BranchFormat:    "{{.Type}}/{{.Key}}-{{.Slug}}",
+				BranchLowercase: &lowercase,
+				BranchTypes:     map[string]string{"Story": "feature"},
+			},
+			task: cfg.Task{IssueID: "ABC-12", Title: "Add login!", Type: "Story"},
+			want: "feature/ABC-12-add-login",
+		},
+		{
+			name:     "unmapped type is slugified",
+			settings: cfg.Settings{BranchFormat: "{{.Type}}/{{.Key}}-{{.Slug}}"},
+			task:     cfg.Task{IssueID: "ABC-12", Title: "Fix it", Type: "Sub-task"},
+			want:     "sub-task/ABC-12-Fix-it",
+		},
+		{
+			name:     "non-ASCII is transliterated",
+			settings: cfg.Settings{BranchFormat: cfg.DefaultBranchFormat, BranchLowercase: &lowercase},
+			task:     cfg.Task{IssueID: "ABC-1", Title: "Größe på Ångström-mätning  ökar"},
+			want:     "ABC-1_grosse-pa-angstrom-matning-okar",
+		},
+		{
+			name:     "truncated at a word boundary",
+			settings: cfg.Settings{BranchFormat: cfg.DefaultBranchFormat, BranchMaxLength: 30},
+			task:     cfg.Task{IssueID: "ABC-12", Title: "Make the login page a lot faster"},
+			want:     "ABC-12_Make-the-login-page-a",
+		},
+		{
+			name:     "truncated inside a long word",
+			settings: cfg.Settings{BranchFormat: cfg.DefaultBranchFormat, BranchMaxLength: 12},
+			task:     cfg.Task{IssueID: "ABC-12", Title: "Supercalifragilistic"},
+			want:     "ABC-12_Super",
+		},
+		{
+			name:     "nothing left of the slug",
+			settings: cfg.Settings{BranchFormat: cfg.DefaultBranchFormat, BranchMaxLength: 6},
+			task:     cfg.Task{IssueID: "ABC-12", Title: "Anything"},
+			want:     "ABC-12",
+		},
+		{
+			name:     "lowercased key",
+			settings: cfg.Settings{BranchFormat: "{{.Key | lower}}-{{.Slug}}", BranchLowercase: &lowercase},
+			task:     cfg.Task{IssueID: "ABC-12", Title: "Add login"},
+			want:     "abc-12-add-login",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := formatBranchName(tt.settings, &tt.task)
+			if err != nil {
+				t.Fatalf("formatBranchName() failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("formatBranchName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindTaskID(t *testing.T) {
+	for branch, want := range map[string]string{
+		"ISSUE-123_CLI-for-easy-life": "ISSUE-123",
+		"feature/ABC-12-add-login":    "ABC-12",
+		"feature/abc-12-add-login":    "ABC-12",
+		"fix/AB2_X-7-broken":          "AB2_X-7",
+		"release-2/ABC-12-add-login":  "ABC-12",
+		"main":                        "",
+	} {
+		if got := findTaskID(branch); got != want {
+			t.Errorf("findTaskID(%q) = %q, want %q", branch, got, want)
+		}
+	}
+}
+
+func TestFormatPRTitle(t *testing.T) {
+	task := &cfg.Task{IssueID: "ABC-12", Title: "Add login", Type: "Story"}
+	tests := []struct {
+		format  string
+		options cfg.PRTitleOptions
+		want    string
+	}{
+		{"conventional", cfg.PRTitleOptions{}, "ABC-12: Add login"},
+		{"conventional", cfg.PRTitleOptions{Prefix: "feat"}, "feat(ABC-12): Add login"},
+		{"conventional", cfg.PRTitleOptions{Prefix: "refactor", Scope: " api ", Breaking: true}, "refactor(ABC-12, api)!: Add login"},
+		{"brackets", cfg.PRTitleOptions{Prefix: "feat"}, "[ABC-12] Add login"},
+		{"pipe", cfg.PRTitleOptions{}, "ABC-12 | Add login"},
+		{"pipe", cfg.PRTitleOptions{Prefix: "perf", Scope: "db", Breaking: true}, "ABC-12 | perf(db)!: Add login"},
+		{"{{.Type}} {{.Key}}: {{.Title | lower}}", cfg.PRTitleOptions{}, "Story ABC-12: add login"},
+	}
+	for _, tt := range tests {
+		if got, err := formatPRTitle(tt.format, task, tt.options); err != nil || got != tt.want {
+			t.Errorf("formatPRTitle(%q, %+v) = %q, %v, want %q", tt.format, tt.options, got, err, tt.want)
+		}
+	}
+}
+
+func TestFormatPRBody(t *testing.T) {
+	data := prBodyData{
+		Key:                "ABC-12",
+		URL:                "https://example.atlassian.net/browse/ABC-12",
+		Title:              "Add login",
+		Description:        "Users need to log in.",
+		AcceptanceCriteria: "- Login works",
+		Commits:            []string{"Add login form", "Check passwords"},
+	}
+	want := `[ABC-12](https://example.atlassian.net/browse/ABC-12): Add login
+
+## Description
+
+Users need to log in.
+
+## Acceptance criteria
+
+- Login works
+
+## Commits
+
+- Add login form
+- Check passwords
+
+## Checklist
+
+- [ ] Tests added or updated
+- [ ] Documentation updated
+- [ ] Acceptance criteria met
+`
+	if got, err := formatPRBody("default", data); err != nil || got != want {
+		t.Errorf("formatPRBody(default) = %q, %v, want %q", got, err, want)
+	}
+
+	// the repository's template replaces the checklist, empty sections go away
+	data = prBodyData{Key: "ABC-12", URL: "https://jira/browse/ABC-12", Title: "Add login", Template: "## Why?"}
+	want = "[ABC-12](https://jira/browse/ABC-12): Add login\n\n## Why?\n"
+	if got, err := formatPRBody("default", data); err != nil || got != want {
+		t.Errorf("formatPRBody(default) with template = %q, %v, want %q", got, err, want)
+	}
+
+	if got, err := formatPRBody("Fixes {{.Key}}", data); err != nil || got != "Fixes ABC-12\n" {
+		t.Errorf("formatPRBody(custom) = %q, %v", got, err)
+	}
+}
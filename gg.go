@@ -1,12 +1,14 @@
 package main
 
 import (
-	"fmt"
+	"errors"
 	"log"
-	"regexp"
+	"maps"
+	"slices"
 	"strings"
 
 	"github.com/bricktopab/gg/cfg"
+	"github.com/bricktopab/gg/hosting"
 )
 
 type GG struct {
@@ -17,61 +19,459 @@ type GG struct {
 }
 
 type Jira interface {
-	CreateIssue(typeID string, name string, description string) *cfg.Task
-	FindOpenIssues(onlyMine bool) []cfg.Task
-	GetIssueTypes() map[string]string
+	LookupMyAccountID() (*string, error)
+	CreateIssue(draft *cfg.IssueDraft) (*cfg.Task, error)
+	LinkIssue(issueID string, link cfg.IssueLink) error
+	SearchIssues(jql string) ([]cfg.Task, error)
+	GetIssueTypes(subtask bool) (map[string]string, error)
+	IssueFields(typeID string) ([]cfg.IssueField, error)
+	AssignToMe(issueID string) (bool, error)
+	TransitionIssue(issueID, transition string) (bool, error)
+	GetIssue(issueID, acceptanceCriteriaField string) (*cfg.Task, error)
+	ActiveSprint(boardID int) (*cfg.Sprint, error)
+	SprintIssues(sprintID int) ([]cfg.SprintIssue, error)
+	AddToSprint(sprintID int, issueID string) error
 }
 
 type Git interface {
-	SwitchLocalBranch(name string)
+	SwitchLocalBranch(name string) error
 	ChangesInRemote() bool
-	GetBranchName() string
-	CreatePR() string
-	OpenPR(string)
+	GetBranchName() (string, error)
+	BaseBranches() []string
+	CreatePR(title, base, body string) (*hosting.PullRequest, error)
+	OpenPR(title, base, body string) error
+	Commits(base string) []string
+	PRTemplate() string
+	MergedPR(branch string) (*hosting.PullRequest, error)
+	Pull() error
+	DeleteBranch(name string, remote bool) error
 }
 
-func (g *GG) CreateIssue(name string, description string) {
-	typeID, typeName, title, description := g.Gui.AskForIssueDetails(name,
-		description, g.Jira.GetIssueTypes)
+// IssueOptions are what gg new takes besides the title and description.
+type IssueOptions struct {
+	// Sprint adds the issue to the active sprint of the board
+	Sprint bool
+	// Parent makes the issue a sub-task of it, "." is the current branch's issue
+	Parent string
+	// Epic is the parent of an issue that's not a sub-task
+	Epic  string
+	Links []cfg.IssueLink
+	// Fields are values for other fields by field name or ID
+	Fields map[string]string
+}
+
+// currentIssue is how --parent refers to the issue of the current branch.
+const currentIssue = "."
+
+// CreateIssue creates an issue and a branch for it, optionally adding the
+// issue to the active sprint of the configured board.
+func (g *GG) CreateIssue(name string, description string, options IssueOptions) error {
+	if options.Parent != "" && options.Epic != "" {
+		return configError("a sub-task can't have an epic, pick either --parent or --epic")
+	}
+	var sprint *cfg.Sprint
+	if options.Sprint {
+		var err error
+		if sprint, err = g.activeSprint(); err != nil {
+			return err
+		}
+	}
+
+	draft := &cfg.IssueDraft{
+		Title:       name,
+		Description: description,
+		Subtask:     options.Parent != "",
+		Parent:      options.Parent + options.Epic,
+		Links:       options.Links,
+	}
+	if options.Parent == currentIssue {
+		_, taskID, err := g.currentTaskID()
+		if err != nil {
+			return err
+		}
+		draft.Parent = taskID
+	}
+	issueTypes := func() (map[string]string, error) { return g.Jira.GetIssueTypes(draft.Subtask) }
+	project := g.Config.Settings().JiraProject
+	issueFields := func(typeID string) ([]cfg.IssueField, error) {
+		fields, err := g.Jira.IssueFields(typeID)
+		if err != nil {
+			return nil, err
+		}
+		return pickFields(fields, g.Config.Settings().IssueFields[project], options.Fields)
+	}
+	if err := g.Gui.AskForIssueDetails(draft, issueTypes, issueFields); err != nil {
+		return err
+	}
 
-	task := g.Jira.CreateIssue(typeID, title, description)
-	task.Type = typeName
-	g.Config.AddTask(task)
+	task, err := g.Jira.CreateIssue(draft)
+	if err != nil {
+		return err
+	}
+	if err := g.Config.AddTask(task); err != nil {
+		return configError("failed to save task: %w", err)
+	}
 
-	branchName := formatBranchName(task.IssueID, task.Title)
-	g.Git.SwitchLocalBranch(branchName)
+	branchName, err := formatBranchName(g.Config.Settings(), task)
+	if err != nil {
+		return err
+	}
+	if err := g.Git.SwitchLocalBranch(branchName); err != nil {
+		return err
+	}
+
+	// the issue is there and worked on by now, whatever else fails
+	if err := g.Config.RememberIssueFields(project, draft.Fields); err != nil {
+		log.Printf("Could not save the field values as defaults: %v\n", err)
+	}
+	var links []string
+	for _, link := range draft.Links {
+		if err := g.Jira.LinkIssue(task.IssueID, link); err != nil {
+			log.Printf("Could not link %s: %v\n", task.IssueID, err)
+			continue
+		}
+		links = append(links, link.String())
+	}
+	if sprint != nil {
+		if err := g.Jira.AddToSprint(sprint.ID, task.IssueID); err != nil {
+			log.Printf("Could not add %s to %s: %v\n", task.IssueID, sprint.Name, err)
+			sprint = nil
+		}
+	}
+
+	result := &cfg.Result{
+		Command:  "new",
+		Issue:    task.IssueID,
+		Title:    task.Title,
+		Type:     task.Type,
+		IssueURL: g.issueURL(task.IssueID),
+		Branch:   branchName,
+		Parent:   draft.Parent,
+		Links:    links,
+	}
+	if sprint != nil {
+		result.Sprint = sprint.Name
+	}
+	return g.Gui.ShowSummary(result)
 }
 
-var stripOddNameChars = regexp.MustCompile(`[^\w\-\.~]`)
-var taskIDRe = regexp.MustCompile(`([A-Z]+-\d+)`)
+// pickFields are the fields gg new asks for: the required ones, the ones with
+// defaults in the config and the ones given with --field.
+func pickFields(fields []cfg.IssueField, defaults, given map[string]string) ([]cfg.IssueField, error) {
+	picked := []cfg.IssueField{}
+	used := map[string]bool{}
+	for _, field := range fields {
+		configured := false
+		for key, value := range defaults {
+			if field.Matches(key) {
+				field.Value, configured = value, true
+			}
+		}
+		for key, value := range given {
+			if field.Matches(key) {
+				field.Value, configured = value, true
+				used[key] = true
+			}
+		}
+		if configured || field.Required {
+			picked = append(picked, field)
+		}
+	}
+	for key := range given {
+		if !used[key] {
+			names := make([]string, 0, len(fields))
+			for _, field := range fields {
+				names = append(names, field.Name)
+			}
+			return nil, configError("unknown field %q, pick one of: %s", key, strings.Join(names, ", "))
+		}
+	}
+	return picked, nil
+}
+
+func (g *GG) activeSprint() (*cfg.Sprint, error) {
+	board := g.Config.Settings().Board
+	if board == 0 {
+		return nil, configError("no board configured, set board to the ID of your Jira board")
+	}
+	return g.Jira.ActiveSprint(board)
+}
 
-func formatBranchName(taskID, title string) string {
-	branchName := fmt.Sprintf("%s_%s", taskID, strings.ReplaceAll(title, " ", "-"))
-	branchName = stripOddNameChars.ReplaceAllString(branchName, "")
-	return branchName
+func (g *GG) issueURL(issueID string) string {
+	return g.Config.Account().JiraURL + "/browse/" + issueID
 }
 
-func (g *GG) PickIssue() {
-	task := g.Gui.SelectTask(g.Jira.FindOpenIssues)
-	g.Config.AddTask(task)
-	branchName := formatBranchName(task.IssueID, task.Title)
-	g.Git.SwitchLocalBranch(branchName)
+// transition moves the issue and notes it in the result when it did.
+func (g *GG) transition(result *cfg.Result, transition string) error {
+	moved, err := g.Jira.TransitionIssue(result.Issue, transition)
+	if moved {
+		result.Transitions = append(result.Transitions, transition)
+	}
+	return err
 }
 
-func (g *GG) CreatePR() {
-	branch := g.Git.GetBranchName()
-	taskID := taskIDRe.FindString(branch)
+func projectOf(issueID string) string {
+	project, _, _ := strings.Cut(issueID, "-")
+	return project
+}
+
+// currentTaskID finds the issue key in the current branch name.
+func (g *GG) currentTaskID() (string, string, error) {
+	branch, err := g.Git.GetBranchName()
+	if err != nil {
+		return "", "", err
+	}
+	taskID := findTaskID(branch)
 	if taskID == "" {
-		log.Fatal("Current branch does not contain a task ID")
+		return "", "", gitError("current branch %s does not contain a task ID", branch)
+	}
+	return branch, taskID, nil
+}
+
+// querySprint lists the open issues of the active sprint when a board is
+// configured, it's where gg issue starts then.
+const (
+	querySprint        = "sprint"
+	defaultSprintQuery = `sprint = {{.Sprint}} AND statusCategory in ("To Do", "In Progress") ORDER BY rank`
+)
+
+// queryData is what queries can refer to, Sprint is only set with a board.
+type queryData struct {
+	Project string
+	Sprint  int
+}
+
+// issueQueries are the names of the queries to pick an issue from, the one
+// asked for first.
+func issueQueries(queries map[string]string, query string) ([]string, error) {
+	names := []string{}
+	if query != "" {
+		if _, ok := queries[query]; !ok {
+			return nil, configError("unknown query %q, pick one of: %s", query,
+				strings.Join(slices.Sorted(maps.Keys(queries)), ", "))
+		}
+		names = append(names, query)
+	}
+	for _, name := range append([]string{cfg.QueryMine, cfg.QueryUnassigned}, slices.Sorted(maps.Keys(queries))...) {
+		if _, ok := queries[name]; ok && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// PickIssue starts work on an issue found with one of the configured queries,
+// or only with the given JQL.
+func (g *GG) PickIssue(query, jql string) error {
+	settings := g.Config.Settings()
+	queries := settings.Queries
+	data := queryData{Project: settings.JiraProject}
+	if jql != "" {
+		query = "jql"
+		queries = map[string]string{query: jql}
+	} else if settings.Board != 0 {
+		sprint, err := g.Jira.ActiveSprint(settings.Board)
+		switch {
+		case errors.Is(err, errNoActiveSprint):
+			log.Printf("No active sprint on board %d\n", settings.Board)
+		case err != nil:
+			return err
+		default:
+			data.Sprint = sprint.ID
+			if _, ok := queries[querySprint]; !ok {
+				queries[querySprint] = defaultSprintQuery
+			}
+			if query == "" {
+				query = querySprint
+			}
+		}
+	}
+	names, err := issueQueries(queries, query)
+	if err != nil {
+		return err
+	}
+	fetch := func(name string) ([]cfg.Task, error) {
+		jql, err := render("queries."+name, queries[name], data)
+		if err != nil {
+			return nil, err
+		}
+		return g.Jira.SearchIssues(jql)
+	}
+
+	lookup := func(issueID string) (*cfg.Task, error) {
+		return g.Jira.GetIssue(issueID, "")
+	}
+	task, err := g.Gui.SelectTask(names, fetch, lookup)
+	if err != nil {
+		return err
+	}
+	if err := g.Config.AddTask(task); err != nil {
+		return configError("failed to save task: %w", err)
+	}
+	branchName, err := formatBranchName(g.Config.Settings(), task)
+	if err != nil {
+		return err
+	}
+	if err := g.Git.SwitchLocalBranch(branchName); err != nil {
+		return err
+	}
+
+	result := &cfg.Result{
+		Command:  "issue",
+		Issue:    task.IssueID,
+		Title:    task.Title,
+		Type:     task.Type,
+		IssueURL: g.issueURL(task.IssueID),
+		Branch:   branchName,
+	}
+	if result.Assigned, err = g.Jira.AssignToMe(task.IssueID); err != nil {
+		return err
+	}
+	if err := g.transition(result, g.Config.TransitionsFor(projectOf(task.IssueID)).Start); err != nil {
+		return err
+	}
+	return g.Gui.ShowSummary(result)
+}
+
+func (g *GG) CreatePR() error {
+	branch, taskID, err := g.currentTaskID()
+	if err != nil {
+		return err
 	}
 
 	if !g.Git.ChangesInRemote() {
-		log.Fatal("Local changes need to be pushed first")
+		return gitError("local changes need to be pushed first")
 	}
 
+	settings := g.Config.Settings()
+	// the issue has the description, the stored task may not even exist
+	issue, err := g.Jira.GetIssue(taskID, settings.AcceptanceCriteriaField)
+	if err != nil {
+		return err
+	}
 	task := g.Config.GetTask(taskID)
-	title := g.Gui.AskForPRTitle(task)
-	g.Git.OpenPR(title)
+	if task.IssueID == "" {
+		task = issue
+	}
+	if _, err := formatPRTitle(settings.PRTitleFormat, task, cfg.PRTitleOptions{}); err != nil {
+		return err
+	}
+	defaults := cfg.PRTitleOptions{Prefix: settings.PRTypes[task.Type]}
+	title, base, err := g.Gui.AskForPRTitle(task, g.Git.BaseBranches(), conventionalTypes, defaults,
+		func(options cfg.PRTitleOptions) string {
+			// checked above, options don't break a template
+			title, _ := formatPRTitle(settings.PRTitleFormat, task, options)
+			return title
+		})
+	if err != nil {
+		return err
+	}
+
+	body, err := formatPRBody(settings.PRBodyFormat, prBodyData{
+		Key:                issue.IssueID,
+		URL:                g.issueURL(issue.IssueID),
+		Title:              issue.Title,
+		Description:        issue.Description,
+		AcceptanceCriteria: issue.AcceptanceCriteria,
+		Commits:            g.Git.Commits(base),
+		Template:           g.Git.PRTemplate(),
+	})
+	if err != nil {
+		return err
+	}
+	if body, err = g.Gui.AskForPRBody(body); err != nil {
+		return err
+	}
+
+	result := &cfg.Result{
+		Command:  "pr",
+		Issue:    task.IssueID,
+		Title:    task.Title,
+		Type:     task.Type,
+		IssueURL: g.issueURL(task.IssueID),
+		Branch:   branch,
+		Base:     base,
+	}
+	pr, err := g.Git.CreatePR(title, base, body)
+	switch {
+	case errors.Is(err, errNoToken):
+		if err := g.Git.OpenPR(title, base, body); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	default:
+		log.Printf("PR created: #%d\n", pr.Number)
+		result.PRNumber = pr.Number
+		result.PRURL = pr.URL
+	}
 
-	g.Gui.ShowSummary(task.IssueID, task.Title, g.Config.JiraURL+"/browse/"+task.IssueID)
+	if review := g.Config.TransitionsFor(projectOf(task.IssueID)).Review; review != "" {
+		if err := g.transition(result, review); err != nil {
+			return err
+		}
+	}
+
+	return g.Gui.ShowSummary(result)
+}
+
+func (g *GG) Done(deleteRemote bool) error {
+	branch, taskID, err := g.currentTaskID()
+	if err != nil {
+		return err
+	}
+
+	pr, err := g.Git.MergedPR(branch)
+	if errors.Is(err, hosting.ErrNoPullRequest) {
+		return gitError("found no merged PR for %s", branch)
+	}
+	if err != nil {
+		return err
+	}
+
+	result := &cfg.Result{
+		Command:  "done",
+		Issue:    taskID,
+		Title:    g.Config.GetTask(taskID).Title,
+		IssueURL: g.issueURL(taskID),
+		Branch:   branch,
+		Base:     pr.Base,
+		PRNumber: pr.Number,
+		PRURL:    pr.URL,
+	}
+	// git first, so the issue stays open when the branch is still around
+	if err := g.Git.SwitchLocalBranch(pr.Base); err != nil {
+		return err
+	}
+	if err := g.Git.Pull(); err != nil {
+		return err
+	}
+	if err := g.Git.DeleteBranch(branch, deleteRemote); err != nil {
+		return err
+	}
+	result.BranchDeleted = true
+
+	if err := g.transition(result, g.Config.TransitionsFor(projectOf(taskID)).Done); err != nil {
+		return err
+	}
+	if err := g.Config.FinishTask(taskID); err != nil {
+		return configError("failed to save task: %w", err)
+	}
+	return g.Gui.ShowSummary(result)
+}
+
+func (g *GG) ShowConfig() error {
+	return g.Gui.ShowSettings(g.Config.ListSettings())
+}
+
+// ShowSprint lists the issues of the active sprint of the configured board.
+func (g *GG) ShowSprint() error {
+	sprint, err := g.activeSprint()
+	if err != nil {
+		return err
+	}
+	if sprint.Issues, err = g.Jira.SprintIssues(sprint.ID); err != nil {
+		return err
+	}
+	return g.Gui.ShowSprint(sprint)
 }
@@ -0,0 +1,435 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bricktopab/gg/cfg"
+	"github.com/bricktopab/gg/gui"
+	"github.com/bricktopab/gg/hosting"
+)
+
+type fakeJira struct {
+	issue    *cfg.Task
+	err      error
+	moved    []string
+	issues   []cfg.Task
+	searched []string
+	sprint   *cfg.Sprint
+	added    []string
+	created  *cfg.IssueDraft
+	linked   []string
+	fields   []cfg.IssueField
+	// addErr and linkErr fail adding to the sprint and linking only
+	addErr  error
+	linkErr error
+}
+
+func (j *fakeJira) LookupMyAccountID() (*string, error) {
+	id := "me"
+	return &id, j.err
+}
+
+func (j *fakeJira) IssueFields(string) ([]cfg.IssueField, error) { return j.fields, j.err }
+
+func (j *fakeJira) CreateIssue(draft *cfg.IssueDraft) (*cfg.Task, error) {
+	j.created = draft
+	return &cfg.Task{IssueID: "ABC-1", Title: draft.Title, Description: draft.Description, Type: draft.Type}, j.err
+}
+
+func (j *fakeJira) LinkIssue(issueID string, link cfg.IssueLink) error {
+	j.linked = append(j.linked, issueID+" "+link.String())
+	return cmp.Or(j.linkErr, j.err)
+}
+
+func (j *fakeJira) SearchIssues(jql string) ([]cfg.Task, error) {
+	j.searched = append(j.searched, jql)
+	return j.issues, j.err
+}
+
+func (j *fakeJira) GetIssueTypes(subtask bool) (map[string]string, error) {
+	if subtask {
+		return map[string]string{"Sub-task": "5"}, j.err
+	}
+	return map[string]string{"Story": "1"}, j.err
+}
+
+func (j *fakeJira) AssignToMe(string) (bool, error) { return true, j.err }
+
+func (j *fakeJira) TransitionIssue(_, transition string) (bool, error) {
+	j.moved = append(j.moved, transition)
+	return true, j.err
+}
+
+func (j *fakeJira) GetIssue(string, string) (*cfg.Task, error) {
+	if j.err != nil {
+		return nil, j.err
+	}
+	return j.issue, nil
+}
+
+func (j *fakeJira) ActiveSprint(board int) (*cfg.Sprint, error) {
+	if j.sprint == nil {
+		return nil, jiraErrorf("board %d: %w", board, errNoActiveSprint)
+	}
+	return j.sprint, nil
+}
+
+func (j *fakeJira) SprintIssues(int) ([]cfg.SprintIssue, error) { return nil, j.err }
+
+func (j *fakeJira) AddToSprint(_ int, issueID string) error {
+	j.added = append(j.added, issueID)
+	return cmp.Or(j.addErr, j.err)
+}
+
+type fakeGit struct {
+	branch    string
+	createErr error
+	opened    bool
+	body      string
+	merged    *hosting.PullRequest
+	switched  []string
+	deleted   []string
+	pullErr   error
+}
+
+func (g *fakeGit) SwitchLocalBranch(name string) error {
+	g.switched = append(g.switched, name)
+	return nil
+}
+func (g *fakeGit) ChangesInRemote() bool          { return true }
+func (g *fakeGit) GetBranchName() (string, error) { return g.branch, nil }
+func (g *fakeGit) BaseBranches() []string         { return []string{"main"} }
+func (g *fakeGit) Commits(string) []string        { return []string{"Add the thing"} }
+func (g *fakeGit) PRTemplate() string             { return "" }
+func (g *fakeGit) Pull() error                    { return g.pullErr }
+func (g *fakeGit) DeleteBranch(name string, _ bool) error {
+	g.deleted = append(g.deleted, name)
+	return nil
+}
+
+func (g *fakeGit) CreatePR(_, base, body string) (*hosting.PullRequest, error) {
+	g.body = body
+	if g.createErr != nil {
+		return nil, g.createErr
+	}
+	return &hosting.PullRequest{Number: 7, URL: "https://github.com/o/r/pull/7", Base: base}, nil
+}
+
+func (g *fakeGit) OpenPR(_, _, body string) error {
+	g.body = body
+	g.opened = true
+	return nil
+}
+
+func (g *fakeGit) MergedPR(string) (*hosting.PullRequest, error) {
+	if g.merged == nil {
+		return nil, hosting.ErrNoPullRequest
+	}
+	return g.merged, nil
+}
+
+// fakeGui answers with the defaults and remembers what it was asked to show.
+type fakeGui struct {
+	gui.Unattended
+	err    error
+	result *cfg.Result
+	// edit is what the user changes in gg config init
+	edit func(*cfg.Config)
+}
+
+func (g *fakeGui) EditConfig(config *cfg.Config) error {
+	g.edit(config)
+	return g.err
+}
+
+func (g *fakeGui) AskForPRBody(body string) (string, error) { return body, g.err }
+
+func (g *fakeGui) ShowSummary(result *cfg.Result) error {
+	g.result = result
+	return nil
+}
+
+func newTestGG(t *testing.T, jira *fakeJira, git *fakeGit) (*GG, *fakeGui) {
+	t.Helper()
+	// tasks get saved to ~/.gg
+	t.Setenv("HOME", t.TempDir())
+	config := cfg.NewConfg()
+	config.JiraURL = "https://example.atlassian.net"
+	config.Transitions = map[string]cfg.Transitions{"ABC": {Review: "In Review"}}
+	ui := &fakeGui{Unattended: gui.Unattended{Answers: gui.Answers{Yes: true}}}
+	return &GG{Config: &config, Gui: ui, Jira: jira, Git: git}, ui
+}
+
+func TestCreatePR(t *testing.T) {
+	jira := &fakeJira{issue: &cfg.Task{IssueID: "ABC-1", Title: "Things", Type: "Story"}}
+	git := &fakeGit{branch: "ABC-1_Things"}
+	gg, ui := newTestGG(t, jira, git)
+
+	if err := gg.CreatePR(); err != nil {
+		t.Fatalf("CreatePR() failed: %v", err)
+	}
+	if ui.result.PRNumber != 7 || ui.result.Base != "main" || ui.result.Issue != "ABC-1" {
+		t.Errorf("CreatePR() result = %+v", ui.result)
+	}
+	if len(ui.result.Transitions) != 1 || ui.result.Transitions[0] != "In Review" {
+		t.Errorf("CreatePR() transitions = %v, want [In Review]", ui.result.Transitions)
+	}
+	if !strings.Contains(git.body, "- Add the thing") {
+		t.Errorf("CreatePR() body is missing the commits: %q", git.body)
+	}
+}
+
+func TestCreatePRWithoutToken(t *testing.T) {
+	jira := &fakeJira{issue: &cfg.Task{IssueID: "ABC-1", Title: "Things"}}
+	git := &fakeGit{branch: "ABC-1_Things", createErr: errNoToken}
+	gg, ui := newTestGG(t, jira, git)
+
+	if err := gg.CreatePR(); err != nil {
+		t.Fatalf("CreatePR() failed: %v", err)
+	}
+	if !git.opened || ui.result.PRURL != "" {
+		t.Errorf("CreatePR() without a token should open the browser, result = %+v", ui.result)
+	}
+}
+
+func TestCreatePRExitCodes(t *testing.T) {
+	tests := []struct {
+		name   string
+		branch string
+		jira   error
+		gui    error
+		want   int
+	}{
+		{"no issue key", "main", nil, nil, exitGit},
+		{"jira fails", "ABC-1_Things", jiraErrorf("JIRA Error: %s", "boom"), nil, exitJira},
+		{"cancelled", "ABC-1_Things", nil, gui.ErrCancelled, exitCancelled},
+		{"anything else", "ABC-1_Things", nil, errors.New("boom"), exitFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jira := &fakeJira{issue: &cfg.Task{IssueID: "ABC-1", Title: "Things"}, err: tt.jira}
+			gg, ui := newTestGG(t, jira, &fakeGit{branch: tt.branch})
+			ui.err = tt.gui
+
+			err := gg.CreatePR()
+			if err == nil {
+				t.Fatal("CreatePR() succeeded, want an error")
+			}
+			if got := exitCode(err); got != tt.want {
+				t.Errorf("exitCode(%v) = %d, want %d", err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDone(t *testing.T) {
+	jira := &fakeJira{}
+	git := &fakeGit{branch: "ABC-1_Things", merged: &hosting.PullRequest{Number: 7, Base: "develop"}}
+	gg, ui := newTestGG(t, jira, git)
+
+	if err := gg.Done(false); err != nil {
+		t.Fatalf("Done() failed: %v", err)
+	}
+	if len(git.switched) != 1 || git.switched[0] != "develop" || len(git.deleted) != 1 || git.deleted[0] != "ABC-1_Things" {
+		t.Errorf("Done() switched to %v and deleted %v", git.switched, git.deleted)
+	}
+	if !ui.result.BranchDeleted || !gg.Config.GetTask("ABC-1").Done {
+		t.Errorf("Done() result = %+v", ui.result)
+	}
+
+	git = &fakeGit{branch: "ABC-1_Things"}
+	gg, _ = newTestGG(t, jira, git)
+	if err := gg.Done(false); exitCode(err) != exitGit {
+		t.Errorf("Done() without a merged PR = %v, want a git error", err)
+	}
+
+	jira = &fakeJira{}
+	git = &fakeGit{branch: "ABC-1_Things", merged: &hosting.PullRequest{Base: "main"}, pullErr: gitError("failed to pull")}
+	gg, _ = newTestGG(t, jira, git)
+	if err := gg.Done(false); exitCode(err) != exitGit {
+		t.Errorf("Done() with a failing pull = %v, want a git error", err)
+	}
+	if len(jira.moved) != 0 || len(git.deleted) != 0 {
+		t.Errorf("Done() with a failing pull moved %v and deleted %v", jira.moved, git.deleted)
+	}
+}
+
+func TestPickIssue(t *testing.T) {
+	jira := &fakeJira{issues: []cfg.Task{{IssueID: "ABC-2", Title: "Other things", Type: "Bug"}}}
+	git := &fakeGit{}
+	gg, ui := newTestGG(t, jira, git)
+	gg.Config.JiraProject = "ABC"
+	gg.Config.Queries = map[string]string{"bugs": `project = "{{.Project}}" AND issuetype = Bug`}
+	ui.Answers.Issue = "ABC-2"
+
+	if err := gg.PickIssue("bugs", ""); err != nil {
+		t.Fatalf("PickIssue() failed: %v", err)
+	}
+	if len(jira.searched) != 1 || jira.searched[0] != `project = "ABC" AND issuetype = Bug` {
+		t.Errorf("PickIssue() searched %q", jira.searched)
+	}
+	if len(git.switched) != 1 || git.switched[0] != "ABC-2_Other-things" {
+		t.Errorf("PickIssue() switched to %v", git.switched)
+	}
+	if !ui.result.Assigned || ui.result.Transitions[0] != "In Progress" {
+		t.Errorf("PickIssue() result = %+v", ui.result)
+	}
+
+	jira.searched = nil
+	if err := gg.PickIssue("", "filter = 10001"); err != nil {
+		t.Fatalf("PickIssue() with JQL failed: %v", err)
+	}
+	if len(jira.searched) != 1 || jira.searched[0] != "filter = 10001" {
+		t.Errorf("PickIssue() with JQL searched %q", jira.searched)
+	}
+
+	if err := gg.PickIssue("nope", ""); exitCode(err) != exitConfig {
+		t.Errorf("PickIssue() with an unknown query = %v, want a config error", err)
+	}
+}
+
+func TestPickIssueNotListed(t *testing.T) {
+	jira := &fakeJira{issue: &cfg.Task{IssueID: "ABC-9", Title: "Someone else's", Type: "Bug"}}
+	git := &fakeGit{}
+	gg, ui := newTestGG(t, jira, git)
+	gg.Config.Queries = map[string]string{"mine": "assignee = currentUser()"}
+	ui.Answers.Issue = "ABC-9"
+
+	if err := gg.PickIssue("", ""); err != nil {
+		t.Fatalf("PickIssue() failed: %v", err)
+	}
+	if len(git.switched) != 1 || git.switched[0] != "ABC-9_Someone-elses" {
+		t.Errorf("PickIssue() switched to %v", git.switched)
+	}
+}
+
+func TestPickIssueFromSprint(t *testing.T) {
+	jira := &fakeJira{sprint: &cfg.Sprint{ID: 42, Name: "Sprint 7"}, issues: []cfg.Task{{IssueID: "ABC-2", Title: "Things"}}}
+	gg, ui := newTestGG(t, jira, &fakeGit{})
+	gg.Config.JiraProject = "ABC"
+	gg.Config.Board = 3
+	ui.Answers.Issue = "ABC-2"
+
+	if err := gg.PickIssue("", ""); err != nil {
+		t.Fatalf("PickIssue() failed: %v", err)
+	}
+	if len(jira.searched) != 1 || !strings.HasPrefix(jira.searched[0], "sprint = 42 AND") {
+		t.Errorf("PickIssue() searched %q, want the sprint first", jira.searched)
+	}
+
+	// without a sprint going on it's the usual queries
+	jira.sprint, jira.searched = nil, nil
+	if err := gg.PickIssue("", ""); err != nil {
+		t.Fatalf("PickIssue() without a sprint failed: %v", err)
+	}
+	if len(jira.searched) != 1 || !strings.Contains(jira.searched[0], "currentUser()") {
+		t.Errorf("PickIssue() without a sprint searched %q", jira.searched)
+	}
+}
+
+func TestCreateIssueInSprint(t *testing.T) {
+	jira := &fakeJira{sprint: &cfg.Sprint{ID: 42, Name: "Sprint 7"}}
+	gg, ui := newTestGG(t, jira, &fakeGit{})
+	ui.Answers.Type = "Story"
+
+	if err := gg.CreateIssue("Things", "", IssueOptions{Sprint: true}); exitCode(err) != exitConfig {
+		t.Errorf("CreateIssue() in a sprint without a board = %v, want a config error", err)
+	}
+
+	gg.Config.Board = 3
+	if err := gg.CreateIssue("Things", "", IssueOptions{Sprint: true}); err != nil {
+		t.Fatalf("CreateIssue() failed: %v", err)
+	}
+	if len(jira.added) != 1 || jira.added[0] != "ABC-1" || ui.result.Sprint != "Sprint 7" {
+		t.Errorf("CreateIssue() added %v to the sprint, result = %+v", jira.added, ui.result)
+	}
+
+	// the issue is still worked on when the sprint won't take it
+	jira.addErr = jiraErrorf("sprint is closed")
+	git := &fakeGit{}
+	gg.Git = git
+	if err := gg.CreateIssue("Things", "", IssueOptions{Sprint: true}); err != nil {
+		t.Fatalf("CreateIssue() with a failing sprint failed: %v", err)
+	}
+	if len(git.switched) != 1 || gg.Config.GetTask("ABC-1").IssueID != "ABC-1" || ui.result.Sprint != "" {
+		t.Errorf("CreateIssue() with a failing sprint switched to %v, result = %+v", git.switched, ui.result)
+	}
+}
+
+func TestCreateSubtask(t *testing.T) {
+	jira := &fakeJira{}
+	gg, ui := newTestGG(t, jira, &fakeGit{branch: "ABC-7_Parent-things"})
+	ui.Answers.Type = "sub-task"
+	options := IssueOptions{Parent: currentIssue, Links: []cfg.IssueLink{{Relation: "blocks", Issue: "ABC-9"}}}
+
+	if err := gg.CreateIssue("Things", "", options); err != nil {
+		t.Fatalf("CreateIssue() failed: %v", err)
+	}
+	if jira.created.Parent != "ABC-7" || jira.created.TypeID != "5" {
+		t.Errorf("CreateIssue() created %+v, want a sub-task of ABC-7", jira.created)
+	}
+	if len(jira.linked) != 1 || jira.linked[0] != "ABC-1 blocks ABC-9" || ui.result.Parent != "ABC-7" {
+		t.Errorf("CreateIssue() linked %v, result = %+v", jira.linked, ui.result)
+	}
+
+	// a link Jira turns down leaves the issue worked on
+	jira.linkErr = jiraErrorf("no such issue")
+	git := &fakeGit{branch: "ABC-7_Parent-things"}
+	gg.Git = git
+	if err := gg.CreateIssue("Things", "", options); err != nil {
+		t.Fatalf("CreateIssue() with a failing link failed: %v", err)
+	}
+	if len(git.switched) != 1 || len(ui.result.Links) != 0 {
+		t.Errorf("CreateIssue() with a failing link switched to %v, result = %+v", git.switched, ui.result)
+	}
+
+	options = IssueOptions{Parent: "ABC-7", Epic: "ABC-2"}
+	if err := gg.CreateIssue("Things", "", options); exitCode(err) != exitConfig {
+		t.Errorf("CreateIssue() with a parent and an epic = %v, want a config error", err)
+	}
+}
+
+func TestCreateIssueFields(t *testing.T) {
+	jira := &fakeJira{fields: []cfg.IssueField{
+		{ID: "components", Name: "Components", Required: true, Type: "array", Items: "component"},
+		{ID: "priority", Name: "Priority", Type: "priority"},
+		{ID: "customfield_10016", Name: "Story Points", Type: "number"},
+	}}
+	gg, ui := newTestGG(t, jira, &fakeGit{})
+	gg.Config.JiraProject = "ABC"
+	gg.Config.IssueFields = map[string]map[string]string{"ABC": {"priority": "Medium"}}
+	ui.Answers.Type = "Story"
+
+	options := IssueOptions{Fields: map[string]string{"components": "API, Web", "story points": "3"}}
+	if err := gg.CreateIssue("Things", "", options); err != nil {
+		t.Fatalf("CreateIssue() failed: %v", err)
+	}
+	if got := jira.created.Fields; len(got) != 3 || got[0].Value != "API, Web" || got[1].Value != "Medium" || got[2].Value != "3" {
+		t.Errorf("CreateIssue() fields = %+v", got)
+	}
+	want := map[string]string{"priority": "Medium", "components": "API, Web", "customfield_10016": "3"}
+	if !maps.Equal(gg.Config.IssueFields["ABC"], want) {
+		t.Errorf("CreateIssue() remembered %v, want %v", gg.Config.IssueFields["ABC"], want)
+	}
+
+	options = IssueOptions{Fields: map[string]string{"Severity": "High"}}
+	if err := gg.CreateIssue("Things", "", options); exitCode(err) != exitConfig {
+		t.Errorf("CreateIssue() with an unknown field = %v, want a config error", err)
+	}
+}
+
+func TestIssueQueries(t *testing.T) {
+	queries := map[string]string{"mine": "", "unassigned": "", "sprint": "", "bugs": ""}
+	names, err := issueQueries(queries, "sprint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"sprint", "mine", "unassigned", "bugs"}; !slices.Equal(names, want) {
+		t.Errorf("issueQueries() = %v, want %v", names, want)
+	}
+}
@@ -1,38 +1,341 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
-	"net/url"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
+
+	"github.com/bricktopab/gg/hosting"
 )
 
 type ExternalGit struct {
+	// Hosts maps remote hosts to hosting provider kinds for hosts that can't
+	// be told apart by name
+	Hosts map[string]string
+	Token func(kind string) (string, error)
+	// BaseBranch comes from the gg config, `git config gg.base` wins over it
+	BaseBranch string
+}
+
+// parentConfigKey is where we remember which branch a new branch was cut from,
+// so stacked branches can be PRed against their parent.
+const parentConfigKey = "gg-parent"
+
+func gitOutput(args ...string) (string, error) {
+	output, err := exec.Command("git", args...).Output()
+	return strings.TrimSpace(string(output)), err
 }
 
-func (g *ExternalGit) SwitchLocalBranch(name string) {
+func (g *ExternalGit) SwitchLocalBranch(name string) error {
 	// try switching first, then creating
+	current, _ := gitOutput("branch", "--show-current")
 	_, err := exec.Command("git", "switch", name).CombinedOutput()
 	if err != nil {
 		output, err := exec.Command("git", "switch", "-c", name).CombinedOutput()
 		if err != nil {
-			log.Fatalf("Failed to create local branch: %s, %s", output, err)
+			return gitError("failed to create local branch: %s, %w", output, err)
+		}
+		if current != "" && current != g.DefaultBranch() && current != g.configuredBase() {
+			_, _ = gitOutput("config", "branch."+name+"."+parentConfigKey, current)
+		}
+	}
+	return nil
+}
+
+// DefaultBranch resolves the branch origin/HEAD points at, falling back to
+// whichever of main and master exists on the remote.
+func (g *ExternalGit) DefaultBranch() string {
+	ref, err := gitOutput("symbolic-ref", "--short", "refs/remotes/origin/HEAD")
+	if err == nil && ref != "" {
+		return strings.TrimPrefix(ref, "origin/")
+	}
+	for _, candidate := range []string{"main", "master"} {
+		if _, err := gitOutput("show-ref", "--verify", "--quiet", "refs/remotes/origin/"+candidate); err == nil {
+			return candidate
+		}
+	}
+	return "main"
+}
+
+// configuredBase is a per-repo override, set with `git config gg.base develop`
+// or base_branch in the gg config.
+func (g *ExternalGit) configuredBase() string {
+	base, _ := gitOutput("config", "--get", "gg.base")
+	if base == "" {
+		return g.BaseBranch
+	}
+	return base
+}
+
+// BaseBranches lists PR targets for the current branch, most specific first:
+// the parent it was cut from, the per-repo override and the default branch.
+func (g *ExternalGit) BaseBranches() []string {
+	parent := ""
+	if branch, err := g.GetBranchName(); err == nil {
+		parent, _ = gitOutput("config", "--get", "branch."+branch+"."+parentConfigKey)
+	}
+	bases := []string{}
+	for _, base := range []string{parent, g.configuredBase(), g.DefaultBranch()} {
+		if base != "" && !slices.Contains(bases, base) {
+			bases = append(bases, base)
 		}
 	}
+	return bases
 }
 
-func (g *ExternalGit) GetBranchName() string {
+func (g *ExternalGit) GetBranchName() (string, error) {
 	output, err := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD").CombinedOutput()
 	if err != nil {
-		log.Fatalf("Failed to get current branch name: %s", output)
+		return "", gitError("failed to get current branch name: %s", output)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// insteadOfRewrites reads the url.<base>.insteadOf rewrites from git config.
+func insteadOfRewrites() map[string]string {
+	rewrites := map[string]string{}
+	output, err := gitOutput("config", "--get-regexp", `^url\..*\.insteadof$`)
+	if err != nil {
+		// nothing configured
+		return rewrites
+	}
+	for _, line := range strings.Split(output, "\n") {
+		key, prefix, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		base := strings.TrimSuffix(strings.TrimPrefix(key, "url."), ".insteadof")
+		rewrites[prefix] = base
+	}
+	return rewrites
+}
+
+func (g *ExternalGit) remoteRepo() (*hosting.Repo, error) {
+	// the raw URL, our resolver does the rewriting git would do
+	remoteURL, err := gitOutput("config", "--get", "remote.origin.url")
+	if err != nil {
+		return nil, gitError("failed to get remote URL, is there an origin remote?")
 	}
-	return strings.TrimSpace(string(output))
+
+	resolver := &hosting.RemoteResolver{InsteadOf: insteadOfRewrites()}
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		resolver.SSH, err = hosting.LoadSSHConfig(filepath.Join(homeDir, ".ssh", "config"))
+		if err != nil {
+			log.Printf("Ignoring ssh config: %v\n", err)
+		}
+	}
+
+	repo, err := resolver.Resolve(remoteURL)
+	if err != nil {
+		return nil, gitError("%w", err)
+	}
+	return repo, nil
+}
+
+// originRepo names the origin repository as host/owner/name, it's empty
+// outside of repositories.
+func originRepo() string {
+	repo, err := (&ExternalGit{}).remoteRepo()
+	if err != nil {
+		return ""
+	}
+	return repo.Host + "/" + repo.Owner + "/" + repo.Name
+}
+
+// provider picks the hosting provider of origin for its API. It's errNoToken
+// without a token, or when there's no telling which provider origin is on,
+// callers fall back on what works without the API then.
+func (g *ExternalGit) provider(repo *hosting.Repo) (hosting.Provider, error) {
+	kind := g.hostKind(repo.Host)
+	if kind == "" {
+		return nil, errNoToken
+	}
+	token, err := g.Token(kind)
+	if err != nil {
+		return nil, configError("failed to get %s token: %w", kind, err)
+	}
+	if token == "" {
+		return nil, errNoToken
+	}
+	provider, err := hosting.New(kind, repo.Host, token)
+	if err != nil {
+		return nil, configError("%w", err)
+	}
+	return provider, nil
 }
 
-func (g *ExternalGit) CreatePR() string {
-	return "-"
+// hostKind is the kind of hosting provider on the host, empty when gg can't
+// tell.
+func (g *ExternalGit) hostKind(host string) string {
+	if kind, ok := g.Hosts[host]; ok {
+		return kind
+	}
+	kind, _ := hosting.DetectKind(host)
+	return kind
+}
+
+// errNoToken means the hosting provider's API can't be used, a PR is opened
+// with OpenPR then.
+var errNoToken = errors.New("no token for the hosting provider")
+
+func (g *ExternalGit) CreatePR(title, base, body string) (*hosting.PullRequest, error) {
+	repo, err := g.remoteRepo()
+	if err != nil {
+		return nil, err
+	}
+	provider, err := g.provider(repo)
+	if err != nil {
+		return nil, err
+	}
+	head, err := g.GetBranchName()
+	if err != nil {
+		return nil, err
+	}
+	pr, err := provider.CreatePullRequest(context.Background(), repo, hosting.NewPullRequest{
+		Title: title,
+		Head:  head,
+		Base:  base,
+		Body:  body,
+	})
+	if err != nil {
+		return nil, gitError("%w", err)
+	}
+	return pr, nil
+}
+
+// MergedPR finds the merged PR for a branch, hosting.ErrNoPullRequest tells
+// there is none. Without a token we can only tell if the branch was merged as
+// is, squash merges go unnoticed.
+func (g *ExternalGit) MergedPR(branch string) (*hosting.PullRequest, error) {
+	repo, err := g.remoteRepo()
+	if err != nil {
+		return nil, err
+	}
+	provider, err := g.provider(repo)
+	if err != nil && !errors.Is(err, errNoToken) {
+		return nil, err
+	}
+	if err == nil {
+		pr, err := provider.FindMergedPullRequest(context.Background(), repo, branch)
+		if err != nil && !errors.Is(err, hosting.ErrNoPullRequest) {
+			return nil, gitError("%w", err)
+		}
+		return pr, err
+	}
+
+	if output, err := exec.Command("git", "fetch", "--prune", "origin").CombinedOutput(); err != nil {
+		return nil, gitError("failed to fetch from origin: %s", output)
+	}
+	// a branch without commits of its own is on base too, without being merged
+	if !upstreamGone(branch) && !hasOwnCommits(branch) {
+		return nil, hosting.ErrNoPullRequest
+	}
+	for _, base := range g.BaseBranches() {
+		if _, err := gitOutput("merge-base", "--is-ancestor", branch, "origin/"+base); err == nil {
+			return &hosting.PullRequest{Base: base}, nil
+		}
+	}
+	return nil, hosting.ErrNoPullRequest
+}
+
+// upstreamGone tells whether the branch was pushed and then deleted on origin,
+// as is usual once its PR is merged.
+func upstreamGone(branch string) bool {
+	track, err := gitOutput("for-each-ref", "--format=%(upstream:track)", "refs/heads/"+branch)
+	return err == nil && track == "[gone]"
+}
+
+// hasOwnCommits tells whether the branch got commits since it was created,
+// going by its reflog.
+func hasOwnCommits(branch string) bool {
+	reflog, err := gitOutput("reflog", "show", "--format=%H", "refs/heads/"+branch)
+	if err != nil || reflog == "" {
+		return false
+	}
+	entries := strings.Split(reflog, "\n")
+	count, err := gitOutput("rev-list", "--count", entries[len(entries)-1]+".."+branch)
+	return err == nil && count != "0"
+}
+
+func (g *ExternalGit) Pull() error {
+	output, err := exec.Command("git", "pull", "--ff-only").CombinedOutput()
+	if err != nil {
+		return gitError("failed to pull: %s", output)
+	}
+	return nil
+}
+
+// DeleteBranch deletes the local branch and, when asked, the one on origin.
+// Hosts often delete that on merge already, which is fine, and a remote one
+// that won't go is only a warning since the local branch is gone by then.
+func (g *ExternalGit) DeleteBranch(name string, remote bool) error {
+	remote = remote && !upstreamGone(name) && remoteBranchExists(name)
+	// forced, since squash merged branches never look merged to git
+	output, err := exec.Command("git", "branch", "-D", name).CombinedOutput()
+	if err != nil {
+		return gitError("failed to delete local branch: %s", output)
+	}
+	if remote {
+		if output, err := exec.Command("git", "push", "origin", "--delete", name).CombinedOutput(); err != nil {
+			log.Printf("Could not delete the remote branch %s: %s", name, output)
+		}
+	}
+	return nil
+}
+
+func remoteBranchExists(name string) bool {
+	return exec.Command("git", "ls-remote", "--exit-code", "--heads", "origin", "refs/heads/"+name).Run() == nil
+}
+
+// Commits lists the subjects of the commits on the current branch that are
+// not on base yet, oldest first.
+func (g *ExternalGit) Commits(base string) []string {
+	output, err := gitOutput("log", "--format=%s", "--reverse", "origin/"+base+"..HEAD")
+	if err != nil {
+		// base may only exist locally
+		output, err = gitOutput("log", "--format=%s", "--reverse", base+"..HEAD")
+		if err != nil {
+			log.Printf("Failed to list commits since %s\n", base)
+			return nil
+		}
+	}
+	if output == "" {
+		return nil
+	}
+	return strings.Split(output, "\n")
+}
+
+// prTemplatePaths are where GitHub looks for a pull request template, GitLab
+// and Bitbucket users tend to keep theirs in the same places.
+var prTemplatePaths = []string{
+	".github/pull_request_template.md",
+	".github/PULL_REQUEST_TEMPLATE.md",
+	"docs/pull_request_template.md",
+	"docs/PULL_REQUEST_TEMPLATE.md",
+	"pull_request_template.md",
+	"PULL_REQUEST_TEMPLATE.md",
+}
+
+// PRTemplate returns the repository's pull request template, if it has one.
+func (g *ExternalGit) PRTemplate() string {
+	root, err := gitOutput("rev-parse", "--show-toplevel")
+	if err != nil {
+		return ""
+	}
+	for _, path := range prTemplatePaths {
+		content, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(path)))
+		if err == nil {
+			return strings.TrimSpace(string(content))
+		}
+	}
+	return ""
 }
 
 func (g *ExternalGit) ChangesInRemote() bool {
@@ -41,37 +344,47 @@ func (g *ExternalGit) ChangesInRemote() bool {
 	return err == nil
 }
 
-func (g *ExternalGit) OpenPR(title string) {
-	output, err := exec.Command("git", "remote", "get-url", "origin").CombinedOutput()
+func (g *ExternalGit) OpenPR(title, base, body string) error {
+	repo, err := g.remoteRepo()
 	if err != nil {
-		log.Fatalf("Failed to get remote URL: %s", output)
+		return err
 	}
-	remoteURL := strings.TrimSpace(string(output))
-	if strings.HasPrefix(remoteURL, "git@") {
-		parts := strings.SplitN(remoteURL, ":", 2)
-		if len(parts) == 2 {
-			host := strings.TrimPrefix(parts[0], "git@")
-			repo := parts[1]
-			remoteURL = "https://" + host + "/" + repo
-		}
+	kind := g.hostKind(repo.Host)
+	if kind == "" {
+		log.Printf("Can't tell which hosting provider %s is, set it in hosts in ~/.gg to get the PR page\n", repo.Host)
+		return openBrowser("https://" + repo.Host + "/" + repo.Owner + "/" + repo.Name)
 	}
-	remoteURL = strings.TrimSuffix(remoteURL, ".git")
-
-	encodedTitle := url.QueryEscape(title)
-
-	branch := g.GetBranchName()
+	// only the URLs, which need no token
+	provider, err := hosting.New(kind, repo.Host, "")
+	if err != nil {
+		return configError("%w", err)
+	}
+	head, err := g.GetBranchName()
+	if err != nil {
+		return err
+	}
+	createPRURL := provider.NewPullRequestURL(repo, hosting.NewPullRequest{
+		Title: title,
+		Head:  head,
+		Base:  base,
+		Body:  body,
+	})
 
-	createPRURL := fmt.Sprintf("%s/compare/main...%s?quick_pull=1&title=%s", remoteURL, branch, encodedTitle)
+	return openBrowser(createPRURL)
+}
 
+func openBrowser(url string) error {
+	var err error
 	switch runtime.GOOS {
 	case "windows":
-		err = exec.Command("cmd", "/c", "start", createPRURL).Start() // #nosec G204
+		err = exec.Command("cmd", "/c", "start", url).Start() // #nosec G204
 	case "darwin":
-		err = exec.Command("open", createPRURL).Start() // #nosec G204
+		err = exec.Command("open", url).Start() // #nosec G204
 	default:
-		err = exec.Command("xdg-open", createPRURL).Start() // #nosec G204
+		err = exec.Command("xdg-open", url).Start() // #nosec G204
 	}
 	if err != nil {
-		log.Fatalf("Failed to open URL: %v", err)
+		return fmt.Errorf("failed to open %s: %w", url, err)
 	}
+	return nil
 }
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// gitRepo clones a fresh repository with one commit on main into a temp dir
+// and works in the clone, returning the path of its origin.
+func gitRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	origin := filepath.Join(dir, "origin.git")
+	clone := filepath.Join(dir, "clone")
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(dir, "gitconfig"))
+	t.Setenv("GIT_AUTHOR_NAME", "gg")
+	t.Setenv("GIT_AUTHOR_EMAIL", "gg@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "gg")
+	t.Setenv("GIT_COMMITTER_EMAIL", "gg@example.com")
+	run(t, "", "init", "--bare", "--initial-branch=main", origin)
+	run(t, "", "clone", origin, clone)
+	t.Chdir(clone)
+	run(t, clone, "commit", "--allow-empty", "-m", "first")
+	run(t, clone, "push", "origin", "HEAD:main")
+	return origin
+}
+
+func run(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, output)
+	}
+}
+
+func TestDeleteBranchGoneFromRemote(t *testing.T) {
+	origin := gitRepo(t)
+	git := &ExternalGit{}
+	for _, branch := range []string{"GG-1_Deleted", "GG-2_Kept"} {
+		run(t, "", "switch", "-c", branch, "main")
+		run(t, "", "push", "-u", "origin", branch)
+	}
+	run(t, "", "switch", "main")
+	// deleted by the host on merge
+	run(t, origin, "branch", "-D", "GG-1_Deleted")
+
+	if err := git.DeleteBranch("GG-1_Deleted", true); err != nil {
+		t.Errorf("DeleteBranch() of a branch gone from origin = %v", err)
+	}
+	if err := git.DeleteBranch("GG-2_Kept", true); err != nil {
+		t.Errorf("DeleteBranch() = %v", err)
+	}
+	if remoteBranchExists("GG-2_Kept") {
+		t.Error("DeleteBranch() left the remote branch")
+	}
+	if err := exec.Command("git", "rev-parse", "--verify", "refs/heads/GG-1_Deleted").Run(); err == nil {
+		t.Error("DeleteBranch() left the local branch")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"os"
 	"strings"
 
 	"github.com/bricktopab/gg/cfg"
@@ -15,46 +16,66 @@ import (
 type Gui struct {
 }
 
-func (g *Gui) AskForConfig() *cfg.Config {
-	var cfg = cfg.NewConfg()
+// ErrCancelled is returned when a form is quit with ctrl+c or esc.
+var ErrCancelled = errors.New("cancelled")
+
+func run(form *huh.Form) error {
+	err := form.Run()
+	if errors.Is(err, huh.ErrUserAborted) {
+		return ErrCancelled
+	}
+	return err
+}
+
+func (g *Gui) AskForConfig() (*cfg.Config, error) {
+	var config = cfg.NewConfg()
 	form := huh.NewForm(
 		huh.NewGroup(
 			huh.NewNote().Title("Welcome to gg!").
-				Description("This seems to be your first run. Please provde basic config which we'll hide in ~/.gg\n\n").
+				Description("This seems to be your first run. Please provde basic config which we'll hide in ~/.gg,\n"+
+					"the token goes to your keyring, or to ~/.gg-secrets encrypted with $GG_PASSPHRASE.\n"+
+					"Without either it stays in ~/.gg as plain text.\n\n").
 				Next(true).
 				NextLabel("Continue"),
 		),
-		huh.NewGroup(
-			huh.NewInput().
-				Title("Jira Username").
-				Placeholder("someone@example.com").
-				Validate(validateString("username cannot be empty")).
-				Value(&cfg.JiraUser),
-			huh.NewInput().
-				Title("JIRA API Token").
-				Description("Generate one at https://id.atlassian.com/manage-profile/security/api-tokens").
-				Placeholder("..hamana...").
-				EchoMode(huh.EchoModePassword).
-				Validate(validateString("API Token cannot be empty")).
-				Value(&cfg.JiraToken),
-			huh.NewInput().
-				Title("Jira URL").
-				Placeholder("https://example.atlassian.net").
-				Validate(validateString("URL cannot be empty")).
-				Value(&cfg.JiraURL),
-			huh.NewInput().
-				Title("JIRA projct key").
-				Placeholder("PROJ").
-				Validate(validateString("project key cannot be empty")).
-				Value(&cfg.JiraProject),
-		),
+		configGroup(&config, "Leave it empty to log in to Jira Cloud with gg login instead."),
 	)
-	err := form.Run()
-	if err != nil {
-		log.Fatal(err)
+	if err := run(form); err != nil {
+		return nil, err
 	}
+	return &config, nil
+}
+
+// EditConfig asks for the basic config again, starting from what it is.
+func (g *Gui) EditConfig(config *cfg.Config) error {
+	return run(huh.NewForm(configGroup(config, "Leave it empty to keep the one you have.")))
+}
 
-	return &cfg
+func configGroup(config *cfg.Config, tokenHint string) *huh.Group {
+	return huh.NewGroup(
+		huh.NewInput().
+			Title("Jira Username").
+			Placeholder("someone@example.com").
+			Validate(validateString("username cannot be empty")).
+			Value(&config.JiraUser),
+		huh.NewInput().
+			Title("JIRA API Token").
+			Description("Generate one at https://id.atlassian.com/manage-profile/security/api-tokens,\n"+
+				"on Jira Server or Data Center use a personal access token.\n"+tokenHint).
+			Placeholder("..hamana...").
+			EchoMode(huh.EchoModePassword).
+			Value(&config.JiraToken),
+		huh.NewInput().
+			Title("Jira URL").
+			Placeholder("https://example.atlassian.net").
+			Validate(validateString("URL cannot be empty")).
+			Value(&config.JiraURL),
+		huh.NewInput().
+			Title("JIRA projct key").
+			Placeholder("PROJ").
+			Validate(validateString("project key cannot be empty")).
+			Value(&config.JiraProject),
+	)
 }
 
 func validateString(message string) func(s string) error {
@@ -66,20 +87,35 @@ func validateString(message string) func(s string) error {
 	}
 }
 
-func (g *Gui) AskForIssueDetails(preTitle, preDesc string, typesFn func() map[string]string) (string, string, string, string) {
+// AskForIssueDetails fills in the draft, the parent and links are optional
+// unless it's a sub-task. The fields of the picked type are asked for last.
+func (g *Gui) AskForIssueDetails(draft *cfg.IssueDraft, typesFn func() (map[string]string, error),
+	fieldsFn func(typeID string) ([]cfg.IssueField, error)) error {
 	typeOptions := []huh.Option[string]{}
 	var types map[string]string
+	var typesErr error
 	_ = spinner.New().Title("Asking JIRA for issue types...").Action(
 		func() {
-			types = typesFn()
+			types, typesErr = typesFn()
 			for k, v := range types {
 				typeOptions = append(typeOptions, huh.NewOption(k, v))
 			}
 		},
 	).Run()
-	var issueTypeID string
-	title := preTitle
-	description := preDesc
+	if typesErr != nil {
+		return typesErr
+	}
+	title := draft.Title
+	description := draft.Description
+	parentTitle := "Epic"
+	if draft.Subtask {
+		parentTitle = "Parent issue"
+	}
+	var links []string
+	for _, link := range draft.Links {
+		links = append(links, link.String())
+	}
+	linksText := strings.Join(links, ", ")
 	form := huh.NewForm(
 		huh.NewGroup(
 			huh.NewNote().
@@ -98,117 +134,284 @@ func (g *Gui) AskForIssueDetails(preTitle, preDesc string, typesFn func() map[st
 			huh.NewSelect[string]().
 				Title("Issue type").
 				Options(typeOptions...).
-				Value(&issueTypeID),
+				Value(&draft.TypeID),
+		),
+		huh.NewGroup(
+			huh.NewInput().
+				Title(parentTitle).
+				Inline(true).
+				Placeholder("PROJ-123").
+				Value(&draft.Parent).
+				Validate(func(s string) error {
+					if draft.Subtask && s == "" {
+						return errors.New("a sub-task needs a parent")
+					}
+					return nil
+				}),
+			huh.NewInput().
+				Title("Links").
+				Description(`Like "blocks PROJ-12, relates to PROJ-3"`).
+				Value(&linksText).
+				Validate(func(s string) error {
+					_, err := cfg.ParseIssueLinks(s)
+					return err
+				}),
 		),
 	)
-	err := form.Run()
-	if err != nil {
-		log.Fatal(err)
+	if err := run(form); err != nil {
+		return err
+	}
+	for name, id := range types {
+		if id == draft.TypeID {
+			draft.Type = name
+		}
+	}
+	draft.Title, draft.Description = title, description
+	draft.Parent = strings.ToUpper(strings.TrimSpace(draft.Parent))
+	var err error
+	if draft.Links, err = cfg.ParseIssueLinks(linksText); err != nil {
+		return err
 	}
 
-	return issueTypeID, types[issueTypeID], title, description
+	var fields []cfg.IssueField
+	var fieldsErr error
+	_ = spinner.New().Title("Asking JIRA for fields...").Action(
+		func() {
+			fields, fieldsErr = fieldsFn(draft.TypeID)
+		},
+	).Run()
+	if fieldsErr != nil {
+		return fieldsErr
+	}
+	if err := g.askForFields(fields); err != nil {
+		return err
+	}
+	draft.Fields = fields
+	return nil
 }
 
-func (g *Gui) SelectTask(fetch func(bool) []cfg.Task) *cfg.Task {
-	var task cfg.Task
+// askForFields shows an input for each field, or a list to pick from when
+// Jira has the values.
+func (g *Gui) askForFields(fields []cfg.IssueField) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	inputs := []huh.Field{huh.NewNote().Title("More fields")}
+	selections := map[int]*[]string{}
+	for i := range fields {
+		field := &fields[i]
+		title := field.Name
+		if field.Required {
+			title += " *"
+		}
+		switch {
+		case len(field.Allowed) > 0 && field.Multiple():
+			selected := field.Values()
+			selections[i] = &selected
+			inputs = append(inputs, huh.NewMultiSelect[string]().
+				Title(title).
+				Options(huh.NewOptions(field.Allowed...)...).
+				Value(&selected).
+				Validate(func(s []string) error {
+					if field.Required && len(s) == 0 {
+						return errors.New(field.Name + " cannot be empty")
+					}
+					return nil
+				}))
+		case len(field.Allowed) > 0:
+			options := huh.NewOptions(field.Allowed...)
+			if !field.Required {
+				options = append([]huh.Option[string]{huh.NewOption("-", "")}, options...)
+			}
+			inputs = append(inputs, huh.NewSelect[string]().
+				Title(title).
+				Options(options...).
+				Value(&field.Value))
+		default:
+			input := huh.NewInput().
+				Title(title).
+				Inline(true).
+				Value(&field.Value)
+			if field.Required {
+				input.Validate(validateString(field.Name + " cannot be empty"))
+			}
+			inputs = append(inputs, input)
+		}
+	}
+	if err := run(huh.NewForm(huh.NewGroup(inputs...))); err != nil {
+		return err
+	}
+	for i, selected := range selections {
+		fields[i].Value = strings.Join(*selected, ", ")
+	}
+	return nil
+}
 
-	reloadDummyTask := cfg.Task{
-		Type: "dummy-all",
+// SelectTask lists the issues of the first query, the others can be switched
+// to from the end of the list. Picking from lists, it has no use for lookup.
+func (g *Gui) SelectTask(queries []string, fetch func(query string) ([]cfg.Task, error),
+	_ func(issueID string) (*cfg.Task, error)) (*cfg.Task, error) {
+	if len(queries) == 0 {
+		return nil, errors.New("no query to look for issues with")
 	}
+	var task cfg.Task
+	// the form can't fail on loading options, so we remember what went wrong
+	var fetchErr error
+
+	// picking one of these switches the query, its name is in IssueID
+	const switchQuery = "dummy-query"
 
-	loadIssuesWithSpinner := func(mine bool) []huh.Option[cfg.Task] {
+	loadIssuesWithSpinner := func(query string) []huh.Option[cfg.Task] {
 		var options []huh.Option[cfg.Task]
 		_ = spinner.New().Title("Asking JIRA for issues...").Action(
 			func() {
-				tasks := fetch(mine)
+				tasks, err := fetch(query)
+				if err != nil {
+					fetchErr = err
+					return
+				}
 				for _, t := range tasks {
 					options = append(options, huh.NewOption(t.IssueID+" "+t.Title, t))
 				}
-				assigned := "unassigned"
-				if !mine {
-					assigned = "my issues"
+				for _, other := range queries {
+					if other != query {
+						options = append(options, huh.NewOption(
+							fmt.Sprintf("...fetch %s", other),
+							cfg.Task{IssueID: other, Type: switchQuery}))
+					}
 				}
-				options = append(options,
-
-					huh.NewOption(
-						fmt.Sprintf("...fetch %s", assigned),
-						reloadDummyTask),
-				)
 			},
 		).Run()
 		return options
 	}
 
-	toggleMine := true
+	query := queries[0]
 	form := huh.NewForm(
 		huh.NewGroup(
 			huh.NewSelect[cfg.Task]().
 				Title("Choose a task to start working on").
 				Filtering(true).
-				OptionsFunc(func() []huh.Option[cfg.Task] { return loadIssuesWithSpinner(toggleMine) }, &toggleMine).
-				// Options(options...).
+				OptionsFunc(func() []huh.Option[cfg.Task] { return loadIssuesWithSpinner(query) }, &query).
 				Value(&task).Validate(func(t cfg.Task) error {
 				// hackisk way to trigger reload
-				if t.Type == "dummy-all" {
-					toggleMine = !toggleMine
+				if t.Type == switchQuery {
+					query = t.IssueID
 					return errors.New("loading more")
 				}
+				if fetchErr != nil {
+					return fetchErr
+				}
 				return nil
 			}),
 		),
 	)
-	err := form.Run()
+	err := run(form)
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	return &task
+	return &task, nil
 }
 
-func (g *Gui) AskForPRTitle(task *cfg.Task) string {
+func (g *Gui) AskForPRTitle(task *cfg.Task, bases, prefixes []string, defaults cfg.PRTitleOptions,
+	formatTitle func(cfg.PRTitleOptions) string) (string, string, error) {
 	if task == nil {
-		log.Fatal("task cannot be nil")
+		return "", "", errors.New("task cannot be nil")
+	}
+	if len(bases) == 0 {
+		return "", "", errors.New("no base branch to open the PR against")
 	}
 
-	prTitle := fmt.Sprintf("%s: %s", task.IssueID, task.Title)
-	prefix := "-"
-	form := huh.NewForm(
-		huh.NewGroup(
-			huh.NewNote().
-				Title("Create PR").Description("Select the style of PR you want."),
-			huh.NewSelect[string]().
-				Title("PR title prefix").
-				Height(6).
-				Options(huh.NewOptions("-", "feat", "fix", "chore")...).
-				Value(&prefix),
-			huh.NewNote().TitleFunc(func() string {
-				switch prefix {
-				case "chore":
-					prTitle = fmt.Sprintf("chore(%s): %s", task.IssueID, task.Title)
-				case "feat":
-					prTitle = fmt.Sprintf("feat(%s): %s", task.IssueID, task.Title)
-				case "fix":
-					prTitle = fmt.Sprintf("fix(%s): %s", task.IssueID, task.Title)
-				default:
-					prTitle = fmt.Sprintf("%s: %s", task.IssueID, task.Title)
-				}
-				return prTitle
-			}, &prefix),
-		),
+	const noPrefix = "-"
+	options := defaults
+	if options.Prefix == "" {
+		options.Prefix = noPrefix
+	}
+	titleOptions := func() cfg.PRTitleOptions {
+		o := options
+		if o.Prefix == noPrefix {
+			o.Prefix = ""
+		}
+		return o
+	}
+
+	base := bases[0]
+	fields := []huh.Field{
+		huh.NewNote().
+			Title("Create PR").Description("Select the style of PR you want."),
+		huh.NewSelect[string]().
+			Title("PR title prefix").
+			Height(8).
+			Options(huh.NewOptions(append([]string{noPrefix}, prefixes...)...)...).
+			Value(&options.Prefix),
+		huh.NewInput().
+			Title("Scope").
+			Inline(true).
+			Placeholder("optional").
+			Value(&options.Scope),
+		huh.NewConfirm().
+			Title("Breaking change?").
+			Inline(true).
+			Value(&options.Breaking),
+	}
+	if len(bases) > 1 {
+		fields = append(fields, huh.NewSelect[string]().
+			Title("Base branch").
+			Options(huh.NewOptions(bases...)...).
+			Value(&base))
+	}
+	fields = append(fields,
+		huh.NewNote().TitleFunc(func() string {
+			return formatTitle(titleOptions())
+		}, &options),
 	)
+	form := huh.NewForm(huh.NewGroup(fields...))
 
-	err := form.Run()
-	if err != nil {
-		log.Fatal(err)
+	if err := run(form); err != nil {
+		return "", "", err
 	}
-	return prTitle
+	return formatTitle(titleOptions()), base, nil
 }
 
-func (g *Gui) ShowSummary(issueID, title, url string) {
+// AskForPRBody shows the generated PR description for editing, ctrl+e opens
+// it in $EDITOR.
+func (g *Gui) AskForPRBody(body string) (string, error) {
+	form := huh.NewForm(huh.NewGroup(
+		huh.NewText().
+			Title("PR description").
+			Description("Edit the description, ctrl+e opens your editor.").
+			Lines(15).
+			CharLimit(0).
+			EditorExtension("md").
+			Value(&body),
+	))
+
+	if err := run(form); err != nil {
+		return "", err
+	}
+	return body, nil
+}
+
+func (g *Gui) ShowSummary(result *cfg.Result) error {
+	switch result.Command {
+	case "new", "issue":
+		log.Printf("Working on %s in %s\n", result.Issue, result.Branch)
+		return nil
+	case "done":
+		log.Printf("Done with %s, back on %s\n", result.Issue, result.Base)
+		return nil
+	}
+
 	keyValue := func(k, v string) string {
 		return lipgloss.NewStyle().Foreground(lipgloss.Color("grey")).Render(k) +
 			":\t" +
 			lipgloss.NewStyle().Foreground(lipgloss.Color("212")).Render(v)
 	}
+	link := func(url string) string {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("212")).Underline(true).Render(url)
+	}
 	var sb strings.Builder
 	fmt.Fprintf(&sb,
 		`%s
@@ -217,10 +420,13 @@ func (g *Gui) ShowSummary(issueID, title, url string) {
 %s
 %s`,
 		lipgloss.NewStyle().Bold(true).Render("PR Summary"),
-		keyValue("Issue", issueID),
-		keyValue("Title", title),
-		lipgloss.NewStyle().Foreground(lipgloss.Color("212")).Underline(true).Render(url),
+		keyValue("Issue", result.Issue),
+		keyValue("Title", result.Title),
+		link(result.IssueURL),
 	)
+	if result.PRURL != "" {
+		fmt.Fprintf(&sb, "\n%s", link(result.PRURL))
+	}
 
 	log.Println(
 		lipgloss.NewStyle().
@@ -229,4 +435,99 @@ func (g *Gui) ShowSummary(issueID, title, url string) {
 			Padding(1, 2).
 			Render(sb.String()),
 	)
+	return nil
+}
+
+func (g *Gui) ShowSettings(settings []cfg.Setting) error {
+	for _, s := range settings {
+		value := s.Value
+		if value == "" {
+			value = "-"
+		}
+		log.Printf("%s = %s %s\n",
+			lipgloss.NewStyle().Foreground(lipgloss.Color("grey")).Render(s.Key),
+			lipgloss.NewStyle().Foreground(lipgloss.Color("212")).Render(value),
+			lipgloss.NewStyle().Faint(true).Render("("+s.Source+")"),
+		)
+	}
+	return nil
+}
+
+// ShowSetting prints just the value, for scripts.
+func (g *Gui) ShowSetting(setting cfg.Setting) error {
+	_, err := fmt.Fprintln(os.Stdout, setting.Value)
+	return err
+}
+
+func (g *Gui) ShowSprint(sprint *cfg.Sprint) error {
+	var sb strings.Builder
+	sb.WriteString(lipgloss.NewStyle().Bold(true).Render(sprint.Name))
+	if sprint.Goal != "" {
+		sb.WriteString("\n" + lipgloss.NewStyle().Faint(true).Render(sprint.Goal))
+	}
+	for _, group := range groupByStatus(sprint.Issues) {
+		fmt.Fprintf(&sb, "\n\n%s", lipgloss.NewStyle().Foreground(lipgloss.Color("63")).Bold(true).Render(group.Status))
+		for _, issue := range group.Issues {
+			fmt.Fprintf(&sb, "\n%s %s %s",
+				lipgloss.NewStyle().Foreground(lipgloss.Color("212")).Render(issue.Key),
+				issue.Title,
+				lipgloss.NewStyle().Faint(true).Render("("+assignee(issue)+")"),
+			)
+		}
+	}
+	log.Println(sb.String())
+	return nil
+}
+
+// AskForProfile asks for the Jira account of a profile, starting from what
+// the profile already has. An empty token keeps the one stored.
+func (g *Gui) AskForProfile(name string, profile *cfg.Profile) error {
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewNote().Title("Profile "+name),
+			huh.NewInput().
+				Title("Jira URL").
+				Placeholder("https://example.atlassian.net").
+				Validate(validateString("URL cannot be empty")).
+				Value(&profile.JiraURL),
+			huh.NewInput().
+				Title("Jira Username").
+				Placeholder("someone@example.com").
+				Validate(validateString("username cannot be empty")).
+				Value(&profile.JiraUser),
+			huh.NewInput().
+				Title("JIRA API Token").
+				Description("Leave it empty to keep the current one, or to use gg login.").
+				EchoMode(huh.EchoModePassword).
+				Value(&profile.JiraToken),
+			huh.NewInput().
+				Title("JIRA project key").
+				Description("Leave it empty to use the one from ~/.gg.").
+				Placeholder("PROJ").
+				Value(&profile.JiraProject),
+		),
+	)
+	return run(form)
+}
+
+func (g *Gui) ShowProfiles(profiles []cfg.ProfileInfo) error {
+	for _, p := range profiles {
+		marker := "  "
+		if p.Active {
+			marker = "* "
+		}
+		name := lipgloss.NewStyle().Foreground(lipgloss.Color("212")).Bold(p.Active).Render(marker + p.Name)
+		var notes []string
+		if p.Default {
+			notes = append(notes, "default")
+		}
+		notes = append(notes, p.Remotes...)
+		notes = append(notes, p.Dirs...)
+		line := fmt.Sprintf("%s %s %s", name, p.JiraURL, p.JiraUser)
+		if len(notes) > 0 {
+			line += " " + lipgloss.NewStyle().Faint(true).Render("("+strings.Join(notes, ", ")+")")
+		}
+		log.Println(line)
+	}
+	return nil
 }
@@ -0,0 +1,49 @@
+package gui
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/bricktopab/gg/cfg"
+)
+
+// statusGroup is the issues of a sprint in one status.
+type statusGroup struct {
+	Status string
+	Issues []cfg.SprintIssue
+}
+
+// categoryOrder is the order Jira boards show status categories in.
+var categoryOrder = []string{"To Do", "In Progress", "Done"}
+
+func categoryRank(category string) int {
+	if i := slices.Index(categoryOrder, category); i >= 0 {
+		return i
+	}
+	return len(categoryOrder)
+}
+
+// groupByStatus groups the issues by status, ordered like the columns of a
+// board. Issues keep their rank within a status.
+func groupByStatus(issues []cfg.SprintIssue) []statusGroup {
+	var groups []statusGroup
+	for _, issue := range issues {
+		i := slices.IndexFunc(groups, func(g statusGroup) bool { return g.Status == issue.Status })
+		if i < 0 {
+			groups = append(groups, statusGroup{Status: issue.Status})
+			i = len(groups) - 1
+		}
+		groups[i].Issues = append(groups[i].Issues, issue)
+	}
+	slices.SortStableFunc(groups, func(a, b statusGroup) int {
+		return cmp.Compare(categoryRank(a.Issues[0].StatusCategory), categoryRank(b.Issues[0].StatusCategory))
+	})
+	return groups
+}
+
+func assignee(issue cfg.SprintIssue) string {
+	if issue.Assignee == "" {
+		return "unassigned"
+	}
+	return issue.Assignee
+}
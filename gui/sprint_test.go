@@ -0,0 +1,35 @@
+package gui
+
+import (
+	"testing"
+
+	"github.com/bricktopab/gg/cfg"
+)
+
+func TestGroupByStatus(t *testing.T) {
+	issues := []cfg.SprintIssue{
+		{Key: "ABC-1", Status: "Done", StatusCategory: "Done"},
+		{Key: "ABC-2", Status: "In Review", StatusCategory: "In Progress"},
+		{Key: "ABC-3", Status: "Open", StatusCategory: "To Do"},
+		{Key: "ABC-4", Status: "In Progress", StatusCategory: "In Progress"},
+		{Key: "ABC-5", Status: "In Review", StatusCategory: "In Progress"},
+	}
+
+	groups := groupByStatus(issues)
+	var got []string
+	for _, g := range groups {
+		got = append(got, g.Status)
+	}
+	want := []string{"Open", "In Review", "In Progress", "Done"}
+	if len(got) != len(want) {
+		t.Fatalf("groupByStatus() statuses = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("groupByStatus() statuses = %v, want %v", got, want)
+		}
+	}
+	if review := groups[1].Issues; len(review) != 2 || review[0].Key != "ABC-2" || review[1].Key != "ABC-5" {
+		t.Errorf("groupByStatus() In Review = %+v", review)
+	}
+}
@@ -0,0 +1,66 @@
+package gui
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bricktopab/gg/cfg"
+	"gopkg.in/yaml.v2"
+)
+
+// OutputFormats are the formats Structured can print, besides the default
+// text meant for humans.
+var OutputFormats = []string{"json", "yaml"}
+
+// Structured prints results as JSON or YAML on stdout for scripts and
+// editors. Prompts and logs stay on stderr.
+type Structured struct {
+	*Unattended
+	Format string
+	Out    io.Writer
+}
+
+func (s *Structured) print(v any) error {
+	out := s.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	var err error
+	switch s.Format {
+	case "yaml":
+		var b []byte
+		if b, err = yaml.Marshal(v); err == nil {
+			_, err = out.Write(b)
+		}
+	default:
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(v)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s output: %w", s.Format, err)
+	}
+	return nil
+}
+
+func (s *Structured) ShowSummary(result *cfg.Result) error {
+	return s.print(result)
+}
+
+func (s *Structured) ShowSettings(settings []cfg.Setting) error {
+	return s.print(settings)
+}
+
+func (s *Structured) ShowSetting(setting cfg.Setting) error {
+	return s.print(setting)
+}
+
+func (s *Structured) ShowSprint(sprint *cfg.Sprint) error {
+	return s.print(sprint)
+}
+
+func (s *Structured) ShowProfiles(profiles []cfg.ProfileInfo) error {
+	return s.print(profiles)
+}
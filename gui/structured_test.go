@@ -0,0 +1,50 @@
+package gui
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bricktopab/gg/cfg"
+)
+
+func TestStructuredSummary(t *testing.T) {
+	result := &cfg.Result{
+		Command:     "pr",
+		Issue:       "ABC-1",
+		Branch:      "ABC-1_Things",
+		PRNumber:    42,
+		Transitions: []string{"In Review"},
+	}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"json", `{
+  "command": "pr",
+  "issue": "ABC-1",
+  "branch": "ABC-1_Things",
+  "pr_number": 42,
+  "transitions": [
+    "In Review"
+  ]
+}
+`},
+		{"yaml", `command: pr
+issue: ABC-1
+branch: ABC-1_Things
+pr_number: 42
+transitions:
+- In Review
+`},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		s := &Structured{Format: tt.format, Out: &out}
+		if err := s.ShowSummary(result); err != nil {
+			t.Fatalf("ShowSummary() failed: %v", err)
+		}
+		if out.String() != tt.want {
+			t.Errorf("ShowSummary() as %s = %s, want %s", tt.format, out.String(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/bricktopab/gg/cfg"
+)
+
+// Answers are given up front with flags instead of in forms.
+type Answers struct {
+	Type        string
+	Title       string
+	Description string
+	Issue       string
+	Prefix      string
+	Base        string
+	// Yes takes the defaults for anything not answered
+	Yes bool
+}
+
+// ErrMissingInput is returned for questions left unanswered with --no-input.
+var ErrMissingInput = errors.New("prompting is disabled by --no-input")
+
+// Unattended answers from flags and only falls back on the interactive Gui
+// for what's missing. Without a fallback, missing answers are an error, which
+// is what --no-input is for.
+type Unattended struct {
+	Answers  Answers
+	Fallback *Gui
+}
+
+func missing(flag, what string) error {
+	return fmt.Errorf("missing %s, pass it with --%s (%w)", what, flag, ErrMissingInput)
+}
+
+func (u *Unattended) AskForConfig() (*cfg.Config, error) {
+	if u.Fallback != nil {
+		return u.Fallback.AskForConfig()
+	}
+	return nil, fmt.Errorf("no config found in ~/.gg, run gg once without --no-input to set it up (%w)", ErrMissingInput)
+}
+
+func (u *Unattended) EditConfig(config *cfg.Config) error {
+	if u.Fallback != nil {
+		return u.Fallback.EditConfig(config)
+	}
+	return fmt.Errorf("gg config init asks for the config, use gg config set instead (%w)", ErrMissingInput)
+}
+
+func (u *Unattended) AskForIssueDetails(draft *cfg.IssueDraft, typesFn func() (map[string]string, error),
+	fieldsFn func(typeID string) ([]cfg.IssueField, error)) error {
+	if u.Answers.Title != "" {
+		draft.Title = u.Answers.Title
+	}
+	if u.Answers.Description != "" {
+		draft.Description = u.Answers.Description
+	}
+	if draft.Title == "" || u.Answers.Type == "" {
+		if u.Fallback != nil {
+			return u.Fallback.AskForIssueDetails(draft, typesFn, fieldsFn)
+		}
+		if draft.Title == "" {
+			return missing("title", "issue title")
+		}
+		return missing("type", "issue type")
+	}
+
+	types, err := typesFn()
+	if err != nil {
+		return err
+	}
+	for name, id := range types {
+		if strings.EqualFold(name, u.Answers.Type) || id == u.Answers.Type {
+			draft.TypeID, draft.Type = id, name
+		}
+	}
+	if draft.TypeID == "" {
+		return fmt.Errorf("unknown issue type %q, pick one of: %s",
+			u.Answers.Type, strings.Join(sortedNames(types), ", "))
+	}
+
+	fields, err := fieldsFn(draft.TypeID)
+	if err != nil {
+		return err
+	}
+	var unanswered []string
+	for _, field := range fields {
+		if field.Required && field.Value == "" {
+			unanswered = append(unanswered, field.Name)
+		}
+	}
+	if len(unanswered) > 0 {
+		if u.Fallback == nil {
+			return missing("field", strings.Join(unanswered, ", ")+" field")
+		}
+		if err := u.Fallback.askForFields(fields); err != nil {
+			return err
+		}
+	}
+	draft.Fields = fields
+	return nil
+}
+
+func sortedNames(types map[string]string) []string {
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// SelectTask picks the issue given with --issue from the lists of the queries,
+// or looks it up when it's on none of them, like an issue of someone else.
+func (u *Unattended) SelectTask(queries []string, fetch func(query string) ([]cfg.Task, error),
+	lookup func(issueID string) (*cfg.Task, error)) (*cfg.Task, error) {
+	if u.Answers.Issue == "" {
+		if u.Fallback != nil {
+			return u.Fallback.SelectTask(queries, fetch, lookup)
+		}
+		return nil, missing("issue", "issue key")
+	}
+
+	for _, query := range queries {
+		tasks, err := fetch(query)
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range tasks {
+			if strings.EqualFold(task.IssueID, u.Answers.Issue) {
+				return &task, nil
+			}
+		}
+	}
+	return lookup(strings.ToUpper(u.Answers.Issue))
+}
+
+func (u *Unattended) AskForPRTitle(task *cfg.Task, bases, prefixes []string, defaults cfg.PRTitleOptions,
+	formatTitle func(cfg.PRTitleOptions) string) (string, string, error) {
+	options := defaults
+	answered := u.Answers.Prefix != ""
+	if answered {
+		options.Prefix = u.Answers.Prefix
+		if options.Prefix == "-" || options.Prefix == "none" {
+			options.Prefix = ""
+		} else if !slices.Contains(prefixes, options.Prefix) {
+			return "", "", fmt.Errorf("unknown PR title prefix %q, pick one of: %s", options.Prefix, strings.Join(prefixes, ", "))
+		}
+	}
+
+	if !answered && !u.Answers.Yes && u.Fallback != nil {
+		if u.Answers.Base != "" {
+			bases = []string{u.Answers.Base}
+		}
+		return u.Fallback.AskForPRTitle(task, bases, prefixes, defaults, formatTitle)
+	}
+
+	if len(bases) == 0 && u.Answers.Base == "" {
+		return "", "", missing("base", "base branch")
+	}
+	base := u.Answers.Base
+	if base == "" {
+		base = bases[0]
+	}
+	return formatTitle(options), base, nil
+}
+
+func (u *Unattended) AskForPRBody(body string) (string, error) {
+	if !u.Answers.Yes && u.Fallback != nil {
+		return u.Fallback.AskForPRBody(body)
+	}
+	return body, nil
+}
+
+func (u *Unattended) ShowSummary(result *cfg.Result) error {
+	if u.Fallback != nil {
+		return u.Fallback.ShowSummary(result)
+	}
+	// plain lines for logs
+	log.Printf("Issue: %s\nTitle: %s\n%s\n", result.Issue, result.Title, result.IssueURL)
+	if result.PRURL != "" {
+		log.Println(result.PRURL)
+	}
+	return nil
+}
+
+func (u *Unattended) ShowSettings(settings []cfg.Setting) error {
+	if u.Fallback != nil {
+		return u.Fallback.ShowSettings(settings)
+	}
+	for _, s := range settings {
+		log.Printf("%s = %s (%s)\n", s.Key, s.Value, s.Source)
+	}
+	return nil
+}
+
+func (u *Unattended) ShowSetting(setting cfg.Setting) error {
+	_, err := fmt.Fprintln(os.Stdout, setting.Value)
+	return err
+}
+
+func (u *Unattended) ShowSprint(sprint *cfg.Sprint) error {
+	if u.Fallback != nil {
+		return u.Fallback.ShowSprint(sprint)
+	}
+	log.Println(sprint.Name)
+	for _, group := range groupByStatus(sprint.Issues) {
+		log.Printf("%s:\n", group.Status)
+		for _, issue := range group.Issues {
+			log.Printf("  %s %s (%s)\n", issue.Key, issue.Title, assignee(issue))
+		}
+	}
+	return nil
+}
+
+func (u *Unattended) AskForProfile(name string, profile *cfg.Profile) error {
+	if profile.JiraURL != "" && profile.JiraUser != "" {
+		return nil
+	}
+	if u.Fallback != nil {
+		return u.Fallback.AskForProfile(name, profile)
+	}
+	if profile.JiraURL == "" {
+		return missing("url", "Jira URL")
+	}
+	return missing("user", "Jira username")
+}
+
+func (u *Unattended) ShowProfiles(profiles []cfg.ProfileInfo) error {
+	if u.Fallback != nil {
+		return u.Fallback.ShowProfiles(profiles)
+	}
+	for _, p := range profiles {
+		marker := " "
+		if p.Active {
+			marker = "*"
+		}
+		log.Printf("%s %s %s %s\n", marker, p.Name, p.JiraURL, p.JiraUser)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package gui
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bricktopab/gg/cfg"
+)
+
+func TestUnattendedIssueDetails(t *testing.T) {
+	u := &Unattended{Answers: Answers{Type: "story", Description: "From flags"}}
+	types := func() (map[string]string, error) { return map[string]string{"Story": "10001", "Bug": "10002"}, nil }
+	fields := func(string) ([]cfg.IssueField, error) {
+		return []cfg.IssueField{{ID: "priority", Name: "Priority", Value: "High"}}, nil
+	}
+
+	draft := &cfg.IssueDraft{Title: "From args"}
+	if err := u.AskForIssueDetails(draft, types, fields); err != nil {
+		t.Fatalf("AskForIssueDetails() failed: %v", err)
+	}
+	if draft.TypeID != "10001" || draft.Type != "Story" || draft.Title != "From args" || draft.Description != "From flags" ||
+		len(draft.Fields) != 1 {
+		t.Errorf("AskForIssueDetails() = %+v", draft)
+	}
+
+	u.Answers.Type = ""
+	if err := u.AskForIssueDetails(&cfg.IssueDraft{Title: "From args"}, types, fields); !errors.Is(err, ErrMissingInput) {
+		t.Errorf("AskForIssueDetails() without a type = %v, want ErrMissingInput", err)
+	}
+
+	u.Answers.Type = "bug"
+	required := func(string) ([]cfg.IssueField, error) {
+		return []cfg.IssueField{{ID: "components", Name: "Components", Required: true}}, nil
+	}
+	err := u.AskForIssueDetails(&cfg.IssueDraft{Title: "From args"}, types, required)
+	if !errors.Is(err, ErrMissingInput) || !strings.Contains(err.Error(), "Components") {
+		t.Errorf("AskForIssueDetails() without a required field = %v, want ErrMissingInput", err)
+	}
+}
+
+func TestUnattendedSelectTask(t *testing.T) {
+	u := &Unattended{Answers: Answers{Issue: "abc-2"}}
+	fetch := func(query string) ([]cfg.Task, error) {
+		if query == "mine" {
+			return []cfg.Task{{IssueID: "ABC-1"}}, nil
+		}
+		return []cfg.Task{{IssueID: "ABC-2", Title: "Unassigned"}}, nil
+	}
+
+	var looked []string
+	lookup := func(issueID string) (*cfg.Task, error) {
+		looked = append(looked, issueID)
+		return &cfg.Task{IssueID: issueID, Title: "Someone else's"}, nil
+	}
+
+	task, err := u.SelectTask([]string{"mine", "unassigned"}, fetch, lookup)
+	if err != nil || task.IssueID != "ABC-2" || task.Title != "Unassigned" || len(looked) > 0 {
+		t.Errorf("SelectTask() = %+v, %v, looked up %v", task, err, looked)
+	}
+
+	// on none of the lists
+	u.Answers.Issue = "abc-9"
+	task, err = u.SelectTask([]string{"mine", "unassigned"}, fetch, lookup)
+	if err != nil || task.IssueID != "ABC-9" || task.Title != "Someone else's" {
+		t.Errorf("SelectTask() of an issue on no list = %+v, %v", task, err)
+	}
+}
+
+func TestUnattendedPRTitle(t *testing.T) {
+	task := &cfg.Task{IssueID: "ABC-1", Title: "Things"}
+	format := func(o cfg.PRTitleOptions) string { return o.Prefix + ": " + task.Title }
+	prefixes := []string{"feat", "fix"}
+	defaults := cfg.PRTitleOptions{Prefix: "feat"}
+
+	tests := []struct {
+		answers   Answers
+		wantTitle string
+		wantBase  string
+	}{
+		{Answers{Yes: true}, "feat: Things", "develop"},
+		{Answers{Prefix: "fix", Base: "main"}, "fix: Things", "main"},
+		{Answers{Prefix: "-"}, ": Things", "develop"},
+	}
+	for _, tt := range tests {
+		u := &Unattended{Answers: tt.answers}
+		title, base, err := u.AskForPRTitle(task, []string{"develop", "main"}, prefixes, defaults, format)
+		if err != nil || title != tt.wantTitle || base != tt.wantBase {
+			t.Errorf("AskForPRTitle(%+v) = %q, %q, %v, want %q, %q", tt.answers, title, base, err, tt.wantTitle, tt.wantBase)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package hosting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// apiClient does the JSON plumbing shared by the providers' REST APIs.
+type apiClient struct {
+	name        string
+	baseURL     string
+	client      *http.Client
+	authorize   func(*http.Request)
+	decodeError func(status string, body []byte) error
+}
+
+func newAPIClient(name, baseURL string, authorize func(*http.Request), decodeError func(string, []byte) error) apiClient {
+	return apiClient{
+		name:        name,
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		client:      http.DefaultClient,
+		authorize:   authorize,
+		decodeError: decodeError,
+	}
+}
+
+func (a *apiClient) do(ctx context.Context, method, path string, payload any, wantStatus int, out any) error {
+	var body io.Reader
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, a.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	a.authorize(req)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call %s: %w", a.name, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != wantStatus {
+		respBody, _ := io.ReadAll(resp.Body)
+		return a.decodeError(resp.Status, respBody)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", a.name, err)
+	}
+	return nil
+}
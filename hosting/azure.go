@@ -0,0 +1,101 @@
+package hosting
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	azureHost       = "dev.azure.com"
+	azureAPIVersion = "7.1"
+)
+
+// Azure is Azure Repos, where Repo.Owner is "organization/project".
+type Azure struct {
+	api apiClient
+}
+
+func NewAzure(apiURL, token string) *Azure {
+	authorize := func(req *http.Request) {
+		// personal access tokens go in the password with an empty user
+		req.SetBasicAuth("", token)
+	}
+	return &Azure{api: newAPIClient("Azure DevOps", apiURL, authorize, decodeAzureError)}
+}
+
+func AzureAPIURL(host string) string {
+	return "https://" + host
+}
+
+func decodeAzureError(status string, body []byte) error {
+	var apiErr struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &apiErr) != nil || apiErr.Message == "" {
+		return fmt.Errorf("Azure DevOps Error: %s", status)
+	}
+	return fmt.Errorf("Azure DevOps Error: %s", apiErr.Message)
+}
+
+type azurePullRequest struct {
+	PullRequestID int    `json:"pullRequestId"`
+	TargetRefName string `json:"targetRefName"`
+	Repository    struct {
+		WebURL string `json:"webUrl"`
+	} `json:"repository"`
+}
+
+func (p *azurePullRequest) toPullRequest() *PullRequest {
+	return &PullRequest{
+		Number: p.PullRequestID,
+		URL:    fmt.Sprintf("%s/pullrequest/%d", p.Repository.WebURL, p.PullRequestID),
+		Base:   strings.TrimPrefix(p.TargetRefName, "refs/heads/"),
+	}
+}
+
+func azurePullRequestsPath(repo *Repo, query url.Values) string {
+	query.Set("api-version", azureAPIVersion)
+	return fmt.Sprintf("/%s/_apis/git/repositories/%s/pullrequests?%s", repo.Owner, repo.Name, query.Encode())
+}
+
+func (a *Azure) CreatePullRequest(ctx context.Context, repo *Repo, pr NewPullRequest) (*PullRequest, error) {
+	payload := map[string]string{
+		"title":         pr.Title,
+		"description":   pr.Body,
+		"sourceRefName": "refs/heads/" + pr.Head,
+		"targetRefName": "refs/heads/" + pr.Base,
+	}
+	var created azurePullRequest
+	path := azurePullRequestsPath(repo, url.Values{})
+	if err := a.api.do(ctx, http.MethodPost, path, payload, http.StatusCreated, &created); err != nil {
+		return nil, err
+	}
+	return created.toPullRequest(), nil
+}
+
+func (a *Azure) FindMergedPullRequest(ctx context.Context, repo *Repo, branch string) (*PullRequest, error) {
+	query := url.Values{}
+	query.Set("searchCriteria.sourceRefName", "refs/heads/"+branch)
+	query.Set("searchCriteria.status", "completed")
+	var page struct {
+		Value []azurePullRequest `json:"value"`
+	}
+	if err := a.api.do(ctx, http.MethodGet, azurePullRequestsPath(repo, query), nil, http.StatusOK, &page); err != nil {
+		return nil, err
+	}
+	if len(page.Value) == 0 {
+		return nil, ErrNoPullRequest
+	}
+	return page.Value[0].toPullRequest(), nil
+}
+
+func (a *Azure) NewPullRequestURL(repo *Repo, pr NewPullRequest) string {
+	query := url.Values{}
+	query.Set("sourceRef", pr.Head)
+	query.Set("targetRef", pr.Base)
+	return fmt.Sprintf("https://%s/%s/_git/%s/pullrequestcreate?%s", repo.Host, repo.Owner, repo.Name, query.Encode())
+}
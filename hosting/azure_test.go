@@ -0,0 +1,57 @@
+package hosting
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAzureCreatePullRequest(t *testing.T) {
+	var got map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/org/project/_apis/git/repositories/gg/pullrequests" || r.URL.Query().Get("api-version") == "" {
+			t.Errorf("Unexpected request: %s", r.URL)
+		}
+		if _, token, ok := r.BasicAuth(); !ok || token != "pat" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"pullRequestId": 12, "targetRefName": "refs/heads/main",
+			"repository": {"webUrl": "https://dev.azure.com/org/project/_git/gg"}}`))
+	}))
+	defer server.Close()
+
+	repo := &Repo{Host: "dev.azure.com", Owner: "org/project", Name: "gg"}
+	pr, err := NewAzure(server.URL, "pat").CreatePullRequest(context.Background(), repo,
+		NewPullRequest{Title: "feat(GG-1): Things", Head: "GG-1_Things", Base: "main"})
+	if err != nil {
+		t.Fatalf("CreatePullRequest failed: %v", err)
+	}
+	if pr.Number != 12 || pr.URL != "https://dev.azure.com/org/project/_git/gg/pullrequest/12" || pr.Base != "main" {
+		t.Errorf("Unexpected PR: %+v", pr)
+	}
+	if got["sourceRefName"] != "refs/heads/GG-1_Things" || got["targetRefName"] != "refs/heads/main" {
+		t.Errorf("Unexpected payload: %v", got)
+	}
+
+	if _, err := NewAzure(server.URL, "wrong").CreatePullRequest(context.Background(), repo, NewPullRequest{}); err == nil {
+		t.Error("Expected an error for a wrong token")
+	}
+}
+
+func TestAzureNewPullRequestURL(t *testing.T) {
+	repo := &Repo{Host: "dev.azure.com", Owner: "org/project", Name: "gg"}
+	got := NewAzure("", "").NewPullRequestURL(repo, NewPullRequest{Head: "GG-1_Things", Base: "main"})
+	want := "https://dev.azure.com/org/project/_git/gg/pullrequestcreate?sourceRef=GG-1_Things&targetRef=main"
+	if got != want {
+		t.Errorf("NewPullRequestURL() = %s, want %s", got, want)
+	}
+}
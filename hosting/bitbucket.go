@@ -0,0 +1,200 @@
+package hosting
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const bitbucketAPIURL = "https://api.bitbucket.org/2.0"
+
+// Bitbucket is Bitbucket Cloud, see BitbucketServer for the self-hosted one.
+type Bitbucket struct {
+	api apiClient
+}
+
+// NewBitbucket takes either an access token or "username:app-password".
+func NewBitbucket(apiURL, token string) *Bitbucket {
+	authorize := func(req *http.Request) {
+		if user, password, ok := strings.Cut(token, ":"); ok {
+			req.SetBasicAuth(user, password)
+			return
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return &Bitbucket{api: newAPIClient("Bitbucket", apiURL, authorize, decodeBitbucketError)}
+}
+
+func decodeBitbucketError(status string, body []byte) error {
+	var apiErr struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &apiErr) != nil || apiErr.Error.Message == "" {
+		return fmt.Errorf("Bitbucket Error: %s", status)
+	}
+	return fmt.Errorf("Bitbucket Error: %s", apiErr.Error.Message)
+}
+
+type bitbucketPullRequest struct {
+	ID    int `json:"id"`
+	Links struct {
+		HTML struct {
+			Href string `json:"href"`
+		} `json:"html"`
+	} `json:"links"`
+	Destination struct {
+		Branch struct {
+			Name string `json:"name"`
+		} `json:"branch"`
+	} `json:"destination"`
+}
+
+func (p *bitbucketPullRequest) toPullRequest() *PullRequest {
+	return &PullRequest{Number: p.ID, URL: p.Links.HTML.Href, Base: p.Destination.Branch.Name}
+}
+
+func bitbucketBranch(name string) map[string]any {
+	return map[string]any{"branch": map[string]string{"name": name}}
+}
+
+func (b *Bitbucket) CreatePullRequest(ctx context.Context, repo *Repo, pr NewPullRequest) (*PullRequest, error) {
+	payload := map[string]any{
+		"title":       pr.Title,
+		"description": pr.Body,
+		"source":      bitbucketBranch(pr.Head),
+		"destination": bitbucketBranch(pr.Base),
+	}
+	var created bitbucketPullRequest
+	path := fmt.Sprintf("/repositories/%s/%s/pullrequests", repo.Owner, repo.Name)
+	if err := b.api.do(ctx, http.MethodPost, path, payload, http.StatusCreated, &created); err != nil {
+		return nil, err
+	}
+	return created.toPullRequest(), nil
+}
+
+func (b *Bitbucket) FindMergedPullRequest(ctx context.Context, repo *Repo, branch string) (*PullRequest, error) {
+	query := url.Values{}
+	query.Set("state", "MERGED")
+	query.Set("q", fmt.Sprintf("source.branch.name=%q", branch))
+	var page struct {
+		Values []bitbucketPullRequest `json:"values"`
+	}
+	path := fmt.Sprintf("/repositories/%s/%s/pullrequests?%s", repo.Owner, repo.Name, query.Encode())
+	if err := b.api.do(ctx, http.MethodGet, path, nil, http.StatusOK, &page); err != nil {
+		return nil, err
+	}
+	if len(page.Values) == 0 {
+		return nil, ErrNoPullRequest
+	}
+	return page.Values[0].toPullRequest(), nil
+}
+
+// NewPullRequestURL can't prefill the title, Bitbucket only takes the branches.
+func (b *Bitbucket) NewPullRequestURL(repo *Repo, pr NewPullRequest) string {
+	query := url.Values{}
+	query.Set("source", pr.Head)
+	query.Set("dest", pr.Base)
+	return repo.WebURL() + "/pull-requests/new?" + query.Encode()
+}
+
+type BitbucketServer struct {
+	api apiClient
+}
+
+func NewBitbucketServer(apiURL, token string) *BitbucketServer {
+	authorize := func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return &BitbucketServer{api: newAPIClient("Bitbucket", apiURL, authorize, decodeBitbucketServerError)}
+}
+
+func BitbucketServerAPIURL(host string) string {
+	return "https://" + host + "/rest/api/1.0"
+}
+
+func decodeBitbucketServerError(status string, body []byte) error {
+	var apiErr struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(body, &apiErr) != nil || len(apiErr.Errors) == 0 {
+		return fmt.Errorf("Bitbucket Error: %s", status)
+	}
+	msgs := []string{}
+	for _, e := range apiErr.Errors {
+		msgs = append(msgs, e.Message)
+	}
+	return fmt.Errorf("Bitbucket Error: %s", strings.Join(msgs, ", "))
+}
+
+type bitbucketServerPullRequest struct {
+	ID    int `json:"id"`
+	ToRef struct {
+		DisplayID string `json:"displayId"`
+	} `json:"toRef"`
+	Links struct {
+		Self []struct {
+			Href string `json:"href"`
+		} `json:"self"`
+	} `json:"links"`
+}
+
+func (p *bitbucketServerPullRequest) toPullRequest() *PullRequest {
+	pr := &PullRequest{Number: p.ID, Base: p.ToRef.DisplayID}
+	if len(p.Links.Self) > 0 {
+		pr.URL = p.Links.Self[0].Href
+	}
+	return pr
+}
+
+func bitbucketServerRepoPath(repo *Repo) string {
+	return fmt.Sprintf("/projects/%s/repos/%s", repo.Owner, repo.Name)
+}
+
+func (b *BitbucketServer) CreatePullRequest(ctx context.Context, repo *Repo, pr NewPullRequest) (*PullRequest, error) {
+	payload := map[string]any{
+		"title":       pr.Title,
+		"description": pr.Body,
+		"fromRef":     map[string]string{"id": "refs/heads/" + pr.Head},
+		"toRef":       map[string]string{"id": "refs/heads/" + pr.Base},
+	}
+	var created bitbucketServerPullRequest
+	path := bitbucketServerRepoPath(repo) + "/pull-requests"
+	if err := b.api.do(ctx, http.MethodPost, path, payload, http.StatusCreated, &created); err != nil {
+		return nil, err
+	}
+	return created.toPullRequest(), nil
+}
+
+func (b *BitbucketServer) FindMergedPullRequest(ctx context.Context, repo *Repo, branch string) (*PullRequest, error) {
+	query := url.Values{}
+	query.Set("state", "MERGED")
+	query.Set("direction", "OUTGOING")
+	query.Set("at", "refs/heads/"+branch)
+	var page struct {
+		Values []bitbucketServerPullRequest `json:"values"`
+	}
+	path := bitbucketServerRepoPath(repo) + "/pull-requests?" + query.Encode()
+	if err := b.api.do(ctx, http.MethodGet, path, nil, http.StatusOK, &page); err != nil {
+		return nil, err
+	}
+	if len(page.Values) == 0 {
+		return nil, ErrNoPullRequest
+	}
+	return page.Values[0].toPullRequest(), nil
+}
+
+func (b *BitbucketServer) NewPullRequestURL(repo *Repo, pr NewPullRequest) string {
+	query := url.Values{}
+	query.Set("sourceBranch", "refs/heads/"+pr.Head)
+	query.Set("targetBranch", "refs/heads/"+pr.Base)
+	query.Set("title", pr.Title)
+	// "create" is a bare flag, url.Values would add a trailing "="
+	return fmt.Sprintf("https://%s%s/pull-requests?create&%s", repo.Host, bitbucketServerRepoPath(repo), query.Encode())
+}
@@ -0,0 +1,122 @@
+package hosting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBitbucketCreatePullRequest(t *testing.T) {
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repositories/workspace/gg/pullrequests" {
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		if user, password, ok := r.BasicAuth(); !ok || user != "someone" || password != "app-password" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"type": "error", "error": {"message": "Unauthorized"}}`))
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id": 5, "links": {"html": {"href": "https://bitbucket.org/workspace/gg/pull-requests/5"}},
+			"destination": {"branch": {"name": "main"}}}`))
+	}))
+	defer server.Close()
+
+	repo := &Repo{Host: "bitbucket.org", Owner: "workspace", Name: "gg"}
+	pr, err := NewBitbucket(server.URL, "someone:app-password").CreatePullRequest(context.Background(), repo,
+		NewPullRequest{Title: "feat(GG-1): Things", Head: "GG-1_Things", Base: "main"})
+	if err != nil {
+		t.Fatalf("CreatePullRequest failed: %v", err)
+	}
+	if pr.Number != 5 || pr.URL != "https://bitbucket.org/workspace/gg/pull-requests/5" || pr.Base != "main" {
+		t.Errorf("Unexpected PR: %+v", pr)
+	}
+	source, _ := json.Marshal(got["source"])
+	if got["title"] != "feat(GG-1): Things" || string(source) != `{"branch":{"name":"GG-1_Things"}}` {
+		t.Errorf("Unexpected payload: %v", got)
+	}
+
+	_, err = NewBitbucket(server.URL, "token").CreatePullRequest(context.Background(), repo, NewPullRequest{})
+	if err == nil || !strings.Contains(err.Error(), "Unauthorized") {
+		t.Errorf("Expected Bitbucket error details, got %v", err)
+	}
+}
+
+func TestBitbucketServerPullRequests(t *testing.T) {
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/1.0/projects/PROJ/repos/gg/pull-requests" {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("Expected bearer token, got %q", r.Header.Get("Authorization"))
+		}
+		if r.Method == http.MethodGet {
+			if r.URL.Query().Get("at") != "refs/heads/GG-1_Things" {
+				_, _ = w.Write([]byte(`{"values": []}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"values": [{"id": 8, "toRef": {"displayId": "develop"},
+				"links": {"self": [{"href": "https://bitbucket.example.com/projects/PROJ/repos/gg/pull-requests/8"}]}}]}`))
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id": 9, "toRef": {"displayId": "main"},
+			"links": {"self": [{"href": "https://bitbucket.example.com/projects/PROJ/repos/gg/pull-requests/9"}]}}`))
+	}))
+	defer server.Close()
+
+	bitbucket := NewBitbucketServer(server.URL+"/rest/api/1.0", "secret")
+	repo := &Repo{Host: "bitbucket.example.com", Owner: "PROJ", Name: "gg"}
+	pr, err := bitbucket.CreatePullRequest(context.Background(), repo, NewPullRequest{Title: "T", Head: "GG-1_Things", Base: "main"})
+	if err != nil {
+		t.Fatalf("CreatePullRequest failed: %v", err)
+	}
+	if pr.Number != 9 || pr.URL != "https://bitbucket.example.com/projects/PROJ/repos/gg/pull-requests/9" {
+		t.Errorf("Unexpected PR: %+v", pr)
+	}
+	fromRef, _ := json.Marshal(got["fromRef"])
+	if string(fromRef) != `{"id":"refs/heads/GG-1_Things"}` {
+		t.Errorf("Unexpected fromRef: %s", fromRef)
+	}
+
+	pr, err = bitbucket.FindMergedPullRequest(context.Background(), repo, "GG-1_Things")
+	if err != nil || pr.Number != 8 || pr.Base != "develop" {
+		t.Errorf("Expected merged PR 8 against develop, got %+v, %v", pr, err)
+	}
+	_, err = bitbucket.FindMergedPullRequest(context.Background(), repo, "GG-2_Unmerged")
+	if !errors.Is(err, ErrNoPullRequest) {
+		t.Errorf("Expected ErrNoPullRequest, got %v", err)
+	}
+}
+
+func TestBitbucketNewPullRequestURLs(t *testing.T) {
+	pr := NewPullRequest{Title: "feat(GG-1): Things", Head: "GG-1_Things", Base: "main"}
+
+	cloud := NewBitbucket(bitbucketAPIURL, "").NewPullRequestURL(&Repo{"bitbucket.org", "workspace", "gg"}, pr)
+	if want := "https://bitbucket.org/workspace/gg/pull-requests/new?dest=main&source=GG-1_Things"; cloud != want {
+		t.Errorf("Bitbucket Cloud URL = %s, want %s", cloud, want)
+	}
+
+	server := NewBitbucketServer("", "").NewPullRequestURL(&Repo{"bitbucket.example.com", "PROJ", "gg"}, pr)
+	want := "https://bitbucket.example.com/projects/PROJ/repos/gg/pull-requests?create&" +
+		"sourceBranch=refs%2Fheads%2FGG-1_Things&targetBranch=refs%2Fheads%2Fmain&title=feat%28GG-1%29%3A+Things"
+	if server != want {
+		t.Errorf("Bitbucket Server URL = %s, want %s", server, want)
+	}
+}
@@ -0,0 +1,115 @@
+package hosting
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const gitHubAPIVersion = "2022-11-28"
+
+type GitHub struct {
+	api apiClient
+}
+
+func NewGitHub(apiURL, token string) *GitHub {
+	authorize := func(req *http.Request) {
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("X-GitHub-Api-Version", gitHubAPIVersion)
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return &GitHub{api: newAPIClient("GitHub", apiURL, authorize, decodeGitHubError)}
+}
+
+// GitHubAPIURL returns the REST endpoint for a host, github.com lives on its
+// own subdomain while Enterprise Server serves the API under /api/v3.
+func GitHubAPIURL(host string) string {
+	if host == "github.com" {
+		return "https://api.github.com"
+	}
+	return "https://" + host + "/api/v3"
+}
+
+type gitHubError struct {
+	Message string `json:"message"`
+	Errors  []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func (e *gitHubError) Error() string {
+	msgs := []string{e.Message}
+	for _, detail := range e.Errors {
+		if detail.Message != "" {
+			msgs = append(msgs, detail.Message)
+		}
+	}
+	return "GitHub Error: " + strings.Join(msgs, ": ")
+}
+
+func decodeGitHubError(status string, body []byte) error {
+	apiErr := &gitHubError{}
+	if json.Unmarshal(body, apiErr) != nil || apiErr.Message == "" {
+		apiErr.Message = status
+	}
+	return apiErr
+}
+
+type gitHubPullRequest struct {
+	Number   int     `json:"number"`
+	HTMLURL  string  `json:"html_url"`
+	MergedAt *string `json:"merged_at"`
+	Base     struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+}
+
+func (p *gitHubPullRequest) toPullRequest() *PullRequest {
+	return &PullRequest{Number: p.Number, URL: p.HTMLURL, Base: p.Base.Ref}
+}
+
+func (g *GitHub) CreatePullRequest(ctx context.Context, repo *Repo, pr NewPullRequest) (*PullRequest, error) {
+	payload := map[string]string{
+		"title": pr.Title,
+		"head":  pr.Head,
+		"base":  pr.Base,
+		"body":  pr.Body,
+	}
+	var created gitHubPullRequest
+	path := fmt.Sprintf("/repos/%s/%s/pulls", repo.Owner, repo.Name)
+	if err := g.api.do(ctx, http.MethodPost, path, payload, http.StatusCreated, &created); err != nil {
+		return nil, err
+	}
+	return created.toPullRequest(), nil
+}
+
+// FindMergedPullRequest looks up the merged PR opened from a branch in the
+// repository itself, PRs from forks are not considered.
+func (g *GitHub) FindMergedPullRequest(ctx context.Context, repo *Repo, branch string) (*PullRequest, error) {
+	query := url.Values{}
+	query.Set("state", "closed")
+	query.Set("head", repo.Owner+":"+branch)
+	var pulls []gitHubPullRequest
+	path := fmt.Sprintf("/repos/%s/%s/pulls?%s", repo.Owner, repo.Name, query.Encode())
+	if err := g.api.do(ctx, http.MethodGet, path, nil, http.StatusOK, &pulls); err != nil {
+		return nil, err
+	}
+	for _, pull := range pulls {
+		if pull.MergedAt != nil {
+			return pull.toPullRequest(), nil
+		}
+	}
+	return nil, ErrNoPullRequest
+}
+
+func (g *GitHub) NewPullRequestURL(repo *Repo, pr NewPullRequest) string {
+	createURL := fmt.Sprintf("%s/compare/%s...%s?quick_pull=1&title=%s",
+		repo.WebURL(), pr.Base, pr.Head, url.QueryEscape(pr.Title))
+	if pr.Body != "" {
+		createURL += "&body=" + url.QueryEscape(urlBody(pr.Body))
+	}
+	return createURL
+}
@@ -0,0 +1,96 @@
+package hosting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCreatePullRequest(t *testing.T) {
+	var got map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/bricktopab/gg/pulls" {
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer secret" {
+			t.Errorf("Expected bearer token, got %q", auth)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"number": 42, "html_url": "https://github.com/bricktopab/gg/pull/42"}`))
+	}))
+	defer server.Close()
+
+	gh := NewGitHub(server.URL, "secret")
+	pr, err := gh.CreatePullRequest(context.Background(), &Repo{Host: "github.com", Owner: "bricktopab", Name: "gg"},
+		NewPullRequest{Title: "feat(GG-1): Things", Head: "GG-1_Things", Base: "main"})
+	if err != nil {
+		t.Fatalf("CreatePullRequest failed: %v", err)
+	}
+
+	if pr.Number != 42 || pr.URL != "https://github.com/bricktopab/gg/pull/42" {
+		t.Errorf("Unexpected PR: %+v", pr)
+	}
+	if got["title"] != "feat(GG-1): Things" || got["head"] != "GG-1_Things" || got["base"] != "main" {
+		t.Errorf("Unexpected payload: %v", got)
+	}
+}
+
+func TestCreatePullRequestError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"message": "Validation Failed",
+			"errors": [{"message": "A pull request already exists for bricktopab:GG-1_Things."}]}`))
+	}))
+	defer server.Close()
+
+	gh := NewGitHub(server.URL, "secret")
+	_, err := gh.CreatePullRequest(context.Background(), &Repo{Owner: "bricktopab", Name: "gg"}, NewPullRequest{})
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Expected GitHub error details, got %q", err)
+	}
+}
+
+func TestFindMergedPullRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/bricktopab/gg/pulls" {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+		if head := r.URL.Query().Get("head"); head != "bricktopab:GG-1_Things" {
+			_, _ = w.Write([]byte(`[]`))
+			return
+		}
+		_, _ = w.Write([]byte(`[
+			{"number": 7, "html_url": "https://github.com/bricktopab/gg/pull/7", "merged_at": null, "base": {"ref": "main"}},
+			{"number": 9, "html_url": "https://github.com/bricktopab/gg/pull/9", "merged_at": "2025-06-01T10:00:00Z",
+			 "base": {"ref": "develop"}}
+		]`))
+	}))
+	defer server.Close()
+
+	gh := NewGitHub(server.URL, "secret")
+	repo := &Repo{Owner: "bricktopab", Name: "gg"}
+	pr, err := gh.FindMergedPullRequest(context.Background(), repo, "GG-1_Things")
+	if err != nil {
+		t.Fatalf("FindMergedPullRequest failed: %v", err)
+	}
+	if pr.Number != 9 || pr.Base != "develop" {
+		t.Errorf("Expected merged PR #9 against develop, got %+v", pr)
+	}
+
+	_, err = gh.FindMergedPullRequest(context.Background(), repo, "GG-2_Unmerged")
+	if !errors.Is(err, ErrNoPullRequest) {
+		t.Errorf("Expected ErrNoPullRequest for a branch without merged PRs, got %v", err)
+	}
+}
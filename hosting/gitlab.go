@@ -0,0 +1,107 @@
+package hosting
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type GitLab struct {
+	api apiClient
+}
+
+func NewGitLab(apiURL, token string) *GitLab {
+	authorize := func(req *http.Request) {
+		req.Header.Set("PRIVATE-TOKEN", token)
+	}
+	return &GitLab{api: newAPIClient("GitLab", apiURL, authorize, decodeGitLabError)}
+}
+
+func GitLabAPIURL(host string) string {
+	return "https://" + host + "/api/v4"
+}
+
+// GitLab reports validation failures as a list of messages and everything
+// else as a single message or error string.
+type gitLabError struct {
+	Message json.RawMessage `json:"message"`
+	Err     string          `json:"error"`
+}
+
+func decodeGitLabError(status string, body []byte) error {
+	var apiErr gitLabError
+	if json.Unmarshal(body, &apiErr) != nil {
+		return fmt.Errorf("GitLab Error: %s", status)
+	}
+	var messages []string
+	var message string
+	switch {
+	case json.Unmarshal(apiErr.Message, &messages) == nil && len(messages) > 0:
+		return fmt.Errorf("GitLab Error: %s", strings.Join(messages, ", "))
+	case json.Unmarshal(apiErr.Message, &message) == nil && message != "":
+		return fmt.Errorf("GitLab Error: %s", message)
+	case apiErr.Err != "":
+		return fmt.Errorf("GitLab Error: %s", apiErr.Err)
+	}
+	return fmt.Errorf("GitLab Error: %s", status)
+}
+
+type gitLabMergeRequest struct {
+	IID          int    `json:"iid"`
+	WebURL       string `json:"web_url"`
+	TargetBranch string `json:"target_branch"`
+}
+
+func (m *gitLabMergeRequest) toPullRequest() *PullRequest {
+	return &PullRequest{Number: m.IID, URL: m.WebURL, Base: m.TargetBranch}
+}
+
+// projectPath addresses a project by its full path, which GitLab wants as a
+// single URL encoded segment.
+func projectPath(repo *Repo) string {
+	return "/projects/" + url.PathEscape(repo.Owner+"/"+repo.Name)
+}
+
+func (g *GitLab) CreatePullRequest(ctx context.Context, repo *Repo, pr NewPullRequest) (*PullRequest, error) {
+	payload := map[string]any{
+		"title":         pr.Title,
+		"source_branch": pr.Head,
+		"target_branch": pr.Base,
+		"description":   pr.Body,
+	}
+	var created gitLabMergeRequest
+	path := projectPath(repo) + "/merge_requests"
+	if err := g.api.do(ctx, http.MethodPost, path, payload, http.StatusCreated, &created); err != nil {
+		return nil, err
+	}
+	return created.toPullRequest(), nil
+}
+
+func (g *GitLab) FindMergedPullRequest(ctx context.Context, repo *Repo, branch string) (*PullRequest, error) {
+	query := url.Values{}
+	query.Set("state", "merged")
+	query.Set("source_branch", branch)
+	var mergeRequests []gitLabMergeRequest
+	path := projectPath(repo) + "/merge_requests?" + query.Encode()
+	if err := g.api.do(ctx, http.MethodGet, path, nil, http.StatusOK, &mergeRequests); err != nil {
+		return nil, err
+	}
+	if len(mergeRequests) == 0 {
+		return nil, ErrNoPullRequest
+	}
+	return mergeRequests[0].toPullRequest(), nil
+}
+
+func (g *GitLab) NewPullRequestURL(repo *Repo, pr NewPullRequest) string {
+	query := url.Values{}
+	query.Set("merge_request[source_branch]", pr.Head)
+	query.Set("merge_request[target_branch]", pr.Base)
+	query.Set("merge_request[title]", pr.Title)
+	if pr.Body != "" {
+		query.Set("merge_request[description]", urlBody(pr.Body))
+	}
+	return repo.WebURL() + "/-/merge_requests/new?" + query.Encode()
+}
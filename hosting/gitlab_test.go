@@ -0,0 +1,117 @@
+package hosting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newFakeGitLab serves merge requests for group/sub/gg on a local server.
+func newFakeGitLab(t *testing.T, created *map[string]any) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/projects/{project}/merge_requests", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("project") != "group/sub/gg" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message": "404 Project Not Found"}`))
+			return
+		}
+		if r.Header.Get("PRIVATE-TOKEN") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"message": "401 Unauthorized"}`))
+			return
+		}
+		switch r.Method {
+		case http.MethodPost:
+			if err := json.NewDecoder(r.Body).Decode(created); err != nil {
+				t.Errorf("Failed to decode request: %v", err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if (*created)["source_branch"] == "GG-2_Again" {
+				w.WriteHeader(http.StatusConflict)
+				_, _ = w.Write([]byte(`{"message": ["Another open merge request already exists for this source branch: !3"]}`))
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"iid": 3, "web_url": "https://gitlab.example.com/group/sub/gg/-/merge_requests/3",
+				"target_branch": "main"}`))
+		case http.MethodGet:
+			if r.URL.Query().Get("state") != "merged" || r.URL.Query().Get("source_branch") != "GG-1_Things" {
+				_, _ = w.Write([]byte(`[]`))
+				return
+			}
+			_, _ = w.Write([]byte(`[{"iid": 2, "web_url": "https://gitlab.example.com/group/sub/gg/-/merge_requests/2",
+				"target_branch": "develop"}]`))
+		}
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGitLabCreateMergeRequest(t *testing.T) {
+	var got map[string]any
+	server := newFakeGitLab(t, &got)
+
+	gitlab := NewGitLab(server.URL+"/api/v4", "secret")
+	repo := &Repo{Host: "gitlab.example.com", Owner: "group/sub", Name: "gg"}
+	mr, err := gitlab.CreatePullRequest(context.Background(), repo,
+		NewPullRequest{Title: "feat(GG-1): Things", Head: "GG-1_Things", Base: "main", Body: "Closes GG-1"})
+	if err != nil {
+		t.Fatalf("CreatePullRequest failed: %v", err)
+	}
+
+	if mr.Number != 3 || mr.URL != "https://gitlab.example.com/group/sub/gg/-/merge_requests/3" {
+		t.Errorf("Unexpected merge request: %+v", mr)
+	}
+	if got["title"] != "feat(GG-1): Things" || got["source_branch"] != "GG-1_Things" ||
+		got["target_branch"] != "main" || got["description"] != "Closes GG-1" {
+		t.Errorf("Unexpected payload: %v", got)
+	}
+
+	_, err = gitlab.CreatePullRequest(context.Background(), repo, NewPullRequest{Head: "GG-2_Again", Base: "main"})
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Expected GitLab error details, got %v", err)
+	}
+
+	_, err = NewGitLab(server.URL+"/api/v4", "wrong").CreatePullRequest(context.Background(), repo, NewPullRequest{})
+	if err == nil || !strings.Contains(err.Error(), "401 Unauthorized") {
+		t.Errorf("Expected unauthorized error, got %v", err)
+	}
+}
+
+func TestGitLabFindMergedMergeRequest(t *testing.T) {
+	server := newFakeGitLab(t, &map[string]any{})
+
+	gitlab := NewGitLab(server.URL+"/api/v4", "secret")
+	repo := &Repo{Host: "gitlab.example.com", Owner: "group/sub", Name: "gg"}
+	mr, err := gitlab.FindMergedPullRequest(context.Background(), repo, "GG-1_Things")
+	if err != nil {
+		t.Fatalf("FindMergedPullRequest failed: %v", err)
+	}
+	if mr.Number != 2 || mr.Base != "develop" {
+		t.Errorf("Expected merge request !2 against develop, got %+v", mr)
+	}
+
+	_, err = gitlab.FindMergedPullRequest(context.Background(), repo, "GG-2_Unmerged")
+	if !errors.Is(err, ErrNoPullRequest) {
+		t.Errorf("Expected ErrNoPullRequest, got %v", err)
+	}
+}
+
+func TestGitLabNewMergeRequestURL(t *testing.T) {
+	gitlab := NewGitLab(GitLabAPIURL("gitlab.example.com"), "")
+	repo := &Repo{Host: "gitlab.example.com", Owner: "group", Name: "gg"}
+	got := gitlab.NewPullRequestURL(repo, NewPullRequest{Title: "fix(GG-1): Things & stuff", Head: "GG-1_Things", Base: "main"})
+	want := "https://gitlab.example.com/group/gg/-/merge_requests/new?" +
+		"merge_request%5Bsource_branch%5D=GG-1_Things&merge_request%5Btarget_branch%5D=main" +
+		"&merge_request%5Btitle%5D=fix%28GG-1%29%3A+Things+%26+stuff"
+	if got != want {
+		t.Errorf("NewPullRequestURL() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,104 @@
+package hosting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	GitHubKind          = "github"
+	GitLabKind          = "gitlab"
+	BitbucketKind       = "bitbucket"
+	BitbucketServerKind = "bitbucket-server"
+	AzureKind           = "azure"
+)
+
+var ErrNoPullRequest = errors.New("no pull request found")
+
+type PullRequest struct {
+	Number int
+	URL    string
+	Base   string
+}
+
+type NewPullRequest struct {
+	Title string
+	Head  string
+	Base  string
+	Body  string
+}
+
+// maxURLBody is how long a PR description may get in a URL once escaped.
+// Browsers, servers and the Windows command line all have their limits.
+const maxURLBody = 4000
+
+const urlBodyCut = "\n\n(cut short to fit in the link)"
+
+// urlBody cuts a PR description short enough to go into a URL, at the end of
+// a line where it can, saying so at the end.
+func urlBody(body string) string {
+	if len(url.QueryEscape(body)) <= maxURLBody {
+		return body
+	}
+	budget := maxURLBody - len(url.QueryEscape(urlBodyCut))
+	end := 0
+	for i, r := range body {
+		if budget -= len(url.QueryEscape(string(r))); budget < 0 {
+			break
+		}
+		end = i + utf8.RuneLen(r)
+	}
+	cut := body[:end]
+	if line := strings.LastIndexByte(cut, '\n'); line > 0 {
+		cut = strings.TrimRight(cut[:line], "\n")
+	}
+	return cut + urlBodyCut
+}
+
+// Provider is a code hosting service we can open pull (or merge) requests on.
+// NewPullRequestURL must work without credentials, it's the fallback when
+// there is no token for the API calls.
+type Provider interface {
+	CreatePullRequest(ctx context.Context, repo *Repo, pr NewPullRequest) (*PullRequest, error)
+	FindMergedPullRequest(ctx context.Context, repo *Repo, branch string) (*PullRequest, error)
+	NewPullRequestURL(repo *Repo, pr NewPullRequest) string
+}
+
+// DetectKind guesses the provider from the remote host, self-hosted
+// instances with neutral host names need to be configured explicitly.
+func DetectKind(host string) (string, error) {
+	host = strings.ToLower(host)
+	switch {
+	case host == "bitbucket.org":
+		return BitbucketKind, nil
+	case host == azureHost:
+		return AzureKind, nil
+	case strings.Contains(host, "bitbucket"):
+		return BitbucketServerKind, nil
+	case strings.Contains(host, "github"):
+		return GitHubKind, nil
+	case strings.Contains(host, "gitlab"):
+		return GitLabKind, nil
+	}
+	return "", fmt.Errorf("can't tell which hosting provider %s is, please configure it", host)
+}
+
+func New(kind, host, token string) (Provider, error) {
+	switch kind {
+	case GitHubKind:
+		return NewGitHub(GitHubAPIURL(host), token), nil
+	case GitLabKind:
+		return NewGitLab(GitLabAPIURL(host), token), nil
+	case BitbucketKind:
+		return NewBitbucket(bitbucketAPIURL, token), nil
+	case BitbucketServerKind:
+		return NewBitbucketServer(BitbucketServerAPIURL(host), token), nil
+	case AzureKind:
+		return NewAzure(AzureAPIURL(host), token), nil
+	}
+	return nil, fmt.Errorf("unknown hosting provider: %s", kind)
+}
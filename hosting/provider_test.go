@@ -0,0 +1,47 @@
+package hosting
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestDetectKind(t *testing.T) {
+	for host, want := range map[string]string{
+		"github.com":            GitHubKind,
+		"github.example.com":    GitHubKind,
+		"gitlab.com":            GitLabKind,
+		"GitLab.example.com":    GitLabKind,
+		"bitbucket.org":         BitbucketKind,
+		"bitbucket.example.com": BitbucketServerKind,
+		"dev.azure.com":         AzureKind,
+	} {
+		if got, err := DetectKind(host); err != nil || got != want {
+			t.Errorf("DetectKind(%q) = %q, %v, want %q", host, got, err, want)
+		}
+	}
+	if _, err := DetectKind("git.example.com"); err == nil {
+		t.Error("Expected an error for an unknown host")
+	}
+}
+
+func TestURLBody(t *testing.T) {
+	if body := "## Summary\n\nShort"; urlBody(body) != body {
+		t.Errorf("urlBody() changed a short body to %q", urlBody(body))
+	}
+	long := strings.Repeat("- a line with ünïcode & escapes\n", 500)
+	got := urlBody(long)
+	if len(url.QueryEscape(got)) > maxURLBody {
+		t.Errorf("urlBody() is %d escaped, want at most %d", len(url.QueryEscape(got)), maxURLBody)
+	}
+	kept, found := strings.CutSuffix(got, urlBodyCut)
+	if !found || !strings.HasPrefix(long, kept+"\n") || !strings.HasSuffix(kept, "escapes") {
+		t.Errorf("urlBody() didn't cut at the end of a line:\n%s", got)
+	}
+
+	gh := NewGitHub("", "").NewPullRequestURL(&Repo{Host: "github.com", Owner: "bricktopab", Name: "gg"},
+		NewPullRequest{Title: "feat(GG-1): Things", Head: "GG-1_Things", Base: "main", Body: long})
+	if !strings.HasSuffix(gh, "&body="+url.QueryEscape(got)) {
+		t.Errorf("GitHub URL has the body uncut: %d long", len(gh))
+	}
+}
@@ -0,0 +1,129 @@
+package hosting
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Repo identifies a repository on a hosting provider. Owner can have several
+// segments, like GitLab subgroups or an Azure DevOps organization/project.
+type Repo struct {
+	Host  string
+	Owner string
+	Name  string
+}
+
+func (r *Repo) WebURL() string {
+	return fmt.Sprintf("https://%s/%s/%s", r.Host, r.Owner, r.Name)
+}
+
+// RemoteResolver turns a remote URL as configured into the repository git
+// actually talks to, applying url.<base>.insteadOf rewrites and SSH host
+// aliases on the way.
+type RemoteResolver struct {
+	// InsteadOf maps the insteadOf prefixes to the base URL replacing them
+	InsteadOf map[string]string
+	SSH       *SSHConfig
+}
+
+func (r *RemoteResolver) Resolve(remoteURL string) (*Repo, error) {
+	remoteURL = r.rewrite(strings.TrimSpace(remoteURL))
+	remote, err := splitRemote(remoteURL)
+	if err != nil {
+		return nil, err
+	}
+	if remote.ssh && r.SSH != nil {
+		remote.host = r.SSH.HostName(remote.host)
+	}
+	return remote.repo(remoteURL)
+}
+
+// rewrite applies the longest matching insteadOf prefix, like git does.
+func (r *RemoteResolver) rewrite(remoteURL string) string {
+	longest := ""
+	for prefix := range r.InsteadOf {
+		if strings.HasPrefix(remoteURL, prefix) && len(prefix) > len(longest) {
+			longest = prefix
+		}
+	}
+	if longest == "" {
+		return remoteURL
+	}
+	return r.InsteadOf[longest] + strings.TrimPrefix(remoteURL, longest)
+}
+
+// ParseRemote understands URL style remotes (https://, ssh://, git://) with or
+// without credentials as well as scp-style "git@host:owner/repo.git". Use a
+// RemoteResolver to also take the user's git and ssh config into account.
+func ParseRemote(remoteURL string) (*Repo, error) {
+	return (&RemoteResolver{}).Resolve(remoteURL)
+}
+
+type remoteParts struct {
+	host string
+	path string
+	ssh  bool
+}
+
+func splitRemote(remoteURL string) (*remoteParts, error) {
+	if !strings.Contains(remoteURL, "://") {
+		hostPart, pathPart, ok := strings.Cut(remoteURL, ":")
+		if !ok || strings.Contains(hostPart, "/") {
+			return nil, fmt.Errorf("unsupported remote URL: %s", remoteURL)
+		}
+		if _, after, found := strings.Cut(hostPart, "@"); found {
+			hostPart = after
+		}
+		return &remoteParts{host: hostPart, path: pathPart, ssh: true}, nil
+	}
+
+	u, err := url.Parse(remoteURL)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported remote URL: %s", remoteURL)
+	}
+	switch u.Scheme {
+	case "https", "http":
+		// keep the port, it's where the web UI lives too
+		return &remoteParts{host: u.Host, path: u.Path}, nil
+	case "ssh", "git+ssh", "ssh+git":
+		return &remoteParts{host: u.Hostname(), path: u.Path, ssh: true}, nil
+	case "git":
+		return &remoteParts{host: u.Hostname(), path: u.Path}, nil
+	}
+	return nil, fmt.Errorf("unsupported remote URL scheme: %s", u.Scheme)
+}
+
+func (p *remoteParts) repo(remoteURL string) (*Repo, error) {
+	if p.host == "" {
+		return nil, fmt.Errorf("remote URL has no host: %s", remoteURL)
+	}
+	host, path := normalizeRemotePath(strings.ToLower(p.host), strings.TrimSuffix(strings.Trim(p.path, "/"), ".git"))
+	idx := strings.LastIndex(path, "/")
+	if idx <= 0 || idx == len(path)-1 {
+		return nil, fmt.Errorf("remote URL has no owner/repo: %s", remoteURL)
+	}
+	return &Repo{Host: host, Owner: path[:idx], Name: path[idx+1:]}, nil
+}
+
+// normalizeRemotePath strips the parts of a path that only exist in clone
+// URLs, so what's left is owner/repo like on the web.
+func normalizeRemotePath(host, path string) (string, string) {
+	switch {
+	case host == "ssh.dev.azure.com" || host == "vs-ssh.visualstudio.com":
+		// git@ssh.dev.azure.com:v3/org/project/repo
+		return azureHost, strings.TrimPrefix(path, "v3/")
+	case host == azureHost:
+		// https://dev.azure.com/org/project/_git/repo
+		return host, strings.Replace(path, "/_git/", "/", 1)
+	case strings.HasSuffix(host, ".visualstudio.com"):
+		// https://org.visualstudio.com/DefaultCollection/project/_git/repo
+		org := strings.TrimSuffix(host, ".visualstudio.com")
+		path = strings.TrimPrefix(path, "DefaultCollection/")
+		return azureHost, org + "/" + strings.Replace(path, "/_git/", "/", 1)
+	case strings.HasPrefix(path, "scm/"):
+		// Bitbucket Server clones over HTTP from https://host/scm/project/repo
+		return host, strings.TrimPrefix(path, "scm/")
+	}
+	return host, path
+}
@@ -0,0 +1,90 @@
+package hosting
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+// SSHConfig knows just enough of ~/.ssh/config to map host aliases to real
+// host names. Include directives are not followed and Match blocks are
+// skipped.
+type SSHConfig struct {
+	blocks []sshHostBlock
+}
+
+type sshHostBlock struct {
+	patterns []string
+	hostName string
+}
+
+func LoadSSHConfig(filename string) (*SSHConfig, error) {
+	file, err := os.Open(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &SSHConfig{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	return ParseSSHConfig(file)
+}
+
+func ParseSSHConfig(r io.Reader) (*SSHConfig, error) {
+	// options before the first Host line apply to every host
+	config := &SSHConfig{blocks: []sshHostBlock{{patterns: []string{"*"}}}}
+	current := &config.blocks[0]
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		keyword, args, _ := strings.Cut(strings.Replace(line, "=", " ", 1), " ")
+		args = strings.Trim(strings.TrimSpace(args), `"`)
+		switch strings.ToLower(keyword) {
+		case "host":
+			config.blocks = append(config.blocks, sshHostBlock{patterns: strings.Fields(args)})
+			current = &config.blocks[len(config.blocks)-1]
+		case "match":
+			config.blocks = append(config.blocks, sshHostBlock{})
+			current = &config.blocks[len(config.blocks)-1]
+		case "hostname":
+			// like ssh, the first value found wins
+			if current.hostName == "" {
+				current.hostName = args
+			}
+		}
+	}
+	return config, scanner.Err()
+}
+
+func (b *sshHostBlock) matches(host string) bool {
+	matched := false
+	for _, pattern := range b.patterns {
+		negated := strings.HasPrefix(pattern, "!")
+		ok, _ := path.Match(strings.ToLower(strings.TrimPrefix(pattern, "!")), strings.ToLower(host))
+		if ok && negated {
+			return false
+		}
+		matched = matched || ok
+	}
+	return matched
+}
+
+// HostName returns the host an alias connects to, or the alias itself.
+func (c *SSHConfig) HostName(alias string) string {
+	for _, block := range c.blocks {
+		if block.hostName != "" && block.matches(alias) {
+			return strings.NewReplacer("%h", alias, "%%", "%").Replace(block.hostName)
+		}
+	}
+	return alias
+}
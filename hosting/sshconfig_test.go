@@ -0,0 +1,57 @@
+package hosting
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSSHConfigHostName(t *testing.T) {
+	config, err := ParseSSHConfig(strings.NewReader(`
+Host first
+    HostName first.example.com
+    HostName ignored.example.com
+
+Host first second
+    HostName second.example.com
+
+Match host third
+    HostName never.example.com
+
+Host "quoted"
+    HostName "quoted.example.com"
+
+# like in ssh, catch-alls go last since the first value wins
+Host *
+    HostName fallback.example.com
+`))
+	if err != nil {
+		t.Fatalf("ParseSSHConfig failed: %v", err)
+	}
+
+	tests := []struct {
+		alias string
+		want  string
+	}{
+		{"first", "first.example.com"},
+		{"FIRST", "first.example.com"},
+		{"second", "second.example.com"},
+		{"third", "fallback.example.com"},
+		{"quoted", "quoted.example.com"},
+	}
+	for _, tt := range tests {
+		if got := config.HostName(tt.alias); got != tt.want {
+			t.Errorf("HostName(%q) = %q, want %q", tt.alias, got, tt.want)
+		}
+	}
+}
+
+func TestLoadSSHConfigMissingFile(t *testing.T) {
+	config, err := LoadSSHConfig(filepath.Join(t.TempDir(), "config"))
+	if err != nil {
+		t.Fatalf("LoadSSHConfig failed: %v", err)
+	}
+	if got := config.HostName("github.com"); got != "github.com" {
+		t.Errorf("Expected host to be left alone, got %q", got)
+	}
+}
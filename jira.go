@@ -1,28 +1,79 @@
 package main
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
 
+	"github.com/bricktopab/gg/adf"
 	"github.com/bricktopab/gg/cfg"
+	"github.com/bricktopab/gg/oauth"
+	"github.com/ctreminiom/go-atlassian/jira/agile"
 	jira "github.com/ctreminiom/go-atlassian/jira/v3"
 	"github.com/ctreminiom/go-atlassian/pkg/infra/models"
 )
 
+// JiraWrapper talks to Jira Cloud, see ServerJira for Server and Data Center.
 type JiraWrapper struct {
+	jiraBoards
 	client *jira.Client
 	config *cfg.JiraConfig
 }
 
-func NewJiraWrapperWithOldConfig(jiraUser, jiraToken, jiraURL, jiraProject string) (*JiraWrapper, error) {
-	return NewJiraWrapper(&cfg.JiraConfig{
-		JiraUser:      jiraUser,
-		JiraURL:       jiraURL,
-		JiraToken:     jiraToken,
-		JiraProject:   jiraProject,
-		JiraAccountID: nil,
-	})
+// jiraBoards has the boards and sprints, which work the same everywhere.
+type jiraBoards struct {
+	agile *agile.Client
+}
+
+// NewJira picks the client for the deployment, Cloud unless it's Server.
+func NewJira(jiraConfig *cfg.JiraConfig) (Jira, error) {
+	switch jiraConfig.JiraDeployment {
+	case cfg.JiraServer:
+		return NewServerJira(jiraConfig)
+	case cfg.JiraCloud, "":
+		return NewJiraWrapper(jiraConfig)
+	}
+	return nil, configError("unknown jira_deployment %q, use %s or %s",
+		jiraConfig.JiraDeployment, cfg.JiraCloud, cfg.JiraServer)
+}
+
+// newJiraClient connects to the Jira of the profile in use, with the login of
+// gg login when there is one. Jira is asked whether it's Cloud or Server
+// unless the config says so.
+func newJiraClient(config *cfg.Config, jiraToken string) (Jira, error) {
+	account := config.Account()
+	jiraConfig := &cfg.JiraConfig{JiraAccount: account, JiraToken: jiraToken, JiraProject: config.Settings().JiraProject}
+	if account.JiraOAuth != nil && account.JiraOAuth.CloudID != "" {
+		tokens, err := jiraTokens(config)
+		if err != nil {
+			return nil, err
+		}
+		jira, err := NewOAuthJiraWrapper(jiraConfig, account.JiraOAuth.CloudID, tokens)
+		if err != nil {
+			return nil, configError("failed to create Jira client: %w", err)
+		}
+		return jira, nil
+	}
+	if account.JiraDeployment == "" {
+		deployment, err := DetectJiraDeployment(account.JiraURL)
+		if err != nil {
+			return nil, configError("failed to tell Jira Cloud from Server, set jira_deployment: %w", err)
+		}
+		account.JiraDeployment = deployment
+	}
+	jira, err := NewJira(jiraConfig)
+	if err != nil {
+		return nil, configError("failed to create Jira client: %w", err)
+	}
+	return jira, nil
 }
 
 func NewJiraWrapper(jiraConfig *cfg.JiraConfig) (*JiraWrapper, error) {
@@ -31,76 +82,116 @@ func NewJiraWrapper(jiraConfig *cfg.JiraConfig) (*JiraWrapper, error) {
 		return nil, err
 	}
 	atlassian.Auth.SetBasicAuth(jiraConfig.JiraUser, jiraConfig.JiraToken)
-	return &JiraWrapper{client: atlassian, config: jiraConfig}, nil
+	agileClient, err := agile.New(nil, jiraConfig.JiraURL)
+	if err != nil {
+		return nil, err
+	}
+	agileClient.Auth.SetBasicAuth(jiraConfig.JiraUser, jiraConfig.JiraToken)
+	return &JiraWrapper{jiraBoards: jiraBoards{agile: agileClient}, client: atlassian, config: jiraConfig}, nil
 }
 
-func (j *JiraWrapper) GetIssueTypes() map[string]string {
-	project, resp, err := j.client.Project.Get(context.Background(), j.config.JiraProject, nil)
+// NewOAuthJiraWrapper uses the login of gg login rather than the API token.
+// OAuth apps reach the site through the Atlassian API by its cloud ID.
+func NewOAuthJiraWrapper(jiraConfig *cfg.JiraConfig, cloudID string, tokens *oauth.Source) (*JiraWrapper, error) {
+	httpClient := &http.Client{Transport: &oauth.Transport{Source: tokens}}
+	site := atlassianAPI + "/ex/jira/" + cloudID
+	atlassian, err := jira.New(httpClient, site)
 	if err != nil {
-		log.Fatal("JIRA Error: ", resp.Bytes.String())
+		return nil, err
+	}
+	agileClient, err := agile.New(httpClient, site)
+	if err != nil {
+		return nil, err
+	}
+	return &JiraWrapper{jiraBoards: jiraBoards{agile: agileClient}, client: atlassian, config: jiraConfig}, nil
+}
+
+// GetIssueTypes lists the sub-task types of the project, or all the others.
+func (j *JiraWrapper) GetIssueTypes(subtask bool) (map[string]string, error) {
+	return issueTypes(j.client.Project, j.config.JiraProject, subtask)
+}
+
+// projects is the project API, the same in both API versions.
+type projects interface {
+	Get(ctx context.Context, projectKeyOrID string, expand []string) (*models.ProjectScheme, *models.ResponseScheme, error)
+}
+
+func issueTypes(api projects, key string, subtask bool) (map[string]string, error) {
+	project, resp, err := api.Get(context.Background(), key, nil)
+	if err != nil {
+		return nil, jiraError(resp, err)
 	}
 	if project.IssueTypes == nil {
-		log.Fatalf("No project with key: %s found", j.config.JiraProject)
+		return nil, jiraErrorf("no project with key: %s found", key)
 	}
 	types := map[string]string{}
 	for _, v := range project.IssueTypes {
-		if !v.Subtask {
+		if v.Subtask == subtask {
 			types[v.Name] = v.ID
 		}
 	}
-	return types
+	return types, nil
+}
+
+func jiraError(resp *models.ResponseScheme, err error) error {
+	if errors.Is(err, oauth.ErrNotLoggedIn) {
+		return configError("%w, run gg login", err)
+	}
+	if resp == nil || resp.Bytes.Len() == 0 {
+		return jiraErrorf("JIRA Error: %w", err)
+	}
+	return jiraErrorf("JIRA Error: %s", resp.Bytes.String())
 }
 
 func (j *JiraWrapper) LookupMyAccountID() (*string, error) {
 	currentUser, resp, err := j.client.MySelf.Details(context.Background(), nil)
 	if err != nil {
-		return nil, fmt.Errorf("JIRA Error: %s", resp.Bytes.String())
+		return nil, jiraError(resp, err)
 	}
 	return &currentUser.AccountID, nil
 }
 
-func (j *JiraWrapper) CreateIssue(typeID string, title, description string) *cfg.Task {
-	if j.config.JiraAccountID == nil {
+func (j *JiraWrapper) myAccountID() *string {
+	if j.config.JiraAccountID == "" {
 		id, err := j.LookupMyAccountID()
-		if err == nil {
-			j.config.JiraAccountID = id
+		if err != nil {
+			return nil
 		}
+		j.config.JiraAccountID = *id
 	}
+	return &j.config.JiraAccountID
+}
+
+func (j *JiraWrapper) CreateIssue(draft *cfg.IssueDraft) (*cfg.Task, error) {
+	title, description := draft.Title, draft.Description
 	payload := &models.IssueScheme{
 		Fields: &models.IssueFieldsScheme{
 			Summary:   title,
 			Project:   &models.ProjectScheme{Key: j.config.JiraProject},
-			IssueType: &models.IssueTypeScheme{ID: typeID},
+			IssueType: &models.IssueTypeScheme{ID: draft.TypeID},
 		},
 	}
+	if draft.Parent != "" {
+		payload.Fields.Parent = &models.ParentScheme{Key: draft.Parent}
+	}
 
-	if j.config.JiraAccountID != nil {
+	if accountID := j.myAccountID(); accountID != nil {
 		payload.Fields.Assignee = &models.UserScheme{
-			AccountID: *j.config.JiraAccountID,
+			AccountID: *accountID,
 		}
 	}
 
 	if description != "" {
-		payload.Fields.Description = &models.CommentNodeScheme{
-			Type:    "doc",
-			Version: 1,
-			Content: []*models.CommentNodeScheme{
-				{
-					Type: "paragraph",
-					Content: []*models.CommentNodeScheme{
-						{
-							Type: "text",
-							Text: description,
-						},
-					},
-				},
-			},
-		}
+		payload.Fields.Description = adf.FromMarkdown(description)
 	}
 
-	issue, resp, err := j.client.Issue.Create(context.Background(), payload, nil)
+	fields, err := customFields(draft.Fields, adfText)
 	if err != nil {
-		log.Fatal("JIRA Error: ", resp.Bytes.String())
+		return nil, err
+	}
+	issue, resp, err := j.client.Issue.Create(context.Background(), payload, fields)
+	if err != nil {
+		return nil, jiraError(resp, err)
 	}
 
 	log.Printf("Issue created: %s\n", issue.Key)
@@ -108,33 +199,445 @@ func (j *JiraWrapper) CreateIssue(typeID string, title, description string) *cfg
 		IssueID:     issue.Key,
 		Title:       title,
 		Description: description,
-		Type:        typeID,
+		Type:        draft.Type,
+	}, nil
+}
+
+// customFields has the values of the fields that were filled in, richText
+// turns Markdown into what rich text fields take.
+func customFields(draftFields []cfg.IssueField, richText func(markdown string) any) (*models.CustomFields, error) {
+	var fields *models.CustomFields
+	for _, field := range draftFields {
+		if field.Value == "" {
+			continue
+		}
+		value, err := fieldValue(field, richText)
+		if err != nil {
+			return nil, err
+		}
+		if fields == nil {
+			fields = &models.CustomFields{}
+		}
+		fields.Fields = append(fields.Fields, map[string]any{"fields": map[string]any{field.ID: value}})
+	}
+	return fields, nil
+}
+
+func adfText(markdown string) any {
+	return adf.FromMarkdown(markdown)
+}
+
+// createMeta is the part of the create metadata gg reads.
+// cloudCreateMeta is a page of the fields of an issue type.
+type cloudCreateMeta struct {
+	Total  int `json:"total"`
+	Fields []struct {
+		FieldID string `json:"fieldId"`
+		fieldMeta
+	} `json:"fields"`
+}
+
+type fieldMeta struct {
+	Required bool   `json:"required"`
+	Name     string `json:"name"`
+	Schema   struct {
+		Type   string `json:"type"`
+		Items  string `json:"items"`
+		Custom string `json:"custom"`
+	} `json:"schema"`
+	AllowedValues []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"allowedValues"`
+}
+
+// setFields are set by gg itself or after creating the issue.
+var setFields = []string{"summary", "description", "issuetype", "project", "assignee", "reporter", "parent", "issuelinks"}
+
+// IssueFields reads the create metadata of an issue type for the fields gg
+// knows how to fill in.
+func (j *JiraWrapper) IssueFields(typeID string) ([]cfg.IssueField, error) {
+	meta := map[string]fieldMeta{}
+	for startAt := 0; ; {
+		path := fmt.Sprintf("rest/api/3/issue/createmeta/%s/issuetypes/%s?startAt=%d",
+			url.PathEscape(j.config.JiraProject), url.PathEscape(typeID), startAt)
+		var page cloudCreateMeta
+		if err := j.call(http.MethodGet, path, nil, &page); err != nil {
+			return nil, err
+		}
+		for _, field := range page.Fields {
+			meta[field.FieldID] = field.fieldMeta
+		}
+		startAt += len(page.Fields)
+		if startAt >= page.Total || len(page.Fields) == 0 {
+			return issueFields(meta), nil
+		}
+	}
+}
+
+// call does a request the client has no method for.
+func (j *JiraWrapper) call(method, path string, body, result any) error {
+	req, err := j.client.NewRequest(context.Background(), method, path, "", body)
+	if err != nil {
+		return jiraErrorf("JIRA Error: %w", err)
 	}
+	resp, err := j.client.Call(req, result)
+	if err != nil {
+		return jiraError(resp, err)
+	}
+	return nil
+}
+
+// issueFields picks the fields gg can fill in from the metadata by field ID.
+func issueFields(meta map[string]fieldMeta) []cfg.IssueField {
+	fields := []cfg.IssueField{}
+	for id, f := range meta {
+		if slices.Contains(setFields, id) || !supportedField(f.Schema.Type, f.Schema.Items) {
+			continue
+		}
+		field := cfg.IssueField{ID: id, Name: f.Name, Required: f.Required, Type: f.Schema.Type, Items: f.Schema.Items}
+		if strings.HasSuffix(f.Schema.Custom, ":textarea") {
+			field.Type = "textarea"
+		}
+		for _, allowed := range f.AllowedValues {
+			field.Allowed = append(field.Allowed, cmp.Or(allowed.Name, allowed.Value))
+		}
+		fields = append(fields, field)
+	}
+	slices.SortFunc(fields, func(a, b cfg.IssueField) int { return cmp.Compare(a.Name, b.Name) })
+	return fields
+}
+
+// namedTypes are set by name, options by value.
+var namedTypes = []string{"priority", "component", "version"}
+
+func supportedField(fieldType, items string) bool {
+	switch fieldType {
+	case "string", "number", "date", "option":
+		return true
+	case "array":
+		return items == "string" || items == "option" || slices.Contains(namedTypes, items)
+	}
+	return slices.Contains(namedTypes, fieldType)
+}
+
+// fieldValue turns what was entered for a field into what Jira expects.
+func fieldValue(field cfg.IssueField, richText func(markdown string) any) (any, error) {
+	switch field.Type {
+	case "number":
+		n, err := strconv.ParseFloat(field.Value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s should be a number, not %q", field.Name, field.Value)
+		}
+		return n, nil
+	case "textarea":
+		return richText(field.Value), nil
+	case "array":
+		values := []any{}
+		for _, v := range field.Values() {
+			values = append(values, itemValue(field.Items, v))
+		}
+		return values, nil
+	}
+	return itemValue(field.Type, field.Value), nil
 }
 
-func (j *JiraWrapper) FindOpenIssues(onlyMine bool) []cfg.Task {
-	mine := "AND assignee is empty"
-	if onlyMine {
-		mine = "AND assignee = currentUser()"
+func itemValue(itemType, value string) any {
+	switch {
+	case itemType == "option":
+		return map[string]string{"value": value}
+	case slices.Contains(namedTypes, itemType):
+		return map[string]string{"name": value}
 	}
-	jql := fmt.Sprintf(`project="%s"  %s AND 
-		statusCategory in ("To Do", "In Progress") order by updated DESC`, j.config.JiraProject, mine)
-	issues, _, err := j.client.Issue.Search.Get(
-		context.Background(),
-		jql,
-		[]string{"summary", "description", "issuetype"},
-		[]string{},
-		0, 50, "")
+	return value
+}
+
+// LinkIssue links an issue to another one. The relation is matched against
+// the inward and outward descriptions of the link types, so "blocks" and
+// "is blocked by" both work with the Blocks type.
+func (j *JiraWrapper) LinkIssue(issueID string, link cfg.IssueLink) error {
+	types, resp, err := j.client.Issue.Link.Type.Gets(context.Background())
 	if err != nil {
-		log.Fatal("Error: ", err)
+		return jiraError(resp, err)
 	}
+	payload, err := linkPayload(types.IssueLinkTypes, issueID, link)
+	if err != nil {
+		return err
+	}
+	if resp, err := j.client.Issue.Link.Create(context.Background(), payload); err != nil {
+		return jiraError(resp, err)
+	}
+	log.Printf("Issue %s %s\n", issueID, link)
+	return nil
+}
+
+// linkPayload reads "A blocks B" as a Blocks link with A as the inward issue,
+// which is how Jira's POST /issueLink takes it.
+func linkPayload(types []*models.LinkTypeScheme, issueID string, link cfg.IssueLink) (*models.LinkPayloadSchemeV3, error) {
+	var relations []string
+	for _, t := range types {
+		from, to := issueID, link.Issue
+		switch {
+		case strings.EqualFold(t.Outward, link.Relation), strings.EqualFold(t.Name, link.Relation):
+		case strings.EqualFold(t.Inward, link.Relation):
+			from, to = to, from
+		default:
+			relations = append(relations, t.Outward, t.Inward)
+			continue
+		}
+		return &models.LinkPayloadSchemeV3{
+			Type:         &models.LinkTypeScheme{Name: t.Name},
+			InwardIssue:  &models.LinkedIssueScheme{Key: from},
+			OutwardIssue: &models.LinkedIssueScheme{Key: to},
+		}, nil
+	}
+	return nil, jiraErrorf("unknown link %q, use one of: %s", link.Relation, strings.Join(relations, ", "))
+}
+
+// searchPageSize is the most Jira returns in one page.
+const searchPageSize = 100
+
+// SearchIssues pages through all issues matching the JQL.
+func (j *JiraWrapper) SearchIssues(jql string) ([]cfg.Task, error) {
+	return searchIssues(j.search, jql)
+}
+
+// searchFunc calls add with every issue matching the JQL.
+type searchFunc func(jql string, fields []string, add func(*models.IssueScheme)) error
+
+func searchIssues(search searchFunc, jql string) ([]cfg.Task, error) {
 	tasks := []cfg.Task{}
-	for _, issue := range issues.Issues {
-		tasks = append(tasks, cfg.Task{
-			IssueID: issue.Key,
-			Title:   issue.Fields.Summary,
-			Type:    issue.Fields.IssueType.Name,
-		})
-	}
-	return tasks
+	err := search(jql, []string{"summary", "issuetype"}, func(issue *models.IssueScheme) {
+		task := cfg.Task{IssueID: issue.Key}
+		if issue.Fields != nil {
+			task.Title = issue.Fields.Summary
+			if issue.Fields.IssueType != nil {
+				task.Type = issue.Fields.IssueType.Name
+			}
+		}
+		tasks = append(tasks, task)
+	})
+	return tasks, err
+}
+
+func (j *JiraWrapper) search(jql string, fields []string, add func(*models.IssueScheme)) error {
+	startAt := 0
+	for {
+		page, resp, err := j.client.Issue.Search.Get(context.Background(), jql, fields, []string{},
+			startAt, searchPageSize, "")
+		if err != nil {
+			return jiraError(resp, err)
+		}
+		for _, issue := range page.Issues {
+			add(issue)
+		}
+		startAt += len(page.Issues)
+		if len(page.Issues) == 0 || startAt >= page.Total {
+			return nil
+		}
+	}
+}
+
+// errNoActiveSprint tells that a board has no sprint going on.
+var errNoActiveSprint = errors.New("no active sprint")
+
+// ActiveSprint finds the sprint going on for a board, without its issues.
+func (j *jiraBoards) ActiveSprint(boardID int) (*cfg.Sprint, error) {
+	page, resp, err := j.agile.Board.Sprints(context.Background(), boardID, 0, 1, []string{"active"})
+	if err != nil {
+		return nil, jiraError(resp, err)
+	}
+	if len(page.Values) == 0 {
+		return nil, jiraErrorf("board %d: %w", boardID, errNoActiveSprint)
+	}
+	sprint := page.Values[0]
+	return &cfg.Sprint{ID: sprint.ID, Name: sprint.Name, Goal: sprint.Goal}, nil
+}
+
+// SprintIssues lists the issues of a sprint in rank order.
+func (j *JiraWrapper) SprintIssues(sprintID int) ([]cfg.SprintIssue, error) {
+	return sprintIssues(j.search, sprintID)
+}
+
+func sprintIssues(search searchFunc, sprintID int) ([]cfg.SprintIssue, error) {
+	issues := []cfg.SprintIssue{}
+	jql := fmt.Sprintf("sprint = %d ORDER BY rank", sprintID)
+	err := search(jql, []string{"summary", "issuetype", "status", "assignee"}, func(issue *models.IssueScheme) {
+		sprintIssue := cfg.SprintIssue{Key: issue.Key}
+		if f := issue.Fields; f != nil {
+			sprintIssue.Title = f.Summary
+			if f.IssueType != nil {
+				sprintIssue.Type = f.IssueType.Name
+			}
+			if f.Status != nil {
+				sprintIssue.Status = f.Status.Name
+				if f.Status.StatusCategory != nil {
+					sprintIssue.StatusCategory = f.Status.StatusCategory.Name
+				}
+			}
+			if f.Assignee != nil {
+				sprintIssue.Assignee = f.Assignee.DisplayName
+			}
+		}
+		issues = append(issues, sprintIssue)
+	})
+	return issues, err
+}
+
+func (j *jiraBoards) AddToSprint(sprintID int, issueID string) error {
+	resp, err := j.agile.Sprint.Move(context.Background(), sprintID, &models.SprintMovePayloadScheme{
+		Issues: []string{issueID},
+	})
+	if err != nil {
+		return jiraError(resp, err)
+	}
+	log.Printf("Issue %s added to the sprint\n", issueID)
+	return nil
+}
+
+// AssignToMe takes over unassigned issues, issues already assigned to someone
+// are left alone. It tells whether the issue was assigned.
+func (j *JiraWrapper) AssignToMe(issueID string) (bool, error) {
+	assigned := func() (bool, error) {
+		issue, resp, err := j.client.Issue.Get(context.Background(), issueID, []string{"assignee"}, nil)
+		if err != nil {
+			return false, jiraError(resp, err)
+		}
+		return issue.Fields != nil && issue.Fields.Assignee != nil, nil
+	}
+	assign := func(accountID string) error {
+		resp, err := j.client.Issue.Assign(context.Background(), issueID, accountID)
+		if err != nil {
+			return jiraError(resp, err)
+		}
+		return nil
+	}
+	return assignToMe(issueID, assigned, j.myAccountID, assign)
+}
+
+// assignToMe assigns an issue nobody is assigned to yet, me being who that is
+// on the Jira at hand, nil when it couldn't be looked up.
+func assignToMe(issueID string, assigned func() (bool, error), me func() *string, assign func(me string) error) (bool, error) {
+	if isAssigned, err := assigned(); err != nil || isAssigned {
+		return false, err
+	}
+	account := me()
+	if account == nil {
+		log.Printf("Could not look up your account, %s is left unassigned\n", issueID)
+		return false, nil
+	}
+	if err := assign(*account); err != nil {
+		return false, err
+	}
+	log.Printf("Issue assigned: %s\n", issueID)
+	return true, nil
+}
+
+// TransitionIssue moves an issue using a transition matched by either its own
+// name or the name of the status it leads to. It tells whether the issue moved.
+func (j *JiraWrapper) TransitionIssue(issueID, transition string) (bool, error) {
+	status := func() (string, error) {
+		issue, resp, err := j.client.Issue.Get(context.Background(), issueID, []string{"status"}, nil)
+		if err != nil {
+			return "", jiraError(resp, err)
+		}
+		if issue.Fields == nil || issue.Fields.Status == nil {
+			return "", nil
+		}
+		return issue.Fields.Status.Name, nil
+	}
+	move := func(transitionID string) error {
+		resp, err := j.client.Issue.Move(context.Background(), issueID, transitionID, nil)
+		if err != nil {
+			return jiraError(resp, err)
+		}
+		return nil
+	}
+	return transitionIssue(issueID, transition, status, j.client.Issue.Transitions, move)
+}
+
+// transitionsFunc lists the transitions of an issue, both Jira clients have
+// one.
+type transitionsFunc func(ctx context.Context, issueID string) (*models.IssueTransitionsScheme, *models.ResponseScheme, error)
+
+// transitionIssue moves an issue not in the status yet with move, status
+// being the one it's in.
+func transitionIssue(issueID, transition string, status func() (string, error), transitions transitionsFunc,
+	move func(transitionID string) error) (bool, error) {
+	current, err := status()
+	if err != nil || strings.EqualFold(current, transition) {
+		return false, err
+	}
+	available, resp, err := transitions(context.Background(), issueID)
+	if err != nil {
+		return false, jiraError(resp, err)
+	}
+	for _, t := range available.Transitions {
+		if strings.EqualFold(t.Name, transition) || (t.To != nil && strings.EqualFold(t.To.Name, transition)) {
+			if err := move(t.ID); err != nil {
+				return false, err
+			}
+			log.Printf("Issue %s moved to: %s\n", issueID, transition)
+			return true, nil
+		}
+	}
+	log.Printf("No transition %q available for %s, leaving it as is\n", transition, issueID)
+	return false, nil
+}
+
+// GetIssue fetches an issue with its description as plain text. The
+// acceptance criteria are read from a custom field when one is given.
+func (j *JiraWrapper) GetIssue(issueID, acceptanceCriteriaField string) (*cfg.Task, error) {
+	fields := []string{"summary", "description", "issuetype"}
+	if acceptanceCriteriaField != "" {
+		fields = append(fields, acceptanceCriteriaField)
+	}
+	issue, resp, err := j.client.Issue.Get(context.Background(), issueID, fields, nil)
+	if err != nil {
+		return nil, jiraError(resp, err)
+	}
+
+	task := &cfg.Task{IssueID: issue.Key}
+	if f := issue.Fields; f != nil {
+		task.Title = f.Summary
+		task.Description = adf.ToMarkdown(f.Description)
+		if f.IssueType != nil {
+			task.Type = f.IssueType.Name
+		}
+	}
+	if acceptanceCriteriaField != "" {
+		task.AcceptanceCriteria = customFieldText(j.call, "rest/api/3/issue/"+issueID, resp.Bytes.Bytes(), acceptanceCriteriaField)
+	}
+	return task, nil
+}
+
+// customFieldText reads a text custom field from the raw issue, which is
+// either a plain string or an ADF document for rich text fields. Without the
+// raw issue the field is asked for on its own, and left empty when that fails.
+func customFieldText(call func(method, path string, body, result any) error, issuePath string, issueJSON []byte,
+	field string) string {
+	if len(issueJSON) == 0 {
+		var issue json.RawMessage
+		if err := call(http.MethodGet, issuePath+"?fields="+url.QueryEscape(field), nil, &issue); err != nil {
+			log.Printf("Could not read %s: %v", field, err)
+			return ""
+		}
+		issueJSON = issue
+	}
+	var raw struct {
+		Fields map[string]json.RawMessage `json:"fields"`
+	}
+	if err := json.Unmarshal(issueJSON, &raw); err != nil {
+		return ""
+	}
+	value := raw.Fields[field]
+	var text string
+	if err := json.Unmarshal(value, &text); err == nil {
+		return strings.TrimSpace(text)
+	}
+	var doc models.CommentNodeScheme
+	if err := json.Unmarshal(value, &doc); err == nil {
+		return adf.ToMarkdown(&doc)
+	}
+	return ""
 }
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bricktopab/gg/cfg"
+	"github.com/ctreminiom/go-atlassian/pkg/infra/models"
+)
+
+// newCloudJira is a Jira Cloud client of someone@example.com for project ABC.
+func newCloudJira(t *testing.T, url string) *JiraWrapper {
+	t.Helper()
+	jira, err := NewJiraWrapper(&cfg.JiraConfig{
+		JiraAccount: &cfg.JiraAccount{JiraUser: "someone@example.com", JiraURL: url},
+		JiraToken:   "secret",
+		JiraProject: "ABC",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jira
+}
+
+func TestSearchIssuesPages(t *testing.T) {
+	const total = 130
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/3/search" {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+		if jql := r.URL.Query().Get("jql"); jql != "project = ABC" {
+			t.Errorf("Unexpected JQL: %s", jql)
+		}
+		startAt, _ := strconv.Atoi(r.URL.Query().Get("startAt"))
+		maxResults, _ := strconv.Atoi(r.URL.Query().Get("maxResults"))
+		issues := []map[string]any{}
+		for i := startAt; i < total && i < startAt+maxResults; i++ {
+			issues = append(issues, map[string]any{
+				"key":    fmt.Sprintf("ABC-%d", i+1),
+				"fields": map[string]any{"summary": "Things", "issuetype": map[string]any{"name": "Story"}},
+			})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"startAt": startAt, "total": total, "issues": issues})
+	}))
+	defer server.Close()
+
+	jira := newCloudJira(t, server.URL)
+	tasks, err := jira.SearchIssues("project = ABC")
+	if err != nil {
+		t.Fatalf("SearchIssues() failed: %v", err)
+	}
+	if len(tasks) != total || tasks[total-1].IssueID != "ABC-130" || tasks[0].Type != "Story" {
+		t.Errorf("SearchIssues() = %d issues, last %+v", len(tasks), tasks[len(tasks)-1])
+	}
+}
+
+func TestActiveSprint(t *testing.T) {
+	sprints := []map[string]any{{"id": 42, "name": "Sprint 7", "state": "active", "goal": "Ship it"}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/agile/1.0/board/3/sprint" || r.URL.Query().Get("state") != "active" {
+			t.Errorf("Unexpected request: %s", r.URL)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"isLast": true, "values": sprints})
+	}))
+	defer server.Close()
+
+	jira := newCloudJira(t, server.URL)
+	sprint, err := jira.ActiveSprint(3)
+	if err != nil {
+		t.Fatalf("ActiveSprint() failed: %v", err)
+	}
+	if sprint.ID != 42 || sprint.Name != "Sprint 7" || sprint.Goal != "Ship it" {
+		t.Errorf("ActiveSprint() = %+v", sprint)
+	}
+
+	sprints = nil
+	if _, err := jira.ActiveSprint(3); !errors.Is(err, errNoActiveSprint) || exitCode(err) != exitJira {
+		t.Errorf("ActiveSprint() without a sprint = %v, want errNoActiveSprint", err)
+	}
+}
+
+func TestIssueFields(t *testing.T) {
+	meta := []map[string]any{
+		{"fieldId": "summary", "required": true, "name": "Summary", "schema": map[string]any{"type": "string"}},
+		{"fieldId": "priority", "name": "Priority", "schema": map[string]any{"type": "priority"},
+			"allowedValues": []any{map[string]any{"name": "High"}, map[string]any{"name": "Low"}}},
+		{"fieldId": "components", "required": true, "name": "Components",
+			"schema": map[string]any{"type": "array", "items": "component"}},
+		{"fieldId": "customfield_10020", "name": "Sprint", "schema": map[string]any{"type": "array", "items": "json"}},
+		{"fieldId": "customfield_10030", "name": "Notes",
+			"schema": map[string]any{"type": "string", "custom": "com.atlassian.jira.plugin.system.customfieldtypes:textarea"}},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/3/issue/createmeta/ABC/issuetypes/10001" {
+			t.Errorf("Unexpected request: %s", r.URL)
+		}
+		// three fields a page
+		startAt, _ := strconv.Atoi(r.URL.Query().Get("startAt"))
+		page := meta[startAt:min(startAt+3, len(meta))]
+		_ = json.NewEncoder(w).Encode(map[string]any{"startAt": startAt, "total": len(meta), "fields": page})
+	}))
+	defer server.Close()
+
+	jira := newCloudJira(t, server.URL)
+	fields, err := jira.IssueFields("10001")
+	if err != nil {
+		t.Fatalf("IssueFields() failed: %v", err)
+	}
+	var names []string
+	for _, f := range fields {
+		names = append(names, f.Name)
+	}
+	if strings.Join(names, ",") != "Components,Notes,Priority" {
+		t.Fatalf("IssueFields() = %v, want Components, Notes and Priority", names)
+	}
+	if !fields[0].Required || fields[1].Type != "textarea" || strings.Join(fields[2].Allowed, ",") != "High,Low" {
+		t.Errorf("IssueFields() = %+v", fields)
+	}
+}
+
+func TestFieldValue(t *testing.T) {
+	tests := []struct {
+		field cfg.IssueField
+		want  string
+	}{
+		{cfg.IssueField{Type: "priority", Value: "High"}, `{"name":"High"}`},
+		{cfg.IssueField{Type: "option", Value: "Yes"}, `{"value":"Yes"}`},
+		{cfg.IssueField{Type: "number", Value: "3"}, `3`},
+		{cfg.IssueField{Type: "string", Value: "text"}, `"text"`},
+		{cfg.IssueField{Type: "array", Items: "component", Value: "API, Web"}, `[{"name":"API"},{"name":"Web"}]`},
+		{cfg.IssueField{Type: "array", Items: "string", Value: "ui,tech-debt"}, `["ui","tech-debt"]`},
+	}
+	for _, tt := range tests {
+		value, err := fieldValue(tt.field, adfText)
+		if err != nil {
+			t.Fatalf("fieldValue(%+v) failed: %v", tt.field, err)
+		}
+		got, _ := json.Marshal(value)
+		if string(got) != tt.want {
+			t.Errorf("fieldValue(%+v) = %s, want %s", tt.field, got, tt.want)
+		}
+	}
+
+	if _, err := fieldValue(cfg.IssueField{Name: "Story Points", Type: "number", Value: "three"}, adfText); err == nil {
+		t.Error("fieldValue() with a bad number succeeded")
+	}
+}
+
+func TestLinkPayload(t *testing.T) {
+	types := []*models.LinkTypeScheme{
+		{Name: "Blocks", Inward: "is blocked by", Outward: "blocks"},
+		{Name: "Relates", Inward: "relates to", Outward: "relates to"},
+	}
+	// Jira reads the inward issue as the one that "blocks"
+	tests := []struct {
+		relation string
+		want     string
+	}{
+		{"blocks", `{"inwardIssue":{"key":"ABC-1"},"outwardIssue":{"key":"ABC-2"},"type":{"name":"Blocks"}}`},
+		{"is blocked by", `{"inwardIssue":{"key":"ABC-2"},"outwardIssue":{"key":"ABC-1"},"type":{"name":"Blocks"}}`},
+		{"relates to", `{"inwardIssue":{"key":"ABC-1"},"outwardIssue":{"key":"ABC-2"},"type":{"name":"Relates"}}`},
+	}
+	for _, tt := range tests {
+		payload, err := linkPayload(types, "ABC-1", cfg.IssueLink{Relation: tt.relation, Issue: "ABC-2"})
+		if err != nil {
+			t.Fatalf("linkPayload(%q) failed: %v", tt.relation, err)
+		}
+		if got, _ := json.Marshal(payload); string(got) != tt.want {
+			t.Errorf("linkPayload(%q) = %s, want %s", tt.relation, got, tt.want)
+		}
+	}
+
+	if _, err := linkPayload(types, "ABC-1", cfg.IssueLink{Relation: "duplicates", Issue: "ABC-2"}); exitCode(err) != exitJira {
+		t.Errorf("linkPayload() with an unknown relation = %v, want a Jira error", err)
+	}
+}
+
+func TestCustomFieldText(t *testing.T) {
+	noCall := func(method, path string, body, result any) error {
+		t.Errorf("Unexpected call: %s %s", method, path)
+		return nil
+	}
+	issue := []byte(`{"fields": {"customfield_1": " Works offline ", "customfield_2": {"type": "doc", "version": 1,
+		"content": [{"type": "paragraph", "content": [{"type": "text", "text": "Syncs", "marks": [{"type": "strong"}]}]}]}}}`)
+	if got := customFieldText(noCall, "rest/api/3/issue/GG-1", issue, "customfield_1"); got != "Works offline" {
+		t.Errorf("customFieldText() of a string = %q", got)
+	}
+	if got := customFieldText(noCall, "rest/api/3/issue/GG-1", issue, "customfield_2"); got != "**Syncs**" {
+		t.Errorf("customFieldText() of a document = %q", got)
+	}
+
+	// without the body of the issue the field is fetched on its own
+	var asked string
+	refetch := func(method, path string, body, result any) error {
+		asked = method + " " + path
+		*result.(*json.RawMessage) = json.RawMessage(`{"fields": {"customfield_1": "Works offline"}}`)
+		return nil
+	}
+	if got := customFieldText(refetch, "rest/api/3/issue/GG-1", nil, "customfield_1"); got != "Works offline" {
+		t.Errorf("customFieldText() without a body = %q", got)
+	}
+	if asked != "GET rest/api/3/issue/GG-1?fields=customfield_1" {
+		t.Errorf("customFieldText() asked for %q", asked)
+	}
+	failing := func(method, path string, body, result any) error { return errors.New("gone") }
+	if got := customFieldText(failing, "rest/api/3/issue/GG-1", nil, "customfield_1"); got != "" {
+		t.Errorf("customFieldText() after a failure = %q", got)
+	}
+}
+
+func TestTransitionIssue(t *testing.T) {
+	transitions := func(context.Context, string) (*models.IssueTransitionsScheme, *models.ResponseScheme, error) {
+		return &models.IssueTransitionsScheme{Transitions: []*models.IssueTransitionScheme{
+			{ID: "11", Name: "Start", To: &models.StatusScheme{Name: "In Progress"}},
+			{ID: "31", Name: "Finish", To: &models.StatusScheme{Name: "Done"}},
+		}}, nil, nil
+	}
+	status := func(name string) func() (string, error) {
+		return func() (string, error) { return name, nil }
+	}
+	var moved []string
+	move := func(transitionID string) error {
+		moved = append(moved, transitionID)
+		return nil
+	}
+
+	for _, tt := range []struct {
+		status, transition string
+		want               bool
+	}{
+		{"To Do", "in progress", true},
+		{"To Do", "Finish", true},
+		{"Done", "done", false},
+		{"To Do", "Review", false},
+	} {
+		moved = nil
+		got, err := transitionIssue("ABC-1", tt.transition, status(tt.status), transitions, move)
+		if err != nil || got != tt.want || (len(moved) > 0) != tt.want {
+			t.Errorf("transitionIssue() from %s with %s = %v, %v, moved %v", tt.status, tt.transition, got, err, moved)
+		}
+	}
+}
+
+func TestGetIssueWithoutFields(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"key": "ABC-1"})
+	}))
+	defer server.Close()
+
+	task, err := newCloudJira(t, server.URL).GetIssue("ABC-1", "")
+	if err != nil || task.IssueID != "ABC-1" || task.Title != "" {
+		t.Errorf("GetIssue() without fields = %+v, %v", task, err)
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/bricktopab/gg/adf"
+	"github.com/bricktopab/gg/cfg"
+	"github.com/ctreminiom/go-atlassian/jira/agile"
+	v2 "github.com/ctreminiom/go-atlassian/jira/v2"
+	"github.com/ctreminiom/go-atlassian/pkg/infra/models"
+)
+
+// ServerJira talks to Jira Server and Data Center. They only have the v2 API,
+// take personal access tokens, write rich text as wiki markup and know users
+// by name rather than account ID.
+type ServerJira struct {
+	jiraBoards
+	client *v2.Client
+	config *cfg.JiraConfig
+}
+
+func NewServerJira(jiraConfig *cfg.JiraConfig) (*ServerJira, error) {
+	client, err := v2.New(nil, jiraConfig.JiraURL)
+	if err != nil {
+		return nil, err
+	}
+	client.Auth.SetBearerToken(jiraConfig.JiraToken)
+	agileClient, err := agile.New(nil, jiraConfig.JiraURL)
+	if err != nil {
+		return nil, err
+	}
+	agileClient.Auth.SetBearerToken(jiraConfig.JiraToken)
+	return &ServerJira{jiraBoards: jiraBoards{agile: agileClient}, client: client, config: jiraConfig}, nil
+}
+
+// DetectJiraDeployment tells Cloud from Server by asking Jira, unless the
+// URL is an Atlassian one already.
+func DetectJiraDeployment(jiraURL string) (string, error) {
+	site, err := url.Parse(jiraURL)
+	if err != nil {
+		return "", configError("bad Jira URL %q: %w", jiraURL, err)
+	}
+	if strings.HasSuffix(site.Hostname(), ".atlassian.net") || strings.HasSuffix(site.Hostname(), ".jira.com") {
+		return cfg.JiraCloud, nil
+	}
+	client, err := v2.New(nil, jiraURL)
+	if err != nil {
+		return "", configError("bad Jira URL %q: %w", jiraURL, err)
+	}
+	info, resp, err := client.Server.Info(context.Background())
+	if err != nil {
+		return "", jiraError(resp, err)
+	}
+	if strings.EqualFold(info.DeploymentType, "cloud") {
+		return cfg.JiraCloud, nil
+	}
+	return cfg.JiraServer, nil
+}
+
+func (j *ServerJira) GetIssueTypes(subtask bool) (map[string]string, error) {
+	return issueTypes(j.client.Project, j.config.JiraProject, subtask)
+}
+
+// LookupMyAccountID returns the username, which Server uses where Cloud
+// takes account IDs.
+func (j *ServerJira) LookupMyAccountID() (*string, error) {
+	currentUser, resp, err := j.client.MySelf.Details(context.Background(), nil)
+	if err != nil {
+		return nil, jiraError(resp, err)
+	}
+	return &currentUser.Name, nil
+}
+
+func (j *ServerJira) myName() *string {
+	if j.config.JiraAccountID == "" {
+		name, err := j.LookupMyAccountID()
+		if err != nil {
+			return nil
+		}
+		j.config.JiraAccountID = *name
+	}
+	return &j.config.JiraAccountID
+}
+
+// CreateIssue sets epics through the Epic Link field, Server has parents for
+// sub-tasks only.
+func (j *ServerJira) CreateIssue(draft *cfg.IssueDraft) (*cfg.Task, error) {
+	payload := &models.IssueSchemeV2{
+		Fields: &models.IssueFieldsSchemeV2{
+			Summary:     draft.Title,
+			Project:     &models.ProjectScheme{Key: j.config.JiraProject},
+			IssueType:   &models.IssueTypeScheme{ID: draft.TypeID},
+			Description: adf.ToWiki(adf.FromMarkdown(draft.Description)),
+		},
+	}
+	if name := j.myName(); name != nil {
+		payload.Fields.Assignee = &models.UserScheme{Name: *name}
+	}
+
+	fields, err := customFields(draft.Fields, wikiText)
+	if err != nil {
+		return nil, err
+	}
+	if draft.Parent != "" && draft.Subtask {
+		payload.Fields.Parent = &models.ParentScheme{Key: draft.Parent}
+	} else if draft.Parent != "" {
+		epicField, err := j.epicLinkField(draft.TypeID)
+		if err != nil {
+			return nil, err
+		}
+		if fields == nil {
+			fields = &models.CustomFields{}
+		}
+		fields.Fields = append(fields.Fields, map[string]any{"fields": map[string]any{epicField: draft.Parent}})
+	}
+
+	issue, resp, err := j.client.Issue.Create(context.Background(), payload, fields)
+	if err != nil {
+		return nil, jiraError(resp, err)
+	}
+
+	log.Printf("Issue created: %s\n", issue.Key)
+	return &cfg.Task{
+		IssueID:     issue.Key,
+		Title:       draft.Title,
+		Description: draft.Description,
+		Type:        draft.Type,
+	}, nil
+}
+
+func wikiText(markdown string) any {
+	return adf.ToWiki(adf.FromMarkdown(markdown))
+}
+
+// serverCreateMeta is a page of the fields of an issue type.
+type serverCreateMeta struct {
+	IsLast bool `json:"isLast"`
+	Values []struct {
+		FieldID string `json:"fieldId"`
+		fieldMeta
+	} `json:"values"`
+}
+
+// createMeta reads all fields of an issue type. Server 9 dropped the create
+// metadata Cloud has, this is the one that replaced it.
+func (j *ServerJira) createMeta(typeID string) (map[string]fieldMeta, error) {
+	fields := map[string]fieldMeta{}
+	for startAt := 0; ; {
+		path := fmt.Sprintf("rest/api/2/issue/createmeta/%s/issuetypes/%s?startAt=%d",
+			url.PathEscape(j.config.JiraProject), url.PathEscape(typeID), startAt)
+		var page serverCreateMeta
+		if err := j.call(http.MethodGet, path, nil, &page); err != nil {
+			return nil, err
+		}
+		for _, value := range page.Values {
+			fields[value.FieldID] = value.fieldMeta
+		}
+		startAt += len(page.Values)
+		if page.IsLast || len(page.Values) == 0 {
+			return fields, nil
+		}
+	}
+}
+
+func (j *ServerJira) IssueFields(typeID string) ([]cfg.IssueField, error) {
+	meta, err := j.createMeta(typeID)
+	if err != nil {
+		return nil, err
+	}
+	return issueFields(meta), nil
+}
+
+func (j *ServerJira) epicLinkField(typeID string) (string, error) {
+	meta, err := j.createMeta(typeID)
+	if err != nil {
+		return "", err
+	}
+	for id, field := range meta {
+		if strings.HasSuffix(field.Schema.Custom, ":gh-epic-link") {
+			return id, nil
+		}
+	}
+	return "", jiraErrorf("issue type %s has no Epic Link field in %s", typeID, j.config.JiraProject)
+}
+
+// call does a request the client has no method for.
+func (j *ServerJira) call(method, path string, body, result any) error {
+	req, err := j.client.NewRequest(context.Background(), method, path, "", body)
+	if err != nil {
+		return jiraErrorf("JIRA Error: %w", err)
+	}
+	resp, err := j.client.Call(req, result)
+	if err != nil {
+		return jiraError(resp, err)
+	}
+	return nil
+}
+
+func (j *ServerJira) LinkIssue(issueID string, link cfg.IssueLink) error {
+	types, resp, err := j.client.Issue.Link.Type.Gets(context.Background())
+	if err != nil {
+		return jiraError(resp, err)
+	}
+	payload, err := linkPayload(types.IssueLinkTypes, issueID, link)
+	if err != nil {
+		return err
+	}
+	resp, err = j.client.Issue.Link.Create(context.Background(), &models.LinkPayloadSchemeV2{
+		Type:         payload.Type,
+		InwardIssue:  payload.InwardIssue,
+		OutwardIssue: payload.OutwardIssue,
+	})
+	if err != nil {
+		return jiraError(resp, err)
+	}
+	log.Printf("Issue %s %s\n", issueID, link)
+	return nil
+}
+
+func (j *ServerJira) SearchIssues(jql string) ([]cfg.Task, error) {
+	return searchIssues(j.search, jql)
+}
+
+func (j *ServerJira) SprintIssues(sprintID int) ([]cfg.SprintIssue, error) {
+	return sprintIssues(j.search, sprintID)
+}
+
+// search hands out the issues in the shape of Cloud ones, with the fields
+// searches ask for.
+func (j *ServerJira) search(jql string, fields []string, add func(*models.IssueScheme)) error {
+	startAt := 0
+	for {
+		page, resp, err := j.client.Issue.Search.Get(context.Background(), jql, fields, []string{},
+			startAt, searchPageSize, "")
+		if err != nil {
+			return jiraError(resp, err)
+		}
+		for _, issue := range page.Issues {
+			found := &models.IssueScheme{Key: issue.Key}
+			if f := issue.Fields; f != nil {
+				found.Fields = &models.IssueFieldsScheme{
+					Summary:   f.Summary,
+					IssueType: f.IssueType,
+					Status:    f.Status,
+					Assignee:  f.Assignee,
+				}
+			}
+			add(found)
+		}
+		startAt += len(page.Issues)
+		if len(page.Issues) == 0 || startAt >= page.Total {
+			return nil
+		}
+	}
+}
+
+func (j *ServerJira) AssignToMe(issueID string) (bool, error) {
+	assigned := func() (bool, error) {
+		issue, resp, err := j.client.Issue.Get(context.Background(), issueID, []string{"assignee"}, nil)
+		if err != nil {
+			return false, jiraError(resp, err)
+		}
+		return issue.Fields != nil && issue.Fields.Assignee != nil, nil
+	}
+	assign := func(name string) error {
+		path := "rest/api/2/issue/" + url.PathEscape(issueID) + "/assignee"
+		return j.call(http.MethodPut, path, map[string]string{"name": name}, nil)
+	}
+	return assignToMe(issueID, assigned, j.myName, assign)
+}
+
+func (j *ServerJira) TransitionIssue(issueID, transition string) (bool, error) {
+	status := func() (string, error) {
+		issue, resp, err := j.client.Issue.Get(context.Background(), issueID, []string{"status"}, nil)
+		if err != nil {
+			return "", jiraError(resp, err)
+		}
+		if issue.Fields == nil || issue.Fields.Status == nil {
+			return "", nil
+		}
+		return issue.Fields.Status.Name, nil
+	}
+	move := func(transitionID string) error {
+		resp, err := j.client.Issue.Move(context.Background(), issueID, transitionID, nil)
+		if err != nil {
+			return jiraError(resp, err)
+		}
+		return nil
+	}
+	return transitionIssue(issueID, transition, status, j.client.Issue.Transitions, move)
+}
+
+// GetIssue turns the wiki markup of the description and acceptance criteria
+// into Markdown, like the Cloud documents.
+func (j *ServerJira) GetIssue(issueID, acceptanceCriteriaField string) (*cfg.Task, error) {
+	fields := []string{"summary", "description", "issuetype"}
+	if acceptanceCriteriaField != "" {
+		fields = append(fields, acceptanceCriteriaField)
+	}
+	issue, resp, err := j.client.Issue.Get(context.Background(), issueID, fields, nil)
+	if err != nil {
+		return nil, jiraError(resp, err)
+	}
+
+	task := &cfg.Task{IssueID: issue.Key}
+	if f := issue.Fields; f != nil {
+		task.Title = f.Summary
+		task.Description = adf.ToMarkdown(adf.FromWiki(f.Description))
+		if f.IssueType != nil {
+			task.Type = f.IssueType.Name
+		}
+	}
+	if acceptanceCriteriaField != "" {
+		criteria := customFieldText(j.call, "rest/api/2/issue/"+issueID, resp.Bytes.Bytes(), acceptanceCriteriaField)
+		task.AcceptanceCriteria = adf.ToMarkdown(adf.FromWiki(criteria))
+	}
+	return task, nil
+}
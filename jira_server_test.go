@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bricktopab/gg/cfg"
+)
+
+func TestDetectJiraDeployment(t *testing.T) {
+	deploymentType := "Server"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/2/serverInfo" {
+			t.Errorf("Unexpected request: %s", r.URL)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"deploymentType": deploymentType})
+	}))
+	defer server.Close()
+
+	for _, tt := range []struct{ deploymentType, want string }{
+		{"Server", cfg.JiraServer}, {"DataCenter", cfg.JiraServer}, {"Cloud", cfg.JiraCloud},
+	} {
+		deploymentType = tt.deploymentType
+		if got, err := DetectJiraDeployment(server.URL); err != nil || got != tt.want {
+			t.Errorf("DetectJiraDeployment() for %s = %q, %v, want %q", tt.deploymentType, got, err, tt.want)
+		}
+	}
+	// known without asking
+	if got, err := DetectJiraDeployment("https://example.atlassian.net"); err != nil || got != cfg.JiraCloud {
+		t.Errorf("DetectJiraDeployment() for atlassian.net = %q, %v", got, err)
+	}
+}
+
+// fakeServerJira answers like Jira Data Center for user jdoe, keeping the
+// bodies of the issues created and the assignees set.
+func fakeServerJira(t *testing.T, created, assigned *map[string]any) *ServerJira {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "Bearer pat" {
+			t.Errorf("Unexpected authorization %q for %s", auth, r.URL)
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /rest/api/2/myself":
+			_ = json.NewEncoder(w).Encode(map[string]any{"name": "jdoe", "key": "JIRAUSER10100"})
+		case "GET /rest/api/2/issue/createmeta/ABC/issuetypes/10001":
+			_ = json.NewEncoder(w).Encode(map[string]any{"isLast": true, "values": []any{
+				map[string]any{"fieldId": "customfield_10100", "name": "Epic Link",
+					"schema": map[string]any{"type": "any", "custom": "com.pyxis.greenhopper.jira:gh-epic-link"}},
+			}})
+		case "POST /rest/api/2/issue":
+			_ = json.NewDecoder(r.Body).Decode(created)
+			_ = json.NewEncoder(w).Encode(map[string]any{"key": "ABC-7"})
+		case "GET /rest/api/2/issue/ABC-7":
+			_ = json.NewEncoder(w).Encode(map[string]any{"key": "ABC-7", "fields": map[string]any{}})
+		case "GET /rest/api/2/issue/ABC-8":
+			_ = json.NewEncoder(w).Encode(map[string]any{"key": "ABC-8", "fields": map[string]any{
+				"summary":           "Login",
+				"description":       "h2. Steps\n# Open *Safari*\n# Sign in as [~jdoe]",
+				"customfield_10200": "* Shows the {{dashboard}}",
+			}})
+		case "GET /rest/api/2/issue/ABC-9":
+			_ = json.NewEncoder(w).Encode(map[string]any{"key": "ABC-9"})
+		case "PUT /rest/api/2/issue/ABC-7/assignee":
+			_ = json.NewDecoder(r.Body).Decode(assigned)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	jira, err := NewServerJira(&cfg.JiraConfig{
+		JiraAccount: &cfg.JiraAccount{JiraURL: server.URL}, JiraToken: "pat", JiraProject: "ABC",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jira
+}
+
+func TestServerJiraCreateIssue(t *testing.T) {
+	var created map[string]any
+	jira := fakeServerJira(t, &created, nil)
+
+	task, err := jira.CreateIssue(&cfg.IssueDraft{
+		TypeID: "10001", Type: "Story", Title: "Login", Description: "Fails on **Safari**", Parent: "ABC-1",
+	})
+	if err != nil {
+		t.Fatalf("CreateIssue() failed: %v", err)
+	}
+	if task.IssueID != "ABC-7" {
+		t.Errorf("CreateIssue() = %+v", task)
+	}
+	fields, _ := created["fields"].(map[string]any)
+	if fields["description"] != "Fails on *Safari*" {
+		t.Errorf("description = %v, want wiki markup", fields["description"])
+	}
+	if assignee, _ := fields["assignee"].(map[string]any); assignee["name"] != "jdoe" || assignee["accountId"] != nil {
+		t.Errorf("assignee = %v, want jdoe by name", fields["assignee"])
+	}
+	if fields["customfield_10100"] != "ABC-1" || fields["parent"] != nil {
+		t.Errorf("epic = %v, parent = %v, want the epic in the Epic Link field", fields["customfield_10100"], fields["parent"])
+	}
+}
+
+func TestServerJiraAssignToMe(t *testing.T) {
+	var assigned map[string]any
+	jira := fakeServerJira(t, nil, &assigned)
+
+	ok, err := jira.AssignToMe("ABC-7")
+	if err != nil || !ok {
+		t.Fatalf("AssignToMe() = %v, %v", ok, err)
+	}
+	if assigned["name"] != "jdoe" {
+		t.Errorf("assigned %v, want jdoe", assigned)
+	}
+}
+
+func TestServerJiraGetIssue(t *testing.T) {
+	jira := fakeServerJira(t, nil, nil)
+
+	task, err := jira.GetIssue("ABC-8", "customfield_10200")
+	if err != nil {
+		t.Fatalf("GetIssue() failed: %v", err)
+	}
+	if want := "## Steps\n\n1. Open **Safari**\n2. Sign in as [@jdoe](mention:jdoe)"; task.Description != want {
+		t.Errorf("Description = %q, want %q", task.Description, want)
+	}
+	if want := "- Shows the `dashboard`"; task.AcceptanceCriteria != want {
+		t.Errorf("AcceptanceCriteria = %q, want %q", task.AcceptanceCriteria, want)
+	}
+
+	// Jira leaves out the fields it has nothing for
+	if task, err := jira.GetIssue("ABC-9", ""); err != nil || task.IssueID != "ABC-9" || task.Title != "" {
+		t.Errorf("GetIssue() without fields = %+v, %v", task, err)
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bricktopab/gg/cfg"
+	"github.com/bricktopab/gg/oauth"
+)
+
+// jiraScopes are what gg does in Jira, offline_access gets a refresh token.
+var jiraScopes = []string{
+	"read:jira-work", "write:jira-work", "read:jira-user",
+	"read:board-scope:jira-software", "read:sprint:jira-software", "write:sprint:jira-software",
+	"offline_access",
+}
+
+const defaultOAuthPort = 8338
+
+// loginTimeout is how long gg login waits for the browser.
+const loginTimeout = 5 * time.Minute
+
+var (
+	// atlassianAPI is where OAuth apps reach Jira Cloud sites
+	atlassianAPI  = "https://api.atlassian.com"
+	oauthEndpoint = oauth.Atlassian
+	// browse opens the login page, tests take the browser's place
+	browse = openBrowser
+)
+
+type LoginOptions struct {
+	ClientID     string
+	ClientSecret string
+	Port         int
+}
+
+// Login signs in to Jira Cloud in the browser with an OAuth app. The tokens
+// go to the token store and are used instead of the API token from then on.
+func (g *GG) Login(options LoginOptions) error {
+	account := g.Config.Account()
+	app := cfg.OAuth{Port: defaultOAuthPort}
+	if account.JiraOAuth != nil {
+		app = *account.JiraOAuth
+	}
+	if options.ClientID != "" {
+		app.ClientID = options.ClientID
+	}
+	if options.Port != 0 {
+		app.Port = options.Port
+	}
+	if app.ClientID == "" {
+		return configError("no OAuth app to log in with, register one for Jira and pass its --client-id")
+	}
+	if options.ClientSecret != "" {
+		if err := g.Config.SetToken(cfg.JiraOAuthSecretKey, options.ClientSecret); err != nil {
+			return configError("failed to store the client secret: %w", err)
+		}
+	}
+	secret, err := g.Config.Token(cfg.JiraOAuthSecretKey)
+	if err != nil {
+		return configError("failed to get the client secret: %w", err)
+	}
+	if secret == "" {
+		return configError("no client secret for the OAuth app, pass it with --client-secret")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), loginTimeout)
+	defer cancel()
+	token, err := jiraOAuthConfig(app, secret).Login(ctx, func(authURL string) error {
+		log.Printf("Log in to Jira in your browser, or open:\n%s\n", authURL)
+		if err := browse(authURL); err != nil {
+			log.Println(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return configError("failed to log in: %w", err)
+	}
+
+	site, err := g.pickSite(ctx, token)
+	if err != nil {
+		return err
+	}
+	if err := storeOAuthToken(g.Config, token); err != nil {
+		return err
+	}
+	app.CloudID = site.ID
+	account.JiraOAuth = &app
+	account.JiraURL = site.URL
+	account.JiraDeployment = cfg.JiraCloud
+	// whoever signed in, looked up again on first use
+	account.JiraAccountID = ""
+	if err := g.Config.Save(); err != nil {
+		return configError("failed to save config: %w", err)
+	}
+	log.Printf("Logged in to %s\n", site.URL)
+	return nil
+}
+
+// Logout forgets the tokens of gg login, the app stays configured for the
+// next login.
+func (g *GG) Logout() error {
+	if err := g.Config.DeleteToken(cfg.JiraOAuthTokenKey); err != nil {
+		return configError("failed to delete the token: %w", err)
+	}
+	if app := g.Config.Account().JiraOAuth; app != nil {
+		app.CloudID = ""
+	}
+	if err := g.Config.Save(); err != nil {
+		return configError("failed to save config: %w", err)
+	}
+	log.Println("Logged out of Jira")
+	return nil
+}
+
+func jiraOAuthConfig(app cfg.OAuth, secret string) *oauth.Config {
+	return &oauth.Config{
+		Endpoint:     oauthEndpoint,
+		ClientID:     app.ClientID,
+		ClientSecret: secret,
+		Scopes:       jiraScopes,
+		Port:         app.Port,
+		Params:       map[string]string{"audience": "api.atlassian.com", "prompt": "consent"},
+	}
+}
+
+// jiraTokens hands out the tokens of gg login, refreshing them as needed.
+func jiraTokens(config *cfg.Config) (*oauth.Source, error) {
+	secret, err := config.Token(cfg.JiraOAuthSecretKey)
+	if err != nil {
+		return nil, configError("failed to get the client secret: %w", err)
+	}
+	return &oauth.Source{
+		Config: jiraOAuthConfig(*config.Account().JiraOAuth, secret),
+		Load: func() (*oauth.Token, error) {
+			stored, err := config.Token(cfg.JiraOAuthTokenKey)
+			if err != nil || stored == "" {
+				return nil, err
+			}
+			var token oauth.Token
+			if err := json.Unmarshal([]byte(stored), &token); err != nil {
+				return nil, fmt.Errorf("failed to read the stored token: %w", err)
+			}
+			return &token, nil
+		},
+		Save: func(token *oauth.Token) error {
+			return storeOAuthToken(config, token)
+		},
+	}, nil
+}
+
+func storeOAuthToken(config *cfg.Config, token *oauth.Token) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	if err := config.SetToken(cfg.JiraOAuthTokenKey, string(data)); err != nil {
+		return configError("failed to store the token: %w", err)
+	}
+	return nil
+}
+
+// cloudSite is a Jira site the login gives access to.
+type cloudSite struct {
+	ID   string `json:"id"`
+	URL  string `json:"url"`
+	Name string `json:"name"`
+}
+
+// pickSite finds the configured Jira among the sites the login is good for,
+// or the only one when none is configured.
+func (g *GG) pickSite(ctx context.Context, token *oauth.Token) (*cloudSite, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, atlassianAPI+"/oauth/token/accessible-resources", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, jiraErrorf("failed to list your Jira sites: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	var sites []cloudSite
+	if err := json.NewDecoder(resp.Body).Decode(&sites); err != nil || resp.StatusCode != http.StatusOK {
+		return nil, jiraErrorf("failed to list your Jira sites: %s %v", resp.Status, err)
+	}
+
+	configured := strings.TrimSuffix(g.Config.Account().JiraURL, "/")
+	urls := make([]string, 0, len(sites))
+	for i, site := range sites {
+		if strings.EqualFold(strings.TrimSuffix(site.URL, "/"), configured) || (configured == "" && len(sites) == 1) {
+			return &sites[i], nil
+		}
+		urls = append(urls, site.URL)
+	}
+	if len(sites) == 0 {
+		return nil, jiraErrorf("the login gives access to no Jira site")
+	}
+	if configured == "" {
+		return nil, configError("set jira_url to the Jira to use, one of: %s", strings.Join(urls, ", "))
+	}
+	return nil, configError("the login isn't for %s but for: %s", configured, strings.Join(urls, ", "))
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/bricktopab/gg/cfg"
+	"github.com/bricktopab/gg/oauth"
+	"github.com/bricktopab/gg/secrets"
+)
+
+// fakeAtlassian is the authorization server and API of Atlassian Cloud, with
+// one site and access tokens that expire right away.
+func fakeAtlassian(t *testing.T) {
+	t.Helper()
+	issued := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		back := url.Values{"state": {query.Get("state")}, "code": {"the-code"}}
+		http.Redirect(w, r, query.Get("redirect_uri")+"?"+back.Encode(), http.StatusFound)
+	})
+	mux.HandleFunc("/oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("client_secret") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		issued++
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": fmt.Sprintf("access-%d", issued), "refresh_token": fmt.Sprintf("refresh-%d", issued),
+			"expires_in": 1,
+		})
+	})
+	mux.HandleFunc("/oauth/token/accessible-resources", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode([]any{
+			map[string]any{"id": "other", "url": "https://other.atlassian.net", "name": "other"},
+			map[string]any{"id": "cloud-1", "url": "https://example.atlassian.net", "name": "example"},
+		})
+	})
+	mux.HandleFunc("/ex/jira/cloud-1/rest/api/3/myself", func(w http.ResponseWriter, r *http.Request) {
+		// the token of the login expired, gg refreshes it
+		if r.Header.Get("Authorization") != "Bearer access-2" {
+			t.Errorf("Unexpected authorization: %s", r.Header.Get("Authorization"))
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"accountId": "me"})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	api, endpoint, open := atlassianAPI, oauthEndpoint, browse
+	t.Cleanup(func() {
+		atlassianAPI, oauthEndpoint, browse = api, endpoint, open
+	})
+	atlassianAPI = server.URL
+	oauthEndpoint = oauth.Endpoint{AuthURL: server.URL + "/authorize", TokenURL: server.URL + "/oauth/token"}
+	browse = func(authURL string) error {
+		go func() {
+			if resp, err := http.Get(authURL); err == nil { // #nosec G107
+				_ = resp.Body.Close()
+			}
+		}()
+		return nil
+	}
+}
+
+func TestLoginAndLogout(t *testing.T) {
+	fakeAtlassian(t)
+	gg, _ := newTestGG(t, &fakeJira{}, &fakeGit{})
+	t.Setenv(secrets.PassphraseEnv, "correct horse")
+	gg.Config.TokenStore = cfg.TokenStoreFile
+
+	if err := gg.Login(LoginOptions{ClientID: "app", ClientSecret: "secret", Port: freePort(t)}); err != nil {
+		t.Fatalf("Login() failed: %v", err)
+	}
+	if app := gg.Config.JiraOAuth; app == nil || app.CloudID != "cloud-1" || app.ClientID != "app" {
+		t.Fatalf("Login() saved the app %+v, want cloud-1", app)
+	}
+	if stored, _ := gg.Config.Token(cfg.JiraOAuthTokenKey); stored == "" {
+		t.Error("Login() didn't store the token")
+	}
+
+	tokens, err := jiraTokens(gg.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jiraConfig := &cfg.JiraConfig{JiraAccount: &cfg.JiraAccount{}, JiraProject: "ABC"}
+	jira, err := NewOAuthJiraWrapper(jiraConfig, gg.Config.JiraOAuth.CloudID, tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := jira.LookupMyAccountID(); err != nil || *id != "me" {
+		t.Errorf("LookupMyAccountID() with the login = %v, %v", id, err)
+	}
+
+	if err := gg.Logout(); err != nil {
+		t.Fatalf("Logout() failed: %v", err)
+	}
+	if stored, _ := gg.Config.Token(cfg.JiraOAuthTokenKey); stored != "" || gg.Config.JiraOAuth.CloudID != "" {
+		t.Errorf("Logout() left the token %q and cloud ID %q", stored, gg.Config.JiraOAuth.CloudID)
+	}
+	tokens, err = jiraTokens(gg.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jira, err = NewOAuthJiraWrapper(&cfg.JiraConfig{JiraAccount: &cfg.JiraAccount{}, JiraProject: "ABC"}, "cloud-1", tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jira.LookupMyAccountID(); exitCode(err) != exitConfig {
+		t.Errorf("LookupMyAccountID() after logout = %v, want a config error", err)
+	}
+}
+
+func freePort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = listener.Close()
+	}()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestLoginNeedsAnApp(t *testing.T) {
+	gg, _ := newTestGG(t, &fakeJira{}, &fakeGit{})
+	if err := gg.Login(LoginOptions{}); exitCode(err) != exitConfig {
+		t.Errorf("Login() without a client ID = %v, want a config error", err)
+	}
+}
@@ -1,8 +1,13 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
 	"log"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/bricktopab/gg/cfg"
 	"github.com/bricktopab/gg/gui"
@@ -10,17 +15,79 @@ import (
 )
 
 type Cli interface {
-	CreateIssue(name string, description string)
-	PickIssue()
-	CreatePR()
+	CreateIssue(name string, description string, options IssueOptions) error
+	PickIssue(query, jql string) error
+	CreatePR() error
+	Done(deleteRemote bool) error
+	ShowConfig() error
+	GetConfig(key string) error
+	SetConfig(key, value string) error
+	UnsetConfig(key string) error
+	EditConfig() error
+	InitConfig() error
+	ShowSprint() error
+	Login(options LoginOptions) error
+	Logout() error
+	AddProfile(name string, profile *cfg.Profile) error
+	ShowProfiles() error
+	UseProfile(name string) error
+	RemoveProfile(name string) error
 }
 
 type Gui interface {
-	AskForConfig() *cfg.Config
-	AskForIssueDetails(string, string, func() map[string]string) (string, string, string, string)
-	SelectTask(func(bool) []cfg.Task) *cfg.Task
-	AskForPRTitle(*cfg.Task) string
-	ShowSummary(string, string, string)
+	AskForConfig() (*cfg.Config, error)
+	EditConfig(config *cfg.Config) error
+	AskForIssueDetails(draft *cfg.IssueDraft, issueTypes func() (map[string]string, error),
+		issueFields func(typeID string) ([]cfg.IssueField, error)) error
+	SelectTask(queries []string, fetch func(string) ([]cfg.Task, error), lookup func(string) (*cfg.Task, error)) (*cfg.Task, error)
+	AskForPRTitle(task *cfg.Task, bases, prefixes []string, defaults cfg.PRTitleOptions,
+		formatTitle func(cfg.PRTitleOptions) string) (string, string, error)
+	AskForPRBody(body string) (string, error)
+	ShowSummary(*cfg.Result) error
+	ShowSettings([]cfg.Setting) error
+	ShowSetting(cfg.Setting) error
+	ShowSprint(*cfg.Sprint) error
+	AskForProfile(name string, profile *cfg.Profile) error
+	ShowProfiles([]cfg.ProfileInfo) error
+}
+
+// configCommands fix what keeps gg out of Jira, so they run without it.
+var configCommands = []string{"config", "profile", "login", "logout"}
+
+// fieldFlags keeps each --field whole, its values are lists themselves, like
+// components=API,Web.
+type fieldFlags []string
+
+// serializedFields is how cli hands the values of -f over to --field.
+const serializedFields = "fields:"
+
+func (f *fieldFlags) Set(value string) error {
+	if serialized, ok := strings.CutPrefix(value, serializedFields); ok {
+		return json.Unmarshal([]byte(serialized), f)
+	}
+	*f = append(*f, value)
+	return nil
+}
+
+func (f *fieldFlags) String() string {
+	return strings.Join(*f, " ")
+}
+
+func (f *fieldFlags) Serialize() string {
+	data, _ := json.Marshal(*f)
+	return serializedFields + string(data)
+}
+
+// issueJQL is the JQL to list issues with, from --jql or --filter.
+func issueJQL(jql, filter string) (string, error) {
+	if filter == "" {
+		return jql, nil
+	}
+	if jql != "" {
+		return "", configError("use either --jql or --filter, not both")
+	}
+	// Jira resolves saved filters by name or ID itself
+	return "filter = " + strconv.Quote(filter), nil
 }
 
 func init() {
@@ -29,26 +96,89 @@ func init() {
 }
 
 func main() {
-	var gui Gui = &gui.Gui{}
+	// flags answer what the forms would ask, the forms fill in the rest
+	prompts := &gui.Unattended{Fallback: &gui.Gui{}}
+	var ui Gui = prompts
 	var gg Cli
 
 	app := &cli.App{
 		Name:  "gg",
 		Usage: "JIRA 🏓 GitHub",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:    "no-input",
+				Usage:   "never prompt, fail when something is missing",
+				EnvVars: []string{"GG_NO_INPUT"},
+			},
+			&cli.BoolFlag{
+				Name:    "yes",
+				Aliases: []string{"y"},
+				Usage:   "take the defaults instead of asking",
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "print results as json or yaml",
+				EnvVars: []string{"GG_OUTPUT"},
+			},
+			&cli.StringFlag{
+				Name:    "profile",
+				Usage:   "Jira profile to use instead of the one matching the repository",
+				EnvVars: []string{"GG_PROFILE"},
+			},
+		},
 		Before: func(cCtx *cli.Context) error {
-			config, err := cfg.LoadOrCreateConfig(gui.AskForConfig)
+			prompts.Answers.Yes = cCtx.Bool("yes")
+			if cCtx.Bool("no-input") {
+				prompts.Fallback = nil
+				prompts.Answers.Yes = true
+			}
+			if output := cCtx.String("output"); output != "" && output != "text" {
+				if !slices.Contains(gui.OutputFormats, output) {
+					return configError("unknown output format %q, use one of: text, %s", output, strings.Join(gui.OutputFormats, ", "))
+				}
+				ui = &gui.Structured{Unattended: prompts, Format: output}
+			}
+
+			config, err := cfg.LoadOrCreateConfig(ui.AskForConfig)
 			if err != nil {
-				log.Fatalf("Failed to load or create config: %v", err)
+				return configError("failed to load or create config: %w", err)
 			}
-			jira, err := NewJiraWrapperWithOldConfig(config.JiraUser, config.JiraToken, config.JiraURL, config.JiraProject)
+			cwd, err := os.Getwd()
 			if err != nil {
-				log.Fatalf("Failed to create Jira client: %v", err)
+				return fmt.Errorf("failed to get working directory: %w", err)
+			}
+			if err := config.LoadRepoConfig(cwd); err != nil {
+				return configError("failed to load repo config: %w", err)
+			}
+			repo := ""
+			if len(config.Profiles) > 0 {
+				repo = originRepo()
+			}
+			if err := config.SelectProfile(cCtx.String("profile"), cwd, repo); err != nil {
+				return configError("%w", err)
+			}
+			settings := config.Settings()
+			jiraToken, err := config.Token(cfg.JiraTokenKey)
+			if err != nil {
+				return configError("failed to get Jira token: %w", err)
+			}
+			detect := config.Account().JiraDeployment == ""
+			jira, err := newJiraClient(config, jiraToken)
+			if err != nil && !slices.Contains(configCommands, cCtx.Args().First()) {
+				return err
+			}
+			if err == nil && detect && config.Account().JiraDeployment != "" {
+				// detected once, then kept in ~/.gg
+				if err := config.Save(); err != nil {
+					return configError("failed to save config: %w", err)
+				}
 			}
 			gg = &GG{
 				Config: config,
 				Jira:   jira,
-				Gui:    gui,
-				Git:    &ExternalGit{},
+				Gui:    ui,
+				Git:    &ExternalGit{Hosts: config.Hosts, Token: config.HostingToken, BaseBranch: settings.BaseBranch},
 			}
 
 			return nil
@@ -60,33 +190,245 @@ func main() {
 				Args:      true,
 				Usage:     "Create issue and local branch interactively",
 				ArgsUsage: "[issue title] [issue description]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "type", Aliases: []string{"t"}, Usage: "issue type, like Story or Bug"},
+					&cli.StringFlag{Name: "title", Usage: "issue title"},
+					&cli.StringFlag{Name: "description", Aliases: []string{"d"}, Usage: "issue description"},
+					&cli.BoolFlag{Name: "sprint", Aliases: []string{"s"}, Usage: "add the issue to the active sprint of the board"},
+					&cli.StringFlag{Name: "parent", Usage: "create a sub-task of this issue, . for the current branch's issue"},
+					&cli.StringFlag{Name: "epic", Usage: "epic or other parent issue of the new issue"},
+					&cli.StringSliceFlag{Name: "link", Aliases: []string{"l"}, Usage: `link to another issue, like "blocks ABC-12"`},
+					&cli.GenericFlag{
+						Name: "field", Aliases: []string{"f"}, Usage: `set a field, like "components=API,Web"`, Value: &fieldFlags{},
+					},
+				},
 				Action: func(cCtx *cli.Context) error {
-					gg.CreateIssue(cCtx.Args().First(), cCtx.Args().Get(1))
-					return nil
+					prompts.Answers.Type = cCtx.String("type")
+					prompts.Answers.Title = cCtx.String("title")
+					prompts.Answers.Description = cCtx.String("description")
+					options := IssueOptions{
+						Sprint: cCtx.Bool("sprint"),
+						Parent: strings.ToUpper(cCtx.String("parent")),
+						Epic:   strings.ToUpper(cCtx.String("epic")),
+					}
+					for _, link := range cCtx.StringSlice("link") {
+						parsed, err := cfg.ParseIssueLink(link)
+						if err != nil {
+							return configError("%w", err)
+						}
+						options.Links = append(options.Links, parsed)
+					}
+					for _, field := range *cCtx.Generic("field").(*fieldFlags) {
+						name, value, ok := strings.Cut(field, "=")
+						if !ok {
+							return configError("field %q should be name=value", field)
+						}
+						if options.Fields == nil {
+							options.Fields = map[string]string{}
+						}
+						options.Fields[strings.TrimSpace(name)] = strings.TrimSpace(value)
+					}
+					return gg.CreateIssue(cCtx.Args().First(), cCtx.Args().Get(1), options)
 				},
 			},
 			{
 				Name:    "issue",
 				Aliases: []string{"i"},
 				Usage:   "Looks up one of your issues and creates/switches a local branch",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "issue", Usage: "issue key to pick, like PROJ-123"},
+					&cli.StringFlag{Name: "query", Aliases: []string{"q"}, Usage: "one of the configured queries to list first"},
+					&cli.StringFlag{Name: "jql", Usage: "list the issues matching this JQL instead"},
+					&cli.StringFlag{Name: "filter", Aliases: []string{"f"}, Usage: "list the issues of a saved Jira filter, by name or ID"},
+				},
 				Action: func(cCtx *cli.Context) error {
-					gg.PickIssue()
-					return nil
+					prompts.Answers.Issue = cCtx.String("issue")
+					jql, err := issueJQL(cCtx.String("jql"), cCtx.String("filter"))
+					if err != nil {
+						return err
+					}
+					return gg.PickIssue(cCtx.String("query"), jql)
 				},
 			},
 			{
 				Name:    "pull",
 				Aliases: []string{"pr"},
 				Usage:   "Creates a PR with naming that matches your ticket",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "prefix", Aliases: []string{"p"}, Usage: "PR title prefix like feat or fix, - for none"},
+					&cli.StringFlag{Name: "base", Aliases: []string{"b"}, Usage: "branch to open the PR against"},
+				},
+				Action: func(cCtx *cli.Context) error {
+					prompts.Answers.Prefix = cCtx.String("prefix")
+					prompts.Answers.Base = cCtx.String("base")
+					return gg.CreatePR()
+				},
+			},
+			{
+				Name:  "done",
+				Usage: "Wraps up a merged task: closes the issue, removes the branch and switches back to the base",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "remote",
+						Aliases: []string{"r"},
+						Usage:   "also delete the remote branch",
+					},
+				},
+				Action: func(cCtx *cli.Context) error {
+					return gg.Done(cCtx.Bool("remote"))
+				},
+			},
+			{
+				Name:  "sprint",
+				Usage: "Lists the issues in the active sprint of the board by status",
+				Action: func(cCtx *cli.Context) error {
+					return gg.ShowSprint()
+				},
+			},
+			{
+				Name:  "login",
+				Usage: "Logs in to Jira Cloud in the browser, instead of using an API token",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "client-id", Usage: "client ID of your OAuth app for Jira"},
+					&cli.StringFlag{Name: "client-secret", Usage: "client secret of the app, kept in the token store",
+						EnvVars: []string{"GG_CLIENT_SECRET"}},
+					&cli.IntFlag{Name: "port", Usage: "port of the app's callback URL, http://localhost:<port>/callback"},
+				},
+				Action: func(cCtx *cli.Context) error {
+					return gg.Login(LoginOptions{
+						ClientID:     cCtx.String("client-id"),
+						ClientSecret: cCtx.String("client-secret"),
+						Port:         cCtx.Int("port"),
+					})
+				},
+			},
+			{
+				Name:  "logout",
+				Usage: "Forgets the login of gg login",
 				Action: func(cCtx *cli.Context) error {
-					gg.CreatePR()
-					return nil
+					return gg.Logout()
+				},
+			},
+			{
+				Name:  "profile",
+				Usage: "Manages profiles, for working with more than one Jira site or account",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "add",
+						Usage:     "Adds a profile, or changes one",
+						ArgsUsage: "<name>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "url", Usage: "Jira URL, like https://example.atlassian.net"},
+							&cli.StringFlag{Name: "user", Usage: "Jira username"},
+							&cli.StringFlag{Name: "token", Usage: "Jira API token, kept in the token store", EnvVars: []string{"GG_JIRA_TOKEN"}},
+							&cli.StringFlag{Name: "project", Usage: "Jira project key"},
+							&cli.StringSliceFlag{Name: "remote", Usage: "use the profile for origin repositories like github.com/acme/*"},
+							&cli.StringSliceFlag{Name: "dir", Usage: "use the profile in directories like ~/work/acme"},
+						},
+						Action: func(cCtx *cli.Context) error {
+							profile := &cfg.Profile{
+								JiraAccount: cfg.JiraAccount{
+									JiraURL:   cCtx.String("url"),
+									JiraUser:  cCtx.String("user"),
+									JiraToken: cCtx.String("token"),
+								},
+								Remotes: cCtx.StringSlice("remote"),
+								Dirs:    cCtx.StringSlice("dir"),
+							}
+							profile.JiraProject = cCtx.String("project")
+							return gg.AddProfile(cCtx.Args().First(), profile)
+						},
+					},
+					{
+						Name:  "list",
+						Usage: "Lists the profiles, * marks the one in use",
+						Action: func(cCtx *cli.Context) error {
+							return gg.ShowProfiles()
+						},
+					},
+					{
+						Name:      "use",
+						Usage:     "Uses a profile unless another one is picked or matches the repository",
+						ArgsUsage: "<name>",
+						Action: func(cCtx *cli.Context) error {
+							return gg.UseProfile(cCtx.Args().First())
+						},
+					},
+					{
+						Name:      "remove",
+						Usage:     "Removes a profile and its tokens",
+						ArgsUsage: "<name>",
+						Action: func(cCtx *cli.Context) error {
+							return gg.RemoveProfile(cCtx.Args().First())
+						},
+					},
+				},
+			},
+			{
+				Name:  "config",
+				Usage: "Shows and changes the settings",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "list",
+						Usage: "Lists the effective settings and where they come from",
+						Action: func(cCtx *cli.Context) error {
+							return gg.ShowConfig()
+						},
+					},
+					{
+						Name:      "get",
+						Usage:     "Prints the value of a setting, like transitions.WEB.review",
+						ArgsUsage: "<key>",
+						Action: func(cCtx *cli.Context) error {
+							if cCtx.NArg() != 1 {
+								return configError("usage: gg config get <key>")
+							}
+							return gg.GetConfig(cCtx.Args().First())
+						},
+					},
+					{
+						Name:      "set",
+						Usage:     "Changes a setting in ~/.gg, profiles.<name>.<key> for a profile",
+						ArgsUsage: "<key> <value>",
+						Action: func(cCtx *cli.Context) error {
+							if cCtx.NArg() != 2 {
+								return configError("usage: gg config set <key> <value>")
+							}
+							return gg.SetConfig(cCtx.Args().First(), cCtx.Args().Get(1))
+						},
+					},
+					{
+						Name:      "unset",
+						Usage:     "Removes a setting from ~/.gg, so the default is used",
+						ArgsUsage: "<key>",
+						Action: func(cCtx *cli.Context) error {
+							if cCtx.NArg() != 1 {
+								return configError("usage: gg config unset <key>")
+							}
+							return gg.UnsetConfig(cCtx.Args().First())
+						},
+					},
+					{
+						Name:  "edit",
+						Usage: "Opens ~/.gg in your editor and checks it before saving",
+						Action: func(cCtx *cli.Context) error {
+							return gg.EditConfig()
+						},
+					},
+					{
+						Name:  "init",
+						Usage: "Runs the setup of the first run again",
+						Action: func(cCtx *cli.Context) error {
+							return gg.InitConfig()
+						},
+					},
 				},
 			},
 		},
 	}
 
 	if err := app.Run(os.Args); err != nil {
-		log.Fatal(err)
+		log.Println(err)
+		os.Exit(exitCode(err))
 	}
 }
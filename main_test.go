@@ -0,0 +1,48 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+func TestFieldFlags(t *testing.T) {
+	var fields fieldFlags
+	var links []string
+	app := &cli.App{Commands: []*cli.Command{{
+		Name: "new",
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{Name: "link"},
+			&cli.GenericFlag{Name: "field", Aliases: []string{"f"}, Value: &fieldFlags{}},
+		},
+		Action: func(cCtx *cli.Context) error {
+			fields = *cCtx.Generic("field").(*fieldFlags)
+			links = cCtx.StringSlice("link")
+			return nil
+		},
+	}}}
+	args := []string{"gg", "new", "-f", "components=API,Web", "-f", "priority=High", "--link", "blocks ABC-1,relates to ABC-2"}
+	if err := app.Run(args); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"components=API,Web", "priority=High"}; !slices.Equal(fields, want) {
+		t.Errorf("--field gave %q, want %q", fields, want)
+	}
+	// other lists still split on commas
+	if want := []string{"blocks ABC-1", "relates to ABC-2"}; !slices.Equal(links, want) {
+		t.Errorf("--link gave %q, want %q", links, want)
+	}
+}
+
+func TestIssueJQL(t *testing.T) {
+	if jql, err := issueJQL("project = GG", ""); err != nil || jql != "project = GG" {
+		t.Errorf("issueJQL() with --jql = %q, %v", jql, err)
+	}
+	if jql, err := issueJQL("", "My issues"); err != nil || jql != `filter = "My issues"` {
+		t.Errorf("issueJQL() with --filter = %q, %v", jql, err)
+	}
+	if _, err := issueJQL("project = GG", "My issues"); err == nil || exitCode(err) != exitConfig {
+		t.Errorf("issueJQL() with both = %v", err)
+	}
+}
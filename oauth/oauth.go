@@ -0,0 +1,249 @@
+// Package oauth logs in with the OAuth 2.0 authorization code flow, taking
+// the code from the browser on a loopback redirect, and keeps the access
+// token fresh with the refresh token.
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Endpoint is where an authorization server takes logins and hands out tokens.
+type Endpoint struct {
+	AuthURL  string
+	TokenURL string
+}
+
+// Atlassian is the authorization server of Atlassian Cloud.
+var Atlassian = Endpoint{
+	AuthURL:  "https://auth.atlassian.com/authorize",
+	TokenURL: "https://auth.atlassian.com/oauth/token",
+}
+
+// callbackPath is where the browser is sent back to with the code.
+const callbackPath = "/callback"
+
+// Config is an app registered with the authorization server.
+type Config struct {
+	Endpoint
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// Port of the redirect listener, the redirect URL registered for the app
+	// has to match it. Zero picks a free port.
+	Port int
+	// Params are added to the authorization URL, like Atlassian's audience
+	Params map[string]string
+	// HTTPClient is used for the token requests, the default client if nil
+	HTTPClient *http.Client
+}
+
+// Token is what the authorization server hands out.
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`
+}
+
+// expiryDelta renews tokens a bit before they expire, so they don't run out
+// in the middle of a request.
+const expiryDelta = time.Minute
+
+// shutdownTimeout is how long the redirect server gets to finish answering.
+const shutdownTimeout = 5 * time.Second
+
+// Valid tells whether the access token can still be used.
+func (t *Token) Valid() bool {
+	return t != nil && t.AccessToken != "" && (t.Expiry.IsZero() || time.Now().Add(expiryDelta).Before(t.Expiry))
+}
+
+// Login sends the user to the authorization server with open and waits for
+// the browser to come back with the code, which it exchanges for a token.
+func (c *Config) Login(ctx context.Context, open func(authURL string) error) (*Token, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:"+strconv.Itoa(c.Port))
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for the redirect on port %d: %w", c.Port, err)
+	}
+	defer func() {
+		_ = listener.Close()
+	}()
+	redirectURL := fmt.Sprintf("http://localhost:%d%s", listener.Addr().(*net.TCPAddr).Port, callbackPath)
+
+	state, err := randomString()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := randomString()
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make(chan string, 1)
+	errs := make(chan error, 1)
+	server := &http.Server{
+		ReadHeaderTimeout: 10 * time.Second,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != callbackPath {
+				http.NotFound(w, r)
+				return
+			}
+			// the first answer counts, retries and strays mustn't block
+			query := r.URL.Query()
+			switch {
+			case query.Get("state") != state:
+				// not the login gg started, keep waiting for that one
+				http.Error(w, "Login failed, this isn't the login gg started.", http.StatusBadRequest)
+			case query.Get("error") != "":
+				http.Error(w, "Login failed: "+query.Get("error_description"), http.StatusBadRequest)
+				select {
+				case errs <- fmt.Errorf("login failed: %s %s", query.Get("error"), query.Get("error_description")):
+				default:
+				}
+			default:
+				_, _ = fmt.Fprintln(w, "Logged in, you can close this window and go back to gg.")
+				select {
+				case codes <- query.Get("code"):
+				default:
+				}
+			}
+		}),
+	}
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+	}()
+
+	if err := open(c.authURL(redirectURL, state, verifier)); err != nil {
+		return nil, err
+	}
+	select {
+	case code := <-codes:
+		return c.token(ctx, url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {code},
+			"redirect_uri":  {redirectURL},
+			"code_verifier": {verifier},
+		})
+	case err := <-errs:
+		return nil, err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// authURL asks for a code with PKCE on top of the client secret.
+func (c *Config) authURL(redirectURL, state, verifier string) string {
+	challenge := sha256.Sum256([]byte(verifier))
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.ClientID},
+		"redirect_uri":          {redirectURL},
+		"scope":                 {strings.Join(c.Scopes, " ")},
+		"state":                 {state},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	for key, value := range c.Params {
+		params.Set(key, value)
+	}
+	separator := "?"
+	if strings.Contains(c.AuthURL, "?") {
+		separator = "&"
+	}
+	return c.AuthURL + separator + params.Encode()
+}
+
+// Refresh trades the refresh token for a new access token. Servers that
+// rotate refresh tokens send a new one along, otherwise the old one stays.
+func (c *Config) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	token, err := c.token(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	return token, nil
+}
+
+func (c *Config) token(ctx context.Context, params url.Values) (*Token, error) {
+	params.Set("client_id", c.ClientID)
+	if c.ClientSecret != "" {
+		params.Set("client_secret", c.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.TokenURL, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get a token: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	var body struct {
+		AccessToken      string `json:"access_token"`
+		RefreshToken     string `json:"refresh_token"`
+		ExpiresIn        int    `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to read the token (%s): %w", resp.Status, err)
+	}
+	if body.Error != "" || resp.StatusCode != http.StatusOK || body.AccessToken == "" {
+		return nil, &Error{Code: body.Error, Description: body.ErrorDescription, Status: resp.StatusCode}
+	}
+	token := &Token{AccessToken: body.AccessToken, RefreshToken: body.RefreshToken}
+	if body.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// Error is an error response of the token endpoint.
+type Error struct {
+	Code        string
+	Description string
+	Status      int
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("no token from the authorization server (%d)", e.Status)
+	}
+	return strings.TrimSpace("token request failed: " + e.Code + " " + e.Description)
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
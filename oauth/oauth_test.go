@@ -0,0 +1,237 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeServer is an authorization server handing out numbered tokens, which
+// rotates refresh tokens like Atlassian does.
+type fakeServer struct {
+	*httptest.Server
+	t         *testing.T
+	challenge string
+	issued    atomic.Int32
+	// deny is the error the login ends with, if any
+	deny string
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+	f := &fakeServer{t: t}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("client_id") != "app" || query.Get("audience") != "api.example.com" {
+			t.Errorf("Unexpected authorization request: %s", r.URL)
+		}
+		f.challenge = query.Get("code_challenge")
+		back := url.Values{"state": {query.Get("state")}, "code": {"the-code"}}
+		if f.deny != "" {
+			back = url.Values{"state": {query.Get("state")}, "error": {f.deny}}
+		}
+		http.Redirect(w, r, query.Get("redirect_uri")+"?"+back.Encode(), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("client_id") != "app" || r.FormValue("client_secret") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = fmt.Fprint(w, `{"error":"invalid_client"}`)
+			return
+		}
+		switch r.FormValue("grant_type") {
+		case "authorization_code":
+			verifier := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+			if r.FormValue("code") != "the-code" || base64.RawURLEncoding.EncodeToString(verifier[:]) != f.challenge {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = fmt.Fprint(w, `{"error":"invalid_grant"}`)
+				return
+			}
+		case "refresh_token":
+			if r.FormValue("refresh_token") != fmt.Sprintf("refresh-%d", f.issued.Load()) {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = fmt.Fprint(w, `{"error":"invalid_grant","error_description":"Unknown or invalid refresh token."}`)
+				return
+			}
+		}
+		n := f.issued.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token":  fmt.Sprintf("access-%d", n),
+			"refresh_token": fmt.Sprintf("refresh-%d", n),
+			"expires_in":    3600,
+		})
+	})
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeServer) config() *Config {
+	return &Config{
+		Endpoint:     Endpoint{AuthURL: f.URL + "/authorize", TokenURL: f.URL + "/token"},
+		ClientID:     "app",
+		ClientSecret: "secret",
+		Scopes:       []string{"read", "offline_access"},
+		Params:       map[string]string{"audience": "api.example.com"},
+	}
+}
+
+// browser follows the redirects the way a browser would.
+func browser(authURL string) error {
+	go func() {
+		resp, err := http.Get(authURL) // #nosec G107
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+	}()
+	return nil
+}
+
+func TestLogin(t *testing.T) {
+	server := newFakeServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	token, err := server.config().Login(ctx, browser)
+	if err != nil {
+		t.Fatalf("Login() failed: %v", err)
+	}
+	if token.AccessToken != "access-1" || token.RefreshToken != "refresh-1" || !token.Valid() {
+		t.Errorf("Login() = %+v", token)
+	}
+
+	server.deny = "access_denied"
+	if _, err := server.config().Login(ctx, browser); err == nil {
+		t.Error("Login() succeeded after the user said no")
+	}
+}
+
+func TestLoginIgnoresStrayCallbacks(t *testing.T) {
+	server := newFakeServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	statuses := make(chan int, 1)
+	// another state first, then the login twice as a retrying browser would
+	retrying := func(authURL string) error {
+		parsed, err := url.Parse(authURL)
+		if err != nil {
+			return err
+		}
+		stray := parsed.Query().Get("redirect_uri") + "?state=other&code=stolen"
+		go func() {
+			resp, err := http.Get(stray) // #nosec G107
+			if err != nil {
+				statuses <- 0
+				return
+			}
+			_ = resp.Body.Close()
+			statuses <- resp.StatusCode
+			for range 2 {
+				if resp, err := http.Get(authURL); err == nil { // #nosec G107
+					_ = resp.Body.Close()
+				}
+			}
+		}()
+		return nil
+	}
+
+	done := make(chan struct{})
+	var token *Token
+	var err error
+	go func() {
+		defer close(done)
+		token, err = server.config().Login(ctx, retrying)
+	}()
+	if status := <-statuses; status != http.StatusBadRequest {
+		t.Errorf("stray callback got %d, want 400", status)
+	}
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Login() hung on a second callback")
+	}
+	if err != nil || token.AccessToken != "access-1" {
+		t.Errorf("Login() = %+v, %v", token, err)
+	}
+}
+
+func TestSourceRefreshes(t *testing.T) {
+	server := newFakeServer(t)
+	server.issued.Store(1)
+	var saved *Token
+	source := &Source{
+		Config: server.config(),
+		Load: func() (*Token, error) {
+			return &Token{AccessToken: "access-1", RefreshToken: "refresh-1", Expiry: time.Now().Add(-time.Hour)}, nil
+		},
+		Save: func(token *Token) error {
+			saved = token
+			return nil
+		},
+	}
+
+	token, err := source.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Token() failed: %v", err)
+	}
+	if token.AccessToken != "access-2" || saved == nil || saved.RefreshToken != "refresh-2" {
+		t.Errorf("Token() = %+v, saved %+v, want the refreshed token saved", token, saved)
+	}
+	if again, _ := source.Token(context.Background()); again != token {
+		t.Errorf("Token() refreshed a valid token: %+v", again)
+	}
+
+	// someone else used the refresh token
+	server.issued.Store(5)
+	if _, err := source.Renew(context.Background(), token); !errors.Is(err, ErrNotLoggedIn) {
+		t.Errorf("Renew() with a used refresh token = %v, want ErrNotLoggedIn", err)
+	}
+}
+
+func TestSourceNotLoggedIn(t *testing.T) {
+	source := &Source{Load: func() (*Token, error) { return nil, nil }}
+	if _, err := source.Token(context.Background()); !errors.Is(err, ErrNotLoggedIn) {
+		t.Errorf("Token() without a token = %v, want ErrNotLoggedIn", err)
+	}
+}
+
+func TestTransportRetriesWithFreshToken(t *testing.T) {
+	server := newFakeServer(t)
+	server.issued.Store(1)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// access-1 was revoked before it expired
+		if r.Header.Get("Authorization") != "Bearer access-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = fmt.Fprint(w, "ok")
+	}))
+	defer api.Close()
+
+	source := &Source{
+		Config: server.config(),
+		Load: func() (*Token, error) {
+			return &Token{AccessToken: "access-1", RefreshToken: "refresh-1", Expiry: time.Now().Add(time.Hour)}, nil
+		},
+		Save: func(*Token) error { return nil },
+	}
+	client := &http.Client{Transport: &Transport{Source: source}}
+	resp, err := client.Get(api.URL)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Get() = %s, want a retry with the refreshed token", resp.Status)
+	}
+}
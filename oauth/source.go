@@ -0,0 +1,116 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// ErrNotLoggedIn means there's no token to use, or it can't be refreshed.
+var ErrNotLoggedIn = errors.New("not logged in")
+
+// Source hands out a valid access token, refreshing it when it runs out.
+type Source struct {
+	Config *Config
+	// Load reads the stored token, nil when there is none
+	Load func() (*Token, error)
+	// Save stores a refreshed token, whose refresh token may have changed
+	Save func(*Token) error
+
+	mu    sync.Mutex
+	token *Token
+}
+
+// Token returns the stored token, or a fresh one when it expired.
+func (s *Source) Token(ctx context.Context) (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == nil {
+		token, err := s.Load()
+		if err != nil {
+			return nil, err
+		}
+		if token == nil {
+			return nil, ErrNotLoggedIn
+		}
+		s.token = token
+	}
+	if s.token.Valid() {
+		return s.token, nil
+	}
+	return s.refresh(ctx)
+}
+
+// Renew refreshes the token unless that already happened since stale was
+// handed out, for when the server turned it down before it expired.
+func (s *Source) Renew(ctx context.Context, stale *Token) (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != nil && s.token.AccessToken != stale.AccessToken {
+		return s.token, nil
+	}
+	return s.refresh(ctx)
+}
+
+func (s *Source) refresh(ctx context.Context) (*Token, error) {
+	if s.token == nil || s.token.RefreshToken == "" {
+		return nil, ErrNotLoggedIn
+	}
+	token, err := s.Config.Refresh(ctx, s.token.RefreshToken)
+	var tokenErr *Error
+	if errors.As(err, &tokenErr) && tokenErr.Code == "invalid_grant" {
+		return nil, fmt.Errorf("%w, the login expired: %w", ErrNotLoggedIn, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Save(token); err != nil {
+		return nil, fmt.Errorf("failed to store the refreshed token: %w", err)
+	}
+	s.token = token
+	return token, nil
+}
+
+// Transport sends requests with the access token of the source, and tries
+// once more with a fresh token when one is turned down.
+type Transport struct {
+	Source *Source
+	// Base sends the requests, http.DefaultTransport if nil
+	Base http.RoundTripper
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	token, err := t.Source.Token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := base.RoundTrip(authorized(req, token))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || (req.Body != nil && req.GetBody == nil) {
+		return resp, err
+	}
+
+	_ = resp.Body.Close()
+	token, err = t.Source.Renew(req.Context(), token)
+	if err != nil {
+		return nil, err
+	}
+	retry := authorized(req, token)
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	return base.RoundTrip(retry)
+}
+
+func authorized(req *http.Request, token *Token) *http.Request {
+	clone := req.Clone(req.Context())
+	clone.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	return clone
+}
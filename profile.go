@@ -0,0 +1,97 @@
+package main
+
+import (
+	"log"
+	"slices"
+
+	"github.com/bricktopab/gg/cfg"
+)
+
+// AddProfile adds a Jira profile or changes an existing one, asking for what
+// the flags didn't give. The token goes to the token store.
+func (g *GG) AddProfile(name string, changes *cfg.Profile) error {
+	if err := cfg.CheckProfileName(name); err != nil {
+		return configError("%w", err)
+	}
+	profile := &cfg.Profile{}
+	if existing, ok := g.Config.Profiles[name]; ok {
+		copied := *existing
+		profile = &copied
+	}
+	previousURL, previousUser := profile.JiraURL, profile.JiraUser
+	if changes.JiraURL != "" {
+		profile.JiraURL = changes.JiraURL
+	}
+	if changes.JiraUser != "" {
+		profile.JiraUser = changes.JiraUser
+	}
+	if changes.JiraProject != "" {
+		profile.JiraProject = changes.JiraProject
+	}
+	for _, remote := range changes.Remotes {
+		if !slices.Contains(profile.Remotes, remote) {
+			profile.Remotes = append(profile.Remotes, remote)
+		}
+	}
+	for _, dir := range changes.Dirs {
+		if !slices.Contains(profile.Dirs, dir) {
+			profile.Dirs = append(profile.Dirs, dir)
+		}
+	}
+	profile.JiraToken = changes.JiraToken
+	if err := g.Gui.AskForProfile(name, profile); err != nil {
+		return err
+	}
+	if profile.JiraURL != previousURL {
+		// another Jira, detected again on first use
+		profile.JiraDeployment = ""
+		profile.JiraOAuth = nil
+	}
+	if profile.JiraURL != previousURL || profile.JiraUser != previousUser {
+		// looked up again on first use
+		profile.JiraAccountID = ""
+	}
+
+	token := profile.JiraToken
+	profile.JiraToken = ""
+	if err := g.Config.AddProfile(name, profile); err != nil {
+		return configError("%w", err)
+	}
+	if token != "" {
+		if err := g.Config.SetToken(cfg.ProfileKey(name, cfg.JiraTokenKey), token); err != nil {
+			return configError("failed to store the token: %w", err)
+		}
+	}
+	if err := g.Config.Save(); err != nil {
+		return configError("failed to save config: %w", err)
+	}
+	log.Printf("Saved profile %s\n", name)
+	return nil
+}
+
+func (g *GG) ShowProfiles() error {
+	return g.Gui.ShowProfiles(g.Config.ListProfiles())
+}
+
+// UseProfile makes a profile the one used when no other is picked or matches.
+func (g *GG) UseProfile(name string) error {
+	if err := g.Config.SetDefaultProfile(name); err != nil {
+		return configError("%w", err)
+	}
+	if err := g.Config.Save(); err != nil {
+		return configError("failed to save config: %w", err)
+	}
+	log.Printf("Using profile %s by default\n", name)
+	return nil
+}
+
+func (g *GG) RemoveProfile(name string) error {
+	if err := g.Config.RemoveProfile(name); err != nil {
+		return configError("%w", err)
+	}
+	if err := g.Config.Save(); err != nil {
+		return configError("failed to save config: %w", err)
+	}
+	log.Printf("Removed profile %s\n", name)
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/bricktopab/gg/cfg"
+	"github.com/bricktopab/gg/secrets"
+)
+
+func TestAddProfile(t *testing.T) {
+	gg, _ := newTestGG(t, &fakeJira{}, &fakeGit{})
+	t.Setenv(secrets.PassphraseEnv, "correct horse")
+	gg.Config.TokenStore = cfg.TokenStoreFile
+
+	err := gg.AddProfile("acme", &cfg.Profile{
+		JiraAccount: cfg.JiraAccount{JiraURL: "https://jira.acme.com", JiraUser: "me", JiraToken: "acme-token"},
+		Remotes:     []string{"github.com/acme/*"},
+	})
+	if err != nil {
+		t.Fatalf("AddProfile() failed: %v", err)
+	}
+	profile := gg.Config.Profiles["acme"]
+	if profile == nil || profile.JiraToken != "" || profile.JiraURL != "https://jira.acme.com" {
+		t.Fatalf("AddProfile() saved %+v, want the token in the store", profile)
+	}
+
+	// changing the profile keeps what isn't given
+	if err := gg.AddProfile("acme", &cfg.Profile{Dirs: []string{"~/acme"}}); err != nil {
+		t.Fatalf("AddProfile() of an existing profile failed: %v", err)
+	}
+	if err := gg.Config.UseProfile("acme"); err != nil {
+		t.Fatal(err)
+	}
+	if token, _ := gg.Config.Token(cfg.JiraTokenKey); token != "acme-token" {
+		t.Errorf("The profile's token is %q", token)
+	}
+	if profile := gg.Config.Profiles["acme"]; profile.JiraUser != "me" || len(profile.Remotes) != 1 || len(profile.Dirs) != 1 {
+		t.Errorf("Changing the profile lost values: %+v", profile)
+	}
+	if gg.issueURL("ABC-1") != "https://jira.acme.com/browse/ABC-1" {
+		t.Errorf("issueURL() = %s, want the profile's Jira", gg.issueURL("ABC-1"))
+	}
+
+	if err := gg.AddProfile("acme", &cfg.Profile{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := gg.RemoveProfile("acme"); err != nil {
+		t.Fatalf("RemoveProfile() failed: %v", err)
+	}
+	if token, _ := gg.Config.Token(cfg.ProfileKey("acme", cfg.JiraTokenKey)); token != "" {
+		t.Errorf("RemoveProfile() left the token %q", token)
+	}
+}
+
+func TestAddProfileNeedsAnAccount(t *testing.T) {
+	gg, _ := newTestGG(t, &fakeJira{}, &fakeGit{})
+	if err := gg.AddProfile("acme", &cfg.Profile{}); err == nil {
+		t.Error("AddProfile() without a Jira URL succeeded with --no-input")
+	}
+	if err := gg.AddProfile(cfg.DefaultProfile, &cfg.Profile{}); exitCode(err) != exitConfig {
+		t.Errorf("AddProfile(default) = %v, want a config error", err)
+	}
+}
@@ -0,0 +1,46 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Command fetches secrets by running a user configured shell command per
+// key, like `pass show jira` or `op read op://work/jira/token`. It's read
+// only, secrets are managed with the tool behind the command.
+type Command struct {
+	Commands map[string]string
+}
+
+func (c *Command) Get(key string) (string, error) {
+	command, ok := c.Commands[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/c"
+	}
+	output, err := exec.Command(shell, flag, command).Output() // #nosec G204
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf("credential command for %s failed: %s", key, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("credential command for %s failed: %w", key, err)
+	}
+	// only the first line, `pass` keeps metadata below the password
+	value, _, _ := strings.Cut(string(output), "\n")
+	return strings.TrimSpace(value), nil
+}
+
+func (c *Command) Set(key, _ string) error {
+	return fmt.Errorf("%s comes from a credential command and can't be changed by gg", key)
+}
+
+func (c *Command) Delete(key string) error {
+	return c.Set(key, "")
+}
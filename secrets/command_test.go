@@ -0,0 +1,29 @@
+//go:build !windows
+
+package secrets
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCommand(t *testing.T) {
+	store := &Command{Commands: map[string]string{
+		"jira_token":   "printf 's3cr3t\\nurl: https://example.atlassian.net\\n'",
+		"github_token": "echo 'no such entry' >&2; exit 1",
+	}}
+
+	if got, err := store.Get("jira_token"); err != nil || got != "s3cr3t" {
+		t.Errorf("Get() = %q, %v, want the first line of the output", got, err)
+	}
+	_, err := store.Get("github_token")
+	if err == nil || err.Error() != "credential command for github_token failed: no such entry" {
+		t.Errorf("Expected the command's error output, got %v", err)
+	}
+	if _, err := store.Get("gitlab_token"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound without a command, got %v", err)
+	}
+	if err := store.Set("jira_token", "other"); err == nil {
+		t.Error("Expected Set to fail, commands are read only")
+	}
+}
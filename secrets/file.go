@@ -0,0 +1,143 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+const (
+	PassphraseEnv    = "GG_PASSPHRASE"
+	pbkdf2Iterations = 600_000
+)
+
+// File keeps secrets in a file encrypted with AES-GCM, using a key derived
+// from a passphrase. It's the fallback for machines without a keyring.
+type File struct {
+	Path       string
+	Passphrase string
+}
+
+type encryptedFile struct {
+	Salt  []byte `json:"salt"`
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+}
+
+// NewFileFromEnv takes the passphrase from GG_PASSPHRASE.
+func NewFileFromEnv(path string) (*File, error) {
+	passphrase := os.Getenv(PassphraseEnv)
+	if passphrase == "" {
+		return nil, fmt.Errorf("%s must be set to use the encrypted secrets file", PassphraseEnv)
+	}
+	return &File{Path: path, Passphrase: passphrase}, nil
+}
+
+func (f *File) gcm(salt []byte) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, f.Passphrase, salt, pbkdf2Iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (f *File) load() (map[string]string, error) {
+	data, err := os.ReadFile(f.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets file: %w", err)
+	}
+	var encrypted encryptedFile
+	if err := json.Unmarshal(data, &encrypted); err != nil {
+		return nil, fmt.Errorf("failed to decode secrets file: %w", err)
+	}
+	gcm, err := f.gcm(encrypted.Salt)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, encrypted.Nonce, encrypted.Data, nil)
+	if err != nil {
+		return nil, errors.New("failed to decrypt secrets file, wrong passphrase?")
+	}
+	secrets := map[string]string{}
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return nil, fmt.Errorf("failed to decode secrets file: %w", err)
+	}
+	return secrets, nil
+}
+
+func (f *File) save(secrets map[string]string) error {
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+	// fresh salt and nonce on every write
+	encrypted := encryptedFile{Salt: make([]byte, 16)}
+	if _, err := rand.Read(encrypted.Salt); err != nil {
+		return err
+	}
+	gcm, err := f.gcm(encrypted.Salt)
+	if err != nil {
+		return err
+	}
+	encrypted.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(encrypted.Nonce); err != nil {
+		return err
+	}
+	encrypted.Data = gcm.Seal(nil, encrypted.Nonce, plain, nil)
+
+	data, err := json.Marshal(encrypted)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(f.Path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write secrets file: %w", err)
+	}
+	return nil
+}
+
+func (f *File) Get(key string) (string, error) {
+	secrets, err := f.load()
+	if err != nil {
+		return "", err
+	}
+	value, ok := secrets[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+func (f *File) Set(key, value string) error {
+	secrets, err := f.load()
+	if err != nil {
+		return err
+	}
+	secrets[key] = value
+	return f.save(secrets)
+}
+
+func (f *File) Delete(key string) error {
+	secrets, err := f.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := secrets[key]; !ok {
+		return nil
+	}
+	delete(secrets, key)
+	return f.save(secrets)
+}
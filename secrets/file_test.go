@@ -0,0 +1,71 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets")
+	store := &File{Path: path, Passphrase: "correct horse"}
+
+	if _, err := store.Get("jira_token"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound before anything is stored, got %v", err)
+	}
+	if err := store.Set("jira_token", "s3cr3t"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := store.Set("github_token", "ghp_123"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read secrets file: %v", err)
+	}
+	if strings.Contains(string(data), "s3cr3t") {
+		t.Error("Secrets file contains the plaintext token")
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected secrets file to be private, got %v", info.Mode().Perm())
+	}
+
+	reopened := &File{Path: path, Passphrase: "correct horse"}
+	if got, err := reopened.Get("jira_token"); err != nil || got != "s3cr3t" {
+		t.Errorf("Get() = %q, %v, want s3cr3t", got, err)
+	}
+
+	if err := reopened.Delete("jira_token"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := reopened.Get("jira_token"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+	if got, _ := reopened.Get("github_token"); got != "ghp_123" {
+		t.Errorf("Expected other secrets to survive a delete, got %q", got)
+	}
+}
+
+func TestFileWrongPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets")
+	if err := (&File{Path: path, Passphrase: "right"}).Set("jira_token", "s3cr3t"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if _, err := (&File{Path: path, Passphrase: "wrong"}).Get("jira_token"); err == nil {
+		t.Error("Expected an error with the wrong passphrase")
+	}
+}
+
+func TestNewFileFromEnv(t *testing.T) {
+	t.Setenv(PassphraseEnv, "")
+	if _, err := NewFileFromEnv("secrets"); err == nil {
+		t.Errorf("Expected an error without %s", PassphraseEnv)
+	}
+	t.Setenv(PassphraseEnv, "correct horse")
+	if store, err := NewFileFromEnv("secrets"); err != nil || store.Passphrase != "correct horse" {
+		t.Errorf("NewFileFromEnv() = %+v, %v", store, err)
+	}
+}
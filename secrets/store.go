@@ -0,0 +1,49 @@
+package secrets
+
+import (
+	"errors"
+
+	"github.com/zalando/go-keyring"
+)
+
+const service = "gg"
+
+var ErrNotFound = errors.New("secret not found")
+
+// Store keeps secrets like API tokens out of the plaintext config.
+type Store interface {
+	Get(key string) (string, error)
+	Set(key, value string) error
+	Delete(key string) error
+}
+
+// Keyring uses the OS keyring: Keychain on macOS, the Credential Manager on
+// Windows and the Secret Service (GNOME Keyring, KWallet) on Linux.
+type Keyring struct{}
+
+func (k *Keyring) Get(key string) (string, error) {
+	value, err := keyring.Get(service, key)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrNotFound
+	}
+	return value, err
+}
+
+func (k *Keyring) Set(key, value string) error {
+	return keyring.Set(service, key, value)
+}
+
+func (k *Keyring) Delete(key string) error {
+	err := keyring.Delete(service, key)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// KeyringAvailable checks that there's a keyring to talk to, which often isn't
+// the case on headless Linux machines.
+func KeyringAvailable() bool {
+	_, err := keyring.Get(service, "probe")
+	return err == nil || errors.Is(err, keyring.ErrNotFound)
+}